@@ -2,11 +2,11 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
 
-	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"manna.aero/manna.utm.cli/model/uspace/virtual_uspace"
 	"manna.aero/manna.utm.cli/model/utm"
@@ -20,38 +20,44 @@ var Data = &cobra.Command{
 	Short: "Generate data for the configured simulations in <file>.",
 	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		fromFile, err := cmd.Flags().GetString("file")
-		if err != nil {
-			return err
+		var appCnf *config.Config
+		var err error
+		if cmd.Flags().Changed("file") {
+			var fromFile string
+			fromFile, err = cmd.Flags().GetString("file")
+			if err != nil {
+				return err
+			}
+			appCnf, err = config.LoadConfig(fromFile)
+		} else {
+			appCnf, err = config.FromContext(cmd.Context())
 		}
-
-		config, err := config.LoadConfig(fromFile)
 		if err != nil {
-			log.Fatalf("error occurred loading config: %v", err)
+			return err
 		}
 
 		var wg sync.WaitGroup
+		geoJsonErr := make(chan error, 1)
+		// two files are written for each operational intent
+		oiErrs := make(chan error, 2*len(appCnf.OperationalIntentConfigs))
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			// create the GeoJson data
-			err = ConvertToGeoJsonAndWriteToFile(config)
-			if err != nil {
-				log.Fatalf("error occurred writing GeoJson to file: %v", err)
-			}
+			geoJsonErr <- ConvertToGeoJsonAndWriteToFile(appCnf)
 		}()
 
 		err = os.MkdirAll(fmt.Sprintf("%s/utm", PERSONAL_LIB_PATH), os.ModePerm)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("error occurred creating directory for UTM data: %w", err)
 		}
 		err = os.MkdirAll(fmt.Sprintf("%s/uspace", PERSONAL_LIB_PATH), os.ModePerm)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("error occurred creating directory for U-Space data: %w", err)
 		}
 
-		for _, oiConfig := range config.OperationalIntentConfigs {
+		for _, oiConfig := range appCnf.OperationalIntentConfigs {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
@@ -59,14 +65,14 @@ var Data = &cobra.Command{
 				oi := utm.OperationalIntentFromConfig(&oiConfig)
 				data, err := json.MarshalIndent(oi, "", "  ")
 				if err != nil {
-					log.Errorf("error occurred marshalling operational intent (name=%s) to JSON: %v", oiConfig.Name, err.Error())
+					oiErrs <- fmt.Errorf("error occurred marshalling operational intent (name=%s) to JSON: %w", oiConfig.Name, err)
 					return
 				}
 
 				// create the file if it doesn't already exist
 				err = os.WriteFile(fmt.Sprintf("%s/utm/%s.json", PERSONAL_LIB_PATH, oiConfig.Name), data, 0644)
 				if err != nil {
-					log.Errorf("error occurred writing operational intent (name=%s) to file: %v", oiConfig.Name, err.Error())
+					oiErrs <- fmt.Errorf("error occurred writing operational intent (name=%s) to file: %w", oiConfig.Name, err)
 					return
 				}
 			}()
@@ -75,24 +81,32 @@ var Data = &cobra.Command{
 			go func() {
 				defer wg.Done()
 				// create the U-Space telemetry data
-				oi := virtual_uspace.virtualOiFromConfig(&oiConfig)
+				oi := virtual_uspace.OperationalIntentFromConfig(&oiConfig)
 				data, err := json.MarshalIndent(oi, "", "  ")
 				if err != nil {
-					log.Errorf("error occurred marshalling operational intent (name=%s) to JSON: %v", oiConfig.Name, err.Error())
+					oiErrs <- fmt.Errorf("error occurred marshalling operational intent (name=%s) to JSON: %w", oiConfig.Name, err)
 					return
 				}
 
 				// create the file if it doesn't already exist
 				err = os.WriteFile(fmt.Sprintf("%s/uspace/%s.json", PERSONAL_LIB_PATH, oiConfig.Name), data, 0644)
 				if err != nil {
-					log.Errorf("error occurred writing operational intent (name=%s) to file: %v", oiConfig.Name, err.Error())
+					oiErrs <- fmt.Errorf("error occurred writing operational intent (name=%s) to file: %w", oiConfig.Name, err)
 					return
 				}
 			}()
 		}
 
 		wg.Wait()
-		return nil
+		close(oiErrs)
+		var errs []error
+		if err := <-geoJsonErr; err != nil {
+			errs = append(errs, fmt.Errorf("error occurred writing GeoJson to file: %w", err))
+		}
+		for err := range oiErrs {
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
 	},
 }
 
@@ -107,18 +121,13 @@ func ConvertToGeoJsonAndWriteToFile(c *config.Config) error {
 	configGeoJson := c.ToGeoJson()
 	contents, err := json.MarshalIndent(configGeoJson, "", "  ")
 	if err != nil {
-		log.Fatalf("error occurred marshalling sequence to GeoJson %v", err)
-	}
-
-	err = os.MkdirAll("./.libconfig/personal/geojson", os.ModePerm)
-	if err != nil {
-		log.Fatalf("error occurred creating directory for configuration GeoJson %v", err)
+		return fmt.Errorf("error occurred marshalling sequence to GeoJson %w", err)
 	}
 
-	outFileName := fmt.Sprintf("./.libconfig/personal/geojson/%s.geojson", c.Name)
+	outFileName := fmt.Sprintf("%s/%s.geojson", geoJsonDir, c.Name)
 	err = os.WriteFile(outFileName, contents, 0644)
 	if err != nil {
-		log.Fatalf("error occurred writing GeoJson to file: %v", err)
+		return err
 	}
 
 	return nil
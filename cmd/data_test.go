@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"manna.aero/manna.utm.cli/pkg/config"
+)
+
+// runData runs the data command with the config file <file>.
+func runData(file string) error {
+	if Data.Flags().Lookup("file") == nil {
+		Data.Flags().String("file", "", "")
+	}
+	Data.SetArgs([]string{"--file", file})
+	Data.SilenceUsage = true
+	return Data.ExecuteContext(context.Background())
+}
+
+func TestDataMissingFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	err := runData("/nonexistent.yaml")
+	require.Error(t, err)
+	var cnfErr *config.Error
+	assert.True(t, errors.As(err, &cnfErr), "expected a config error, got %v", err)
+}
+
+func TestDataWriteError(t *testing.T) {
+	cnf, err := filepath.Abs("../config.yaml")
+	require.NoError(t, err)
+	t.Chdir(t.TempDir())
+
+	require.NoError(t, runData(cnf))
+	assert.FileExists(t, PERSONAL_LIB_PATH+"/utm/SWITZERLAND1.json")
+	assert.FileExists(t, PERSONAL_LIB_PATH+"/uspace/SWITZERLAND1.json")
+
+	// an operational intent that cannot be written fails the command
+	require.NoError(t, os.Remove(PERSONAL_LIB_PATH+"/uspace/SWITZERLAND1.json"))
+	require.NoError(t, os.Mkdir(PERSONAL_LIB_PATH+"/uspace/SWITZERLAND1.json", os.ModePerm))
+	err = runData(cnf)
+	assert.ErrorContains(t, err, "error occurred writing operational intent (name=SWITZERLAND1) to file")
+}
@@ -0,0 +1,76 @@
+package dss_emulator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/dss_emulator_server"
+	"manna.aero/manna.utm.cli/pkg/lifecycle"
+)
+
+const defaultDssPort = 8082
+
+var DssEmulator = &cobra.Command{
+	Use:   "dss-emulator",
+	Short: "Start a local ASTM F3548-21 DSS on <dss_port>, seeded with the configured operational intents.",
+	Long: `Start a local ASTM F3548-21 DSS on <dss_port>, seeded with the configured operational intents.
+
+The emulator issues OVNs, checks the key of each Accepted or Activated
+operational intent against the operational intents and constraints it
+intersects, answers airspace queries and keeps the subscriptions of USSs,
+returning the subscribers to notify of each change.
+
+The configured operational intents start out Accepted, managed by their
+owner_name at their owner_baseurl, and fly from --depart-in after the server
+starts. Their OVNs are the same as those served by fake-uss, so the two can be
+run together as the pre-existing traffic of a scenario. USSs are told apart by
+the subject of their bearer token, which is not verified.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port, err := cmd.Flags().GetInt("port")
+		if err != nil {
+			return err
+		}
+		shutdownTimeout, err := cmd.Flags().GetDuration("shutdown-timeout")
+		if err != nil {
+			return err
+		}
+		departIn, err := cmd.Flags().GetDuration("depart-in")
+		if err != nil {
+			return err
+		}
+		notifySubscribers, err := cmd.Flags().GetBool("notify-subscribers")
+		if err != nil {
+			return err
+		}
+
+		c, err := config.FromContext(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("port") {
+			port = c.DssPort
+			if port == 0 {
+				port = defaultDssPort
+			}
+		}
+
+		departure := time.Now().Add(departIn)
+		dssServer := dss_emulator_server.GetServer(c.OperationalIntentConfigs, dss_emulator_server.Options{
+			BaseUrl:           fmt.Sprintf("http://localhost:%d", port),
+			Departure:         departure,
+			NotifySubscribers: notifySubscribers,
+		})
+		log.Infof("starting DSS emulator with %d operational intents departing at %s on port: %d", len(c.OperationalIntentConfigs), departure.Format(time.RFC3339), port)
+		server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: dssServer}
+		return lifecycle.Run(cmd.Context(), server, lifecycle.Options{
+			ShutdownTimeout: shutdownTimeout,
+			OnShutdown:      []func(ctx context.Context) error{dssServer.Shutdown},
+		})
+	},
+}
@@ -0,0 +1,98 @@
+package fake_uss
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"manna.aero/manna.utm.cli/pkg/cli_error"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/fake_uss_server"
+	"manna.aero/manna.utm.cli/pkg/lifecycle"
+)
+
+var FakeUss = &cobra.Command{
+	Use:   "fake-uss",
+	Short: "Start a fake peer USS that serves the configured operational intents over the ASTM F3548-21 USS API.",
+	Long: `Start a fake peer USS that serves the configured operational intents over the ASTM F3548-21 USS API.
+
+The operational intents are published as managed by their owner_name at their
+owner_baseurl, and answer details and telemetry requests as they fly from
+--depart-in after the server starts. Notifications of changes sent to the fake
+USS are recorded, and can be read back from /fake/v1/notifications, along with
+every exchange from /uss/v1/logs.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port, err := cmd.Flags().GetInt("port")
+		if err != nil {
+			return err
+		}
+		shutdownTimeout, err := cmd.Flags().GetDuration("shutdown-timeout")
+		if err != nil {
+			return err
+		}
+		owner, err := cmd.Flags().GetString("owner")
+		if err != nil {
+			return err
+		}
+		departIn, err := cmd.Flags().GetDuration("depart-in")
+		if err != nil {
+			return err
+		}
+
+		c, err := config.FromContext(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		var published []config.OperationalIntentConfig
+		for _, oiCnf := range c.OperationalIntentConfigs {
+			if owner == "" || oiCnf.OwnerName == owner {
+				published = append(published, oiCnf)
+			}
+		}
+		if len(published) == 0 {
+			return &config.Error{Err: fmt.Errorf("no operational intents are configured for the owner: %s", owner)}
+		}
+
+		if !cmd.Flags().Changed("port") {
+			port, err = ownerPort(published)
+			if err != nil {
+				return err
+			}
+		}
+
+		departure := time.Now().Add(departIn)
+		router := fake_uss_server.GetServer(published, fmt.Sprintf("http://localhost:%d", port), departure)
+		log.Infof("starting fake USS server with %d operational intents departing at %s on port: %d", len(published), departure.Format(time.RFC3339), port)
+		server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: router}
+		return lifecycle.Run(cmd.Context(), server, lifecycle.Options{ShutdownTimeout: shutdownTimeout})
+	},
+}
+
+// ownerPort is the port of the first owner_baseurl of <oiCnfs> that has a
+// port.
+func ownerPort(oiCnfs []config.OperationalIntentConfig) (int, error) {
+	for _, oiCnf := range oiCnfs {
+		if oiCnf.OwnerBaseURL == "" {
+			continue
+		}
+		u, err := url.Parse(oiCnf.OwnerBaseURL)
+		if err != nil {
+			return 0, &config.Error{Err: fmt.Errorf("owner_baseurl of operational intent %s: %w", oiCnf.Name, err)}
+		}
+		if u.Port() == "" {
+			continue
+		}
+		port, err := strconv.Atoi(u.Port())
+		if err != nil {
+			return 0, &config.Error{Err: fmt.Errorf("owner_baseurl of operational intent %s: %w", oiCnf.Name, err)}
+		}
+		return port, nil
+	}
+	return 0, &cli_error.UsageError{Err: fmt.Errorf("none of the operational intents has an owner_baseurl with a port, set --port")}
+}
@@ -0,0 +1,32 @@
+package fake_uss
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"manna.aero/manna.utm.cli/pkg/cli_error"
+	"manna.aero/manna.utm.cli/pkg/config"
+)
+
+func TestOwnerPort(t *testing.T) {
+	oiCnfs := []config.OperationalIntentConfig{
+		{Name: "none"},
+		{Name: "no port", OwnerBaseURL: "https://uss.example.com"},
+		{Name: "port", OwnerBaseURL: "http://localhost:8074/uss"},
+		{Name: "other port", OwnerBaseURL: "http://localhost:8075"},
+	}
+
+	port, err := ownerPort(oiCnfs)
+	require.NoError(t, err)
+	assert.Equal(t, 8074, port)
+
+	_, err = ownerPort(oiCnfs[:2])
+	var usageErr *cli_error.UsageError
+	assert.True(t, errors.As(err, &usageErr), "expected a usage error, got %v", err)
+
+	_, err = ownerPort([]config.OperationalIntentConfig{{Name: "invalid", OwnerBaseURL: "http://localhost:port"}})
+	var cnfErr *config.Error
+	assert.True(t, errors.As(err, &cnfErr), "expected a config error, got %v", err)
+}
@@ -0,0 +1,41 @@
+package mock_utm
+
+import (
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/lifecycle"
+	"manna.aero/manna.utm.cli/pkg/mock_utm_server"
+)
+
+var MockUtm = &cobra.Command{
+	Use:   "mock-utm",
+	Short: "Start an in-memory mock of the manna-utm U-Space interface on <manna_utm_port>.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port, err := cmd.Flags().GetInt("port")
+		if err != nil {
+			return err
+		}
+		shutdownTimeout, err := cmd.Flags().GetDuration("shutdown-timeout")
+		if err != nil {
+			return err
+		}
+
+		c, err := config.FromContext(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("port") {
+			port = c.MannaUtmPort
+		}
+
+		router := mock_utm_server.GetServer()
+		log.Infof("starting mock manna-utm server on port: %d", port)
+		server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: router}
+		return lifecycle.Run(cmd.Context(), server, lifecycle.Options{ShutdownTimeout: shutdownTimeout})
+	},
+}
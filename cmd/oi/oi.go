@@ -0,0 +1,139 @@
+// Package oi contains the commands for inspecting the operational intents
+// recorded in the local state.
+package oi
+
+import (
+	"strconv"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/spf13/cobra"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/output"
+	"manna.aero/manna.utm.cli/pkg/state"
+)
+
+var Oi = &cobra.Command{
+	Use:   "oi",
+	Short: "Inspect the operational intents created by this CLI.",
+}
+
+var List = &cobra.Command{
+	Use:   "list",
+	Short: "List the operational intents created for the configured simulation.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := storeFromCmd(cmd)
+		if err != nil {
+			return err
+		}
+		records, err := store.List()
+		if err != nil {
+			return err
+		}
+
+		return output.Print(cmd, output.FormatTable, &output.Result{
+			Data:    records,
+			Items:   output.Items(records),
+			Table:   recordsTable(records...),
+			GeoJSON: recordsGeoJson(records...),
+		})
+	},
+}
+
+var Show = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show everything recorded for the operational intent <name>.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := storeFromCmd(cmd)
+		if err != nil {
+			return err
+		}
+		record, err := store.Get(args[0])
+		if err != nil {
+			return err
+		}
+
+		return output.Print(cmd, output.FormatJSON, &output.Result{
+			Data:    record,
+			Table:   recordsTable(*record),
+			GeoJSON: recordsGeoJson(*record),
+		})
+	},
+}
+
+var History = &cobra.Command{
+	Use:   "history <name>",
+	Short: "Show the lifecycle transitions of the operational intent <name>.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := storeFromCmd(cmd)
+		if err != nil {
+			return err
+		}
+		record, err := store.Get(args[0])
+		if err != nil {
+			return err
+		}
+
+		table := &output.Table{Header: []string{"AT", "FROM", "TO", "STATUS", "OVN", "VERSION"}}
+		for _, t := range record.Transitions {
+			from := string(t.From)
+			if from == "" {
+				from = "-"
+			}
+			table.Rows = append(table.Rows, []string{output.FormatTime(t.At), from, string(t.To), strconv.Itoa(t.StatusCode), t.Ovn, strconv.Itoa(t.Version)})
+		}
+
+		return output.Print(cmd, output.FormatTable, &output.Result{
+			Data:  record.Transitions,
+			Items: output.Items(record.Transitions),
+			Table: table,
+		})
+	},
+}
+
+// recordsTable summarizes each of <records> on a row, along with where and
+// when it was created.
+func recordsTable(records ...state.OperationalIntentRecord) *output.Table {
+	t := &output.Table{Header: []string{"NAME", "ENTITY ID", "PRIORITY", "STATE", "TIME START", "TIME END", "VOLUMES", "ENVIRONMENT", "OVN", "VERSION", "UPDATED"}}
+	for _, r := range records {
+		s := output.SummarizeOperationalIntent(r.EntityId, r.State, recordDetails(r))
+		t.Rows = append(t.Rows, []string{
+			r.Name, r.EntityId, strconv.Itoa(int(s.Priority)), string(r.State), output.FormatTime(s.TimeStart), output.FormatTime(s.TimeEnd),
+			strconv.Itoa(s.Volumes), r.Environment, r.Ovn, strconv.Itoa(r.Version), output.FormatTime(r.UpdatedAt),
+		})
+	}
+	return t
+}
+
+// recordsGeoJson is the volumes last sent for each of <records>, annotated
+// with its name.
+func recordsGeoJson(records ...state.OperationalIntentRecord) *geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+	for _, r := range records {
+		for _, f := range recordDetails(r).GeoJsonFeatures() {
+			f.Properties["name"] = r.Name
+			f.Properties["entity_id"] = r.EntityId
+			f.Properties["state"] = r.State
+			fc.Append(f)
+		}
+	}
+	return fc
+}
+
+func recordDetails(r state.OperationalIntentRecord) utm.OperationalIntentDetails {
+	if r.Intent == nil {
+		return utm.OperationalIntentDetails{}
+	}
+	return utm.OperationalIntentDetailsFromUspace(r.Intent)
+}
+
+func storeFromCmd(cmd *cobra.Command) (*state.Store, error) {
+	appCnf, err := config.FromContext(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+	return state.ForConfig(appCnf), nil
+}
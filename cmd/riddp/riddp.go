@@ -1,38 +1,190 @@
 package riddp
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"manna.aero/manna.utm.cli/pkg/cli_error"
 	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/lifecycle"
+	"manna.aero/manna.utm.cli/pkg/rid_client"
 	"manna.aero/manna.utm.cli/pkg/riddp_server"
+	"manna.aero/manna.utm.cli/pkg/transport"
 )
 
+const defaultRidDpPort = 38080
+
 var RidDP = &cobra.Command{
 	Use:   "riddp",
 	Short: "Start the Remote Id Display Provider server on <rid_dp_port>.",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Start the Remote Id Display Provider server on <rid_dp_port>, or --port.
+
+The configured operational intents fly from when the server starts, and are
+served over the ASTM F3411-22a display provider API at /flights, and the
+Service Provider API at /uss/flights. /features/events streams the flights as
+server-sent events: a snapshot of the operational intents in flight with the
+last --trail-length positions of each aircraft, then oi-created, position and
+oi-ended events as the flights happen. /ws streams the same events over a
+WebSocket, filtered by the subscribe messages of the client. / is a map of
+the flights, drawn from /features/events.
+
+With --dss, the server also acts as the Service Provider of the flights: it
+creates an Identification Service Area covering each operational intent in the
+F3411 DSS at --dss, e.g. the dss-emulator at http://localhost:<dss_port>/rid/v2,
+and notifies the subscribers to each area, so that Display Providers fetch the
+flights from --base-url.
+
+The rid_dp_server section of the config sets the bind address, the browser
+origins allowed to call the server, the certificate it is served over HTTPS
+with, and its request limits and timeouts. Each has a flag that overrides it.
+
+/ready responds 200 once the simulation is initialised, and 503 before then
+and while shutting down. On SIGINT or SIGTERM the server stops the simulation,
+ends the event streams, deletes the Identification Service Areas it created
+and, with --dump-requests, writes the last snapshot to .requests.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		writeRequests, err := cmd.Flags().GetBool("dump-requests")
 		if err != nil {
 			return err
 		}
-
-		println(writeRequests)
-		c, err := config.LoadConfig("./config.yaml")
+		dssUrl, err := cmd.Flags().GetString("dss")
+		if err != nil {
+			return err
+		}
+		baseUrl, err := cmd.Flags().GetString("base-url")
 		if err != nil {
 			return err
 		}
+		trailLength, err := cmd.Flags().GetInt("trail-length")
+		if err != nil {
+			return err
+		}
+		if trailLength < 1 {
+			return &cli_error.UsageError{Err: fmt.Errorf("--trail-length must be at least 1, not %d", trailLength)}
+		}
 
-		router := riddp_server.GetServer(*c, writeRequests)
-		log.Debugf("attempting to start server on port: %d", c.RidDpPort)
-		err = router.Run(fmt.Sprintf(":%d", c.RidDpPort))
+		c, err := config.FromContext(cmd.Context())
+		if err != nil {
+			return err
+		}
+		port, err := cmd.Flags().GetInt("port")
+		if err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("port") {
+			port = c.RidDpPort
+			if port == 0 {
+				port = defaultRidDpPort
+			}
+		}
+		c.RidDpServer, err = serverConfig(cmd, c.RidDpServer)
 		if err != nil {
-			log.Fatalf("unable to start application server: %v", err)
+			return err
+		}
+		if err := c.RidDpServer.Validate(); err != nil {
+			return err
 		}
 
-		log.Infof("Server listening on port: %d", c.RidDpPort)
-		return nil
+		departure := time.Now()
+		ridServer := riddp_server.GetServer(*c, riddp_server.Options{WriteRequests: writeRequests, Departure: departure, TrailLength: trailLength})
+		onShutdown := []func(ctx context.Context) error{ridServer.Shutdown}
+
+		if dssUrl != "" {
+			dss, err := rid_client.NewRidClient(dssUrl, nil, transport.DefaultPolicy())
+			if err != nil {
+				return err
+			}
+			if baseUrl == "" {
+				scheme := "http"
+				if c.RidDpServer.TLS.Enabled() {
+					scheme = "https"
+				}
+				baseUrl = fmt.Sprintf("%s://localhost:%d", scheme, port)
+			}
+			isas, err := riddp_server.PublishIdentificationServiceAreas(cmd.Context(), dss, c.OperationalIntentConfigs, departure, baseUrl)
+			if err != nil {
+				// the areas created before the error are not left behind
+				if withdrawErr := riddp_server.WithdrawIdentificationServiceAreas(cmd.Context(), dss, isas); withdrawErr != nil {
+					log.Warn(withdrawErr)
+				}
+				return err
+			}
+			onShutdown = append(onShutdown, func(ctx context.Context) error {
+				return riddp_server.WithdrawIdentificationServiceAreas(ctx, dss, isas)
+			})
+		}
+
+		srvCnf := c.RidDpServer
+		server := &http.Server{
+			Addr:           net.JoinHostPort(srvCnf.BindAddress, strconv.Itoa(port)),
+			Handler:        ridServer,
+			ReadTimeout:    srvCnf.ReadTimeout,
+			WriteTimeout:   srvCnf.WriteTimeout,
+			IdleTimeout:    srvCnf.IdleTimeout,
+			MaxHeaderBytes: srvCnf.MaxHeaderBytes,
+		}
+		return lifecycle.Run(cmd.Context(), server, lifecycle.Options{
+			CertFile:        srvCnf.TLS.CertFile,
+			KeyFile:         srvCnf.TLS.KeyFile,
+			ShutdownTimeout: srvCnf.ShutdownTimeout,
+			OnShutdown:      onShutdown,
+		})
 	},
 }
+
+// serverConfig is <cnf> with the server flags set on <cmd> in place of its
+// values.
+func serverConfig(cmd *cobra.Command, cnf config.ServerConfig) (config.ServerConfig, error) {
+	flags := cmd.Flags()
+	var err error
+	if flags.Changed("bind") {
+		if cnf.BindAddress, err = flags.GetString("bind"); err != nil {
+			return cnf, err
+		}
+	}
+	if flags.Changed("allowed-origin") {
+		if cnf.AllowedOrigins, err = flags.GetStringSlice("allowed-origin"); err != nil {
+			return cnf, err
+		}
+	}
+	if flags.Changed("tls-cert") {
+		if cnf.TLS.CertFile, err = flags.GetString("tls-cert"); err != nil {
+			return cnf, err
+		}
+	}
+	if flags.Changed("tls-key") {
+		if cnf.TLS.KeyFile, err = flags.GetString("tls-key"); err != nil {
+			return cnf, err
+		}
+	}
+	if flags.Changed("max-request-bytes") {
+		if cnf.MaxRequestBytes, err = flags.GetInt64("max-request-bytes"); err != nil {
+			return cnf, err
+		}
+	}
+	if flags.Changed("max-header-bytes") {
+		if cnf.MaxHeaderBytes, err = flags.GetInt("max-header-bytes"); err != nil {
+			return cnf, err
+		}
+	}
+	for name, value := range map[string]*time.Duration{
+		"read-timeout":     &cnf.ReadTimeout,
+		"write-timeout":    &cnf.WriteTimeout,
+		"idle-timeout":     &cnf.IdleTimeout,
+		"shutdown-timeout": &cnf.ShutdownTimeout,
+	} {
+		if flags.Changed(name) {
+			if *value, err = flags.GetDuration(name); err != nil {
+				return cnf, err
+			}
+		}
+	}
+	return cnf, nil
+}
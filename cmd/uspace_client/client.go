@@ -0,0 +1,36 @@
+package uspace_client
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/pkg/auth"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/transport"
+	"manna.aero/manna.utm.cli/pkg/uspace_client"
+)
+
+// newMannaUtmClient creates a manna-utm client for the environment selected
+// in <ctx>.
+func newMannaUtmClient(ctx context.Context, writeRequests bool) (*uspace_client.MannaUtmClient, error) {
+	env, err := config.EnvironmentFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := env.TLS.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("using manna-utm environment %s at: %s", env.Name, env.BaseURL)
+	client, err := uspace_client.NewMannaUtmClient(env.BaseURL, tlsConfig, transport.PolicyFromEnvironment(env), writeRequests)
+	if err != nil {
+		return nil, &config.Error{Err: err}
+	}
+
+	client.TokenSource, err = auth.FromEnvironment(env, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
@@ -1,11 +1,15 @@
 package uspace_client
 
 import (
+	"fmt"
+	"time"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"manna.aero/manna.utm.cli/model/uspace/virtual_uspace"
+	"manna.aero/manna.utm.cli/model/utm"
 	"manna.aero/manna.utm.cli/pkg/config"
-	"manna.aero/manna.utm.cli/pkg/uspace_client"
+	"manna.aero/manna.utm.cli/pkg/state"
 )
 
 var CreateOperationalIntent = &cobra.Command{
@@ -22,34 +26,49 @@ var CreateOperationalIntent = &cobra.Command{
 			return err
 		}
 
-		appConfig, err := config.LoadConfig("./config.yaml")
+		appCnf, err := config.FromContext(cmd.Context())
 		if err != nil {
 			return err
 		}
-
-		mannaUtmClient, err := uspace_client.NewMannaUtmClient("localhost", appConfig.MannaUtmPort, writeRequests)
+		env, err := config.EnvironmentFromContext(cmd.Context())
 		if err != nil {
-			log.Fatalf("unable to create USS mannaUtmClient: %v", err)
+			return err
 		}
 
-		log.Debugf("attempting to create operational intent via manna-utm U-Space interface on port: %d", appConfig.MannaUtmPort)
+		store := state.ForConfig(appCnf)
+		if err := store.CheckCreate(oiName, env.Name); err != nil {
+			return err
+		}
 
-		// load config
-		appCnf, err := config.LoadConfig("./config.yaml")
+		mannaUtmClient, err := newMannaUtmClient(cmd.Context(), writeRequests)
 		if err != nil {
-			log.Fatalf("error occurred loading config: %v", err)
+			return fmt.Errorf("unable to create manna-utm client: %w", err)
 		}
 
+		log.Debugf("attempting to create operational intent via manna-utm U-Space interface")
+
 		oiCnf, err := appCnf.GetOperationalIntentConfigByName(oiName)
 		if err != nil {
-			log.Fatalf("error occurred loading operational intent config: %v", err)
+			return err
 		}
 
-		oi := virtual_uspace.virtualOiFromConfig(oiCnf)
+		oi := virtual_uspace.OperationalIntentFromConfig(oiCnf)
+
+		resp, err := mannaUtmClient.CreateOperationalIntent(cmd.Context(), oiCnf.UavId, oiCnf.MissionId.String(), oi)
+		if err != nil {
+			return fmt.Errorf("failed to create operational intent: %w", err)
+		}
 
-		err = mannaUtmClient.CreateOperationalIntent(cmd.Context(), oiCnf.UavId, oiCnf.MissionId.String(), oi)
+		err = store.RecordCreated(oiName, oiCnf.MissionId.String(), oiCnf.UavId, env.Name, oi, state.Transition{
+			To:         utm.StateAccepted,
+			At:         time.Now(),
+			Ovn:        resp.Ovn(),
+			Version:    resp.Version(),
+			StatusCode: resp.StatusCode,
+			Response:   resp.Body,
+		})
 		if err != nil {
-			log.Fatalf("failed to create operational intent: %v", err.Error())
+			log.Errorf("operational intent was created, but could not be recorded in the local state: %v", err)
 		}
 
 		return nil
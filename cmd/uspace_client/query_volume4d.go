@@ -3,11 +3,16 @@ package uspace_client
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 
+	"github.com/paulmach/orb/geojson"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/model/utm"
 	"manna.aero/manna.utm.cli/pkg/config"
-	"manna.aero/manna.utm.cli/pkg/uspace_client"
+	"manna.aero/manna.utm.cli/pkg/output"
 )
 
 var Query4dVolume = &cobra.Command{
@@ -24,30 +29,86 @@ var Query4dVolume = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		geoJsonFile, err := cmd.Flags().GetString("geojson")
+		if err != nil {
+			return err
+		}
 
-		c, err := config.LoadConfig("./config.yaml")
+		c, err := config.FromContext(cmd.Context())
 		if err != nil {
 			return err
 		}
 
-		client, err := uspace_client.NewMannaUtmClient("localhost", c.MannaUtmPort, writeRequests)
+		client, err := newMannaUtmClient(cmd.Context(), writeRequests)
+		if err != nil {
+			return fmt.Errorf("unable to create manna-utm client: %w", err)
+		}
+
+		volCnf, err := c.Get4dVolumeConfigByName(volName)
 		if err != nil {
-			log.Fatalf("unable to create USS client: %v", err)
+			return err
 		}
+		vol := uspace.GetVolume4dFromConfig(*volCnf)
 
-		log.Debugf("attempting to fetch most recent telemetry message from USS server on port: %d", c.MannaUtmPort)
-		allOperationsIn4dVolume, err := client.Query4dVolume(cmd.Context(), volName)
+		log.Debugf("attempting to query 4d volume %s via manna-utm U-Space interface", volName)
+		allOperationsIn4dVolume, err := client.Query4dVolume(cmd.Context(), volName, vol)
 		if err != nil {
-			log.Fatalf("an error occurred while querying 4d volume: %v", err)
+			return fmt.Errorf("an error occurred while querying 4d volume: %w", err)
 		}
 
-		data, err := json.MarshalIndent(allOperationsIn4dVolume, "", "    ")
+		summaries := make([]output.OperationalIntentSummary, 0, len(allOperationsIn4dVolume))
+		for _, oi := range allOperationsIn4dVolume {
+			summaries = append(summaries, output.SummarizeOperationalIntent("", "", oi))
+		}
+		err = output.Print(cmd, output.FormatJSON, &output.Result{
+			Data:    allOperationsIn4dVolume,
+			Items:   output.Items(allOperationsIn4dVolume),
+			Table:   output.OperationalIntentTable(summaries...),
+			GeoJSON: queryResultGeoJson(volName, vol, allOperationsIn4dVolume),
+		})
 		if err != nil {
-			log.Errorf("unable to marshal returned operational intent details from USS to JSON: %v", err)
+			return err
 		}
 
-		fmt.Println(string(data))
+		if geoJsonFile != "" {
+			fc := queryResultGeoJson(volName, vol, allOperationsIn4dVolume)
+			contents, err := json.MarshalIndent(fc, "", "  ")
+			if err != nil {
+				return fmt.Errorf("error occurred marshalling query result to GeoJson: %w", err)
+			}
+			err = os.MkdirAll(filepath.Dir(geoJsonFile), os.ModePerm)
+			if err != nil {
+				return fmt.Errorf("error occurred creating directory for query result GeoJson: %w", err)
+			}
+			err = os.WriteFile(geoJsonFile, contents, 0644)
+			if err != nil {
+				return fmt.Errorf("error occurred writing query result GeoJson to file: %w", err)
+			}
+			log.Infof("wrote query result GeoJson to: %s", geoJsonFile)
+		}
 
 		return nil
 	},
 }
+
+// queryResultGeoJson overlays the queried volume and the volumes of the
+// operational intents returned for it. Each feature is annotated with the
+// layer it belongs to, so that the two can be styled differently.
+func queryResultGeoJson(volName string, vol uspace.Volume4d, operationalIntents []utm.OperationalIntentDetails) *geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+
+	queried := vol.GeoJsonFeature()
+	queried.Properties["layer"] = "query"
+	queried.Properties["name"] = volName
+	fc.Append(queried)
+
+	for i, oi := range operationalIntents {
+		for _, f := range oi.GeoJsonFeatures() {
+			f.Properties["layer"] = "operational_intent"
+			f.Properties["operational_intent_index"] = i
+			fc.Append(f)
+		}
+	}
+
+	return fc
+}
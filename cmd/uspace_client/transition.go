@@ -0,0 +1,123 @@
+package uspace_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/state"
+	"manna.aero/manna.utm.cli/pkg/uspace_client"
+)
+
+// transitionFunc sends the request that moves the operational intent
+// <entityId> out of state <from>, which is empty when the local state has no
+// record of it or the transition is forced.
+type transitionFunc func(mannaUtmClient *uspace_client.MannaUtmClient, ctx context.Context, entityId string, from utm.OperationalIntentState) (*uspace_client.OperationalIntentResponse, error)
+
+// runTransition moves the operational intent named by the --name flag of
+// <cmd> to state <to> by calling <transition>. When the local state has a
+// record of the operational intent, <validate> is checked against its current
+// state before any request is sent, unless --force is set.
+func runTransition(cmd *cobra.Command, to utm.OperationalIntentState, validate func(from utm.OperationalIntentState) error, transition transitionFunc) error {
+	writeRequests, err := cmd.Flags().GetBool("dump-requests")
+	if err != nil {
+		return err
+	}
+	oiName, err := cmd.Flags().GetString("name")
+	if err != nil {
+		return err
+	}
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return err
+	}
+
+	appCnf, err := config.FromContext(cmd.Context())
+	if err != nil {
+		return err
+	}
+	env, err := config.EnvironmentFromContext(cmd.Context())
+	if err != nil {
+		return err
+	}
+	store := state.ForConfig(appCnf)
+
+	var from utm.OperationalIntentState
+	record, err := store.GetIn(oiName, env.Name)
+	var notFound *state.NotFoundError
+	if errors.As(err, &notFound) {
+		log.Warnf("operational intent %s was not created by this CLI, so the transition to %s cannot be validated", oiName, to)
+	} else if err != nil {
+		return err
+	} else if err := validate(record.State); err != nil {
+		if !force {
+			return err
+		}
+		log.Warnf("sending request despite invalid transition: %v", err)
+	} else {
+		from = record.State
+	}
+
+	entityId, err := entityIdForName(appCnf, store, oiName, env.Name)
+	if err != nil {
+		return err
+	}
+
+	mannaUtmClient, err := newMannaUtmClient(cmd.Context(), writeRequests)
+	if err != nil {
+		return fmt.Errorf("unable to create manna-utm client: %w", err)
+	}
+
+	log.Debugf("attempting to transition operational intent %s to %s via manna-utm U-Space interface", oiName, to)
+	resp, err := transition(mannaUtmClient, cmd.Context(), entityId, from)
+	if err != nil {
+		return fmt.Errorf("failed to transition operational intent to %s: %w", to, err)
+	}
+
+	if record == nil {
+		return nil
+	}
+	err = store.RecordTransition(oiName, state.Transition{
+		To:         to,
+		At:         time.Now(),
+		Ovn:        resp.Ovn(),
+		StatusCode: resp.StatusCode,
+		Response:   resp.Body,
+	})
+	if err != nil {
+		log.Errorf("operational intent transitioned to %s, but could not be recorded in the local state: %v", to, err)
+	}
+	return nil
+}
+
+// validateTransitionTo returns a validator for a transition to state <to>.
+func validateTransitionTo(to utm.OperationalIntentState) func(from utm.OperationalIntentState) error {
+	return func(from utm.OperationalIntentState) error {
+		return utm.ValidateTransition(from, to)
+	}
+}
+
+// entityIdForName returns the entity id that the operational intent called
+// <name> was created with in <environment>, falling back to the mission id in
+// the config for operational intents that were not created by this CLI.
+func entityIdForName(appCnf *config.Config, store *state.Store, name string, environment string) (string, error) {
+	record, err := store.GetIn(name, environment)
+	if err == nil {
+		return record.EntityId, nil
+	}
+	var notFound *state.NotFoundError
+	if !errors.As(err, &notFound) {
+		return "", err
+	}
+
+	oiCnf, err := appCnf.GetOperationalIntentConfigByName(name)
+	if err != nil {
+		return "", err
+	}
+	return oiCnf.MissionId.String(), nil
+}
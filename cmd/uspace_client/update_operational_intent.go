@@ -0,0 +1,163 @@
+package uspace_client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/model/uspace/virtual_uspace"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/state"
+)
+
+var UpdateOperationalIntent = &cobra.Command{
+	Use:     "us-update-operational-intent",
+	Aliases: []string{"uoi"},
+	Short:   "Update the volumes and priority of the operational intent with the name <name> to match the config, in mock-utm only.",
+	Long: `Update the volumes and priority of the operational intent with the name <name> to match the config.
+
+The UTMController of manna-utm has no routes to fetch or update an operational
+intent, so this command only works against mock-utm, and is not listed with the
+other commands.
+
+The update is made against the OVN and version of the operational intent in the
+local state, or that mock-utm holds if it was not created by this CLI. By
+default the updated operational intent keeps its departure time, so that only
+the changes made to the config are sent.`,
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		writeRequests, err := cmd.Flags().GetBool("dump-requests")
+		if err != nil {
+			return err
+		}
+		oiName, err := cmd.Flags().GetString("name")
+		if err != nil {
+			return err
+		}
+		showDiff, err := cmd.Flags().GetBool("diff")
+		if err != nil {
+			return err
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return err
+		}
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+		departNow, err := cmd.Flags().GetBool("depart-now")
+		if err != nil {
+			return err
+		}
+
+		appCnf, err := config.FromContext(cmd.Context())
+		if err != nil {
+			return err
+		}
+		env, err := config.EnvironmentFromContext(cmd.Context())
+		if err != nil {
+			return err
+		}
+		store := state.ForConfig(appCnf)
+
+		oiCnf, err := appCnf.GetOperationalIntentConfigByName(oiName)
+		if err != nil {
+			return err
+		}
+
+		mannaUtmClient, err := newMannaUtmClient(cmd.Context(), writeRequests)
+		if err != nil {
+			return fmt.Errorf("unable to create manna-utm client: %w", err)
+		}
+
+		var previous *uspace.OperationalIntent
+		var entityId, ovn string
+		var version int
+
+		record, err := store.GetIn(oiName, env.Name)
+		var notFound *state.NotFoundError
+		if errors.As(err, &notFound) {
+			record = nil
+		} else if err != nil {
+			return err
+		} else {
+			if err := utm.ValidateUpdate(record.State); err != nil {
+				if !force {
+					return err
+				}
+				log.Warnf("sending update despite invalid state: %v", err)
+			}
+			previous, entityId, ovn, version = record.Intent, record.EntityId, record.Ovn, record.Version
+		}
+
+		if previous == nil {
+			if entityId == "" {
+				entityId = oiCnf.MissionId.String()
+			}
+			log.Debugf("fetching operational intent %s from mock-utm, as it is not in the local state", entityId)
+			current, err := mannaUtmClient.GetOperationalIntent(cmd.Context(), entityId)
+			if err != nil {
+				return fmt.Errorf("failed to fetch the operational intent to update: %w", err)
+			}
+			previous = current.Intent
+			if ovn == "" {
+				ovn, version = current.Ovn, current.Version
+			}
+		}
+
+		departure := time.Now()
+		if previous != nil && !departNow {
+			departure = previous.DepartureTime
+		}
+		updated := virtual_uspace.OperationalIntentFromConfigAt(oiCnf, departure)
+
+		if previous != nil {
+			diff := uspace.DiffOperationalIntents(previous, updated)
+			if showDiff || dryRun {
+				if len(diff) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "no changes")
+				}
+				for _, line := range diff {
+					fmt.Fprintln(cmd.OutOrStdout(), line)
+				}
+			}
+			if len(diff) == 0 && !force {
+				log.Infof("operational intent %s is unchanged, not sending an update", oiName)
+				return nil
+			}
+		}
+		if dryRun {
+			return nil
+		}
+
+		log.Debugf("attempting to update operational intent %s (ovn=%s, version=%d) via manna-utm U-Space interface", oiName, ovn, version)
+		resp, err := mannaUtmClient.UpdateOperationalIntent(cmd.Context(), entityId, ovn, version, updated)
+		if err != nil {
+			return fmt.Errorf("failed to update operational intent: %w", err)
+		}
+
+		if record == nil {
+			return nil
+		}
+		newVersion := resp.Version()
+		if newVersion == 0 && version != 0 {
+			newVersion = version + 1
+		}
+		err = store.RecordUpdated(oiName, updated, state.Transition{
+			At:         time.Now(),
+			Ovn:        resp.Ovn(),
+			Version:    newVersion,
+			StatusCode: resp.StatusCode,
+			Response:   resp.Body,
+		})
+		if err != nil {
+			log.Errorf("operational intent was updated, but could not be recorded in the local state: %v", err)
+		}
+		return nil
+	},
+}
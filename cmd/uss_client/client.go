@@ -0,0 +1,35 @@
+package uss_client
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/pkg/auth"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/transport"
+	"manna.aero/manna.utm.cli/pkg/uss_client"
+)
+
+// newUssClient creates a USS client for the environment selected in <ctx>.
+func newUssClient(ctx context.Context) (*uss_client.UssClient, error) {
+	env, err := config.EnvironmentFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := env.TLS.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("using USS environment %s at: %s", env.Name, env.BaseURL)
+	client, err := uss_client.NewUssClient(env.BaseURL, tlsConfig, transport.PolicyFromEnvironment(env))
+	if err != nil {
+		return nil, &config.Error{Err: err}
+	}
+
+	client.TokenSource, err = auth.FromEnvironment(env, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
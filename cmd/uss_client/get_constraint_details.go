@@ -0,0 +1,52 @@
+package uss_client
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/paulmach/orb/geojson"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"manna.aero/manna.utm.cli/pkg/output"
+)
+
+var GetConstraintDetails = &cobra.Command{
+	Use:   "uss-cd",
+	Short: "Get constraint details for <entity_id> from the USS of the selected environment.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entityId, err := cmd.Flags().GetString("entityId")
+		if err != nil {
+			return err
+		}
+
+		client, err := newUssClient(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("unable to create USS client: %w", err)
+		}
+
+		log.Debugf("attempting to fetch constraint details from USS server")
+		constraint, err := client.GetConstraintDetails(cmd.Context(), entityId)
+		if err != nil {
+			return fmt.Errorf("unable to fetch constraint details from USS server: %w", err)
+		}
+
+		table := &output.Table{Header: []string{"ID", "TYPE", "VERSION", "TIME START", "TIME END", "VOLUMES"}}
+		table.Rows = append(table.Rows, []string{
+			constraint.Reference.ID.String(),
+			constraint.Details.Type,
+			strconv.Itoa(constraint.Reference.Version),
+			output.FormatTime(constraint.Reference.TimeStart.Value),
+			output.FormatTime(constraint.Reference.TimeEnd.Value),
+			strconv.Itoa(len(constraint.Details.Volumes)),
+		})
+		fc := geojson.NewFeatureCollection()
+		for _, vol := range constraint.Details.Volumes {
+			f := vol.GeoJsonFeature()
+			f.Properties["constraint_id"] = constraint.Reference.ID.String()
+			fc.Append(f)
+		}
+
+		return output.Print(cmd, output.FormatJSON, &output.Result{Data: constraint, Table: table, GeoJSON: fc})
+	},
+}
@@ -0,0 +1,43 @@
+package uss_client
+
+import (
+	"fmt"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"manna.aero/manna.utm.cli/pkg/output"
+)
+
+var GetLogs = &cobra.Command{
+	Use:   "uss-logs",
+	Short: "Get the exchanges recorded by the USS of the selected environment.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newUssClient(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("unable to create USS client: %w", err)
+		}
+
+		log.Debugf("attempting to fetch logs from USS server")
+		logs, err := client.GetLogs(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("unable to fetch logs from USS server: %w", err)
+		}
+
+		table := &output.Table{Header: []string{"REQUEST TIME", "ROLE", "METHOD", "URL", "STATUS", "PROBLEM"}}
+		for _, e := range logs.Logs {
+			status := "-"
+			if e.ResponseCode != 0 {
+				status = strconv.Itoa(e.ResponseCode)
+			}
+			problem := e.Problem
+			if problem == "" {
+				problem = "-"
+			}
+			table.Rows = append(table.Rows, []string{output.FormatTime(e.RequestTime.Value), string(e.RecorderRole), e.Method, e.Url, status, problem})
+		}
+
+		return output.Print(cmd, output.FormatTable, &output.Result{Data: logs, Items: output.Items(logs.Logs), Table: table})
+	},
+}
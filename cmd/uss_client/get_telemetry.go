@@ -1,41 +1,116 @@
 package uss_client
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/cli_error"
+	"manna.aero/manna.utm.cli/pkg/output"
+	"manna.aero/manna.utm.cli/pkg/transport"
 	"manna.aero/manna.utm.cli/pkg/uss_client"
 )
 
 var UssClientFetchTelemetry = &cobra.Command{
 	Use:   "uss-tel",
-	Short: "Use the USS client to fetch telemetry from the USS as the specified port.",
-	Args:  cobra.MaximumNArgs(3),
+	Short: "Use the USS client to fetch telemetry from the USS of the selected environment.",
+	Long: `Use the USS client to fetch telemetry from the USS of the selected environment.
+
+With --follow the telemetry is fetched again at each next_telemetry_opportunity
+advertised by the USS, or every --interval when it does not advertise one, and
+each new position is printed until the command is interrupted.`,
+	Args: cobra.MaximumNArgs(3),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		log.SetLevel(log.InfoLevel)
 		entityId, err := cmd.Flags().GetString("entityId")
 		if err != nil {
 			return err
 		}
-
-		appConfig, err := config.LoadConfig("./config.yaml")
+		follow, err := cmd.Flags().GetBool("follow")
+		if err != nil {
+			return err
+		}
+		interval, err := cmd.Flags().GetDuration("interval")
 		if err != nil {
 			return err
 		}
 
-		client, err := uss_client.NewUssClient(fmt.Sprintf("http://localhost:%d", appConfig.MannaUtmPort))
+		client, err := newUssClient(cmd.Context())
 		if err != nil {
-			log.Fatalf("unable to create USS client: %v", err)
+			return fmt.Errorf("unable to create USS client: %w", err)
 		}
 
-		log.Debugf("attempting to fetch most recent telemetry message from USS server on port: %d", appConfig.MannaUtmPort)
-		err = client.GetLatestTelemetryForOperationalIntentByEntityId(cmd.Context(), entityId)
+		if follow {
+			return followTelemetry(cmd, client, entityId, interval)
+		}
+
+		log.Debugf("attempting to fetch most recent telemetry message from USS server")
+		telemetry, err := client.GetOperationalIntentTelemetry(cmd.Context(), entityId)
 		if err != nil {
-			log.Fatalf("unable to fetch most recent telemetry message from USS server: %v", err)
+			return fmt.Errorf("unable to fetch most recent telemetry message from USS server: %w", err)
 		}
 
-		return nil
+		return output.Print(cmd, output.FormatJSON, telemetryResult(*telemetry))
 	},
 }
+
+// followTelemetry prints each new position of the operational intent
+// <entityId> until the command is interrupted, polling the USS at its next
+// telemetry opportunity or every <interval>.
+func followTelemetry(cmd *cobra.Command, client *uss_client.UssClient, entityId string, interval time.Duration) error {
+	if interval <= 0 {
+		return &cli_error.UsageError{Err: fmt.Errorf("--interval must be positive, got %s", interval)}
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	stream := output.NewStream(cmd, output.FormatTable)
+	var lastMeasured time.Time
+	for {
+		next := time.Now().Add(interval)
+		telemetry, err := client.GetOperationalIntentTelemetry(ctx, entityId)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case telemetryUnavailable(err):
+			log.Debugf("the USS has no telemetry for operational intent %s yet", entityId)
+		case err != nil:
+			return fmt.Errorf("unable to fetch most recent telemetry message from USS server: %w", err)
+		default:
+			next = telemetry.NextPoll(time.Now(), interval)
+			if telemetry.Telemetry != nil && telemetry.Telemetry.TimeMeasured.Value.After(lastMeasured) {
+				lastMeasured = telemetry.Telemetry.TimeMeasured.Value
+				if err := stream.Write(telemetryResult(*telemetry)); err != nil {
+					return err
+				}
+			}
+		}
+
+		log.Debugf("fetching telemetry again at %s", next.Format(time.RFC3339Nano))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+// telemetryUnavailable is whether <err> is the USS reporting that it has no
+// telemetry for the operational intent, e.g. because it is not yet activated.
+func telemetryUnavailable(err error) bool {
+	var clientErr *transport.ClientError
+	return errors.As(err, &clientErr) && clientErr.StatusCode == http.StatusPreconditionFailed
+}
+
+func telemetryResult(telemetry utm.OperationalIntentTelemetry) *output.Result {
+	return &output.Result{
+		Data:    telemetry,
+		Table:   output.TelemetryTable(telemetry),
+		GeoJSON: output.TelemetryGeoJson(telemetry),
+	}
+}
@@ -1,12 +1,29 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"manna.aero/manna.utm.cli/cmd"
+	"manna.aero/manna.utm.cli/cmd/dss_emulator"
+	"manna.aero/manna.utm.cli/cmd/fake_uss"
+	"manna.aero/manna.utm.cli/cmd/mock_utm"
+	"manna.aero/manna.utm.cli/cmd/oi"
 	"manna.aero/manna.utm.cli/cmd/riddp"
 	"manna.aero/manna.utm.cli/cmd/uspace_client"
 	"manna.aero/manna.utm.cli/cmd/uss_client"
+	"manna.aero/manna.utm.cli/pkg/cli_error"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/lifecycle"
+	"manna.aero/manna.utm.cli/pkg/output"
+	"manna.aero/manna.utm.cli/pkg/riddp_server"
+	"manna.aero/manna.utm.cli/pkg/tracing"
+	"manna.aero/manna.utm.cli/pkg/transport"
 )
 
 var (
@@ -17,13 +34,47 @@ var (
 	logLevel                string = "info"
 	writeRequestsToHttpFile bool   = false
 	volName                 string
+	configPath              string
+	envName                 string
+	geoJsonFile             string
+	force                   bool
+	showDiff                bool
+	dryRun                  bool
+	departNow               bool
+	outputFormat            string
+	follow                  bool
+	pollInterval            time.Duration
+	ownerName               string
+	departIn                time.Duration
+	notifySubscribers       bool
+	dssUrl                  string
+	baseUrl                 string
+	trailLength             int
+	bindAddress             string
+	allowedOrigins          []string
+	tlsCertFile             string
+	tlsKeyFile              string
+	maxRequestBytes         int64
+	maxHeaderBytes          int
+	readTimeout             time.Duration
+	writeTimeout            time.Duration
+	idleTimeout             time.Duration
+	shutdownTimeout         time.Duration
+	metricsFile             string
+	traceFile               string
+	otlpEndpoint            string
+	traceparent             string
+	// commandSpan is the span of the command, the parent of the spans of its
+	// requests
+	commandSpan *tracing.Span
 )
 
-const ConfigPath = "./config.yaml"
-
 var rootCmd = &cobra.Command{
 	Use:   "manna-utm-cli",
 	Short: "A command line tool for working with manna-utm.",
+	// errors are logged by main, which exits with the code for the error
+	SilenceErrors: true,
+	SilenceUsage:  true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.Help()
 		return nil
@@ -31,22 +82,78 @@ var rootCmd = &cobra.Command{
 }
 
 func init() {
-	riddp.RidDP.Flags().IntVarP(&port, "port", "p", 38080, "Listen port to bind the server to.")
-	cmd.Data.Flags().StringVar(&fromFile, "file", ConfigPath, "The path to the directory that you want to write the JSON contents of the simulation data to.")
+	riddp.RidDP.Flags().IntVarP(&port, "port", "p", 0, "Listen port to bind the server to, defaults to rid_dp_port in the config.")
+	mock_utm.MockUtm.Flags().IntVarP(&port, "port", "p", 0, "Listen port to bind the server to, defaults to manna_utm_port in the config.")
+	fake_uss.FakeUss.Flags().IntVarP(&port, "port", "p", 0, "Listen port to bind the server to, defaults to the port of the owner_baseurl of the operational intents.")
+	fake_uss.FakeUss.Flags().StringVar(&ownerName, "owner", "", "Only serve the operational intents with this owner_name.")
+	fake_uss.FakeUss.Flags().DurationVar(&departIn, "depart-in", 0, "How long after the server starts the operational intents depart.")
+	dss_emulator.DssEmulator.Flags().IntVarP(&port, "port", "p", 0, "Listen port to bind the server to, defaults to dss_port in the config.")
+	mock_utm.MockUtm.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", lifecycle.DefaultShutdownTimeout, "The time allowed for requests to finish when the server shuts down.")
+	fake_uss.FakeUss.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", lifecycle.DefaultShutdownTimeout, "The time allowed for requests to finish when the server shuts down.")
+	dss_emulator.DssEmulator.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", lifecycle.DefaultShutdownTimeout, "The time allowed for requests to finish when the server shuts down.")
+	dss_emulator.DssEmulator.Flags().DurationVar(&departIn, "depart-in", 0, "How long after the server starts the operational intents depart.")
+	dss_emulator.DssEmulator.Flags().BoolVar(&notifySubscribers, "notify-subscribers", false, "Notify the subscribers to each change, fetching the details from the uss_base_url of the changed entity, instead of leaving it to the USS that made the change.")
+	cmd.Data.Flags().StringVar(&fromFile, "file", "", "The path to the config file of the simulation that you want to generate data for.")
+	cmd.Data.Flags().MarkDeprecated("file", "use --config instead")
 
 	uss_client.UssClientFetchTelemetry.Flags().StringVar(&fromFile, "file", "", "The file that contains the JSON for the telemetry message required to send.")
-	uss_client.GetOperationalIntentDetails.Flags().StringVar(&entityId, "entityId", "", "The entityId of the operational intent to fetch latest telemetry for.")
+	uss_client.UssClientFetchTelemetry.Flags().StringVar(&entityId, "entityId", "", "The entityId of the operational intent to fetch latest telemetry for.")
+	uss_client.UssClientFetchTelemetry.Flags().BoolVarP(&follow, "follow", "f", false, "Keep fetching the telemetry at each next telemetry opportunity and print every new position.")
+	uss_client.UssClientFetchTelemetry.Flags().DurationVar(&pollInterval, "interval", 5*time.Second, "How often to fetch the telemetry with --follow when the USS does not advertise a next telemetry opportunity.")
+	uss_client.GetOperationalIntentDetails.Flags().StringVar(&entityId, "entityId", "", "The entityId of the operational intent to fetch the details of.")
+	uss_client.GetConstraintDetails.Flags().StringVar(&entityId, "entityId", "", "The entityId of the constraint to fetch the details of.")
 
-	uspace_client.Query4dVolume.Flags().StringVarP(&volName, "name", "n", "", "The name of the 4d volume in config.yaml to query.")
+	uspace_client.Query4dVolume.Flags().StringVarP(&volName, "name", "n", "", "The name of the 4d volume in the config to query.")
 	uspace_client.Query4dVolume.Flags().BoolVarP(&writeRequestsToHttpFile, "dump-requests", "d", false, "Specify true/false to enable/disable writing requests to http files.")
+	uspace_client.Query4dVolume.Flags().StringVar(&geoJsonFile, "geojson", "", "Write a GeoJSON FeatureCollection of the queried volume and the returned operational intents to <file>.")
 
 	uspace_client.CreateOperationalIntent.Flags().StringVarP(&oiName, "name", "n", "", "The name of the operational intent that you want to create.")
 	uspace_client.CreateOperationalIntent.Flags().BoolVarP(&writeRequestsToHttpFile, "dump-requests", "d", false, "Specify true/false to enable/disable writing requests to http files.")
 
-	uspace_client.EndOperationalIntent.Flags().BoolVarP(&writeRequestsToHttpFile, "dump-requests", "d", false, "Specify true/false to enable/disable writing requests to http files.")
-	uspace_client.EndOperationalIntent.Flags().StringVarP(&oiName, "name", "n", "", "the name of the operational intent that you want to delete.")
+	uspace_client.UpdateOperationalIntent.Flags().StringVarP(&oiName, "name", "n", "", "The name of the operational intent that you want to update.")
+	uspace_client.UpdateOperationalIntent.Flags().BoolVarP(&writeRequestsToHttpFile, "dump-requests", "d", false, "Specify true/false to enable/disable writing requests to http files.")
+	uspace_client.UpdateOperationalIntent.Flags().BoolVar(&showDiff, "diff", false, "Print the changes to the operational intent before sending the update.")
+	uspace_client.UpdateOperationalIntent.Flags().BoolVar(&dryRun, "dry-run", false, "Print the changes to the operational intent without sending the update.")
+	uspace_client.UpdateOperationalIntent.Flags().BoolVar(&departNow, "depart-now", false, "Reschedule the operational intent to depart now, rather than keeping its departure time.")
+	uspace_client.UpdateOperationalIntent.Flags().BoolVar(&force, "force", false, "Send the update even if the local state says it is not allowed, or nothing has changed.")
+
+	for _, transitionCmd := range []*cobra.Command{
+		uspace_client.EndOperationalIntent,
+		uspace_client.CancelOperationalIntent,
+	} {
+		transitionCmd.Flags().BoolVarP(&writeRequestsToHttpFile, "dump-requests", "d", false, "Specify true/false to enable/disable writing requests to http files.")
+		transitionCmd.Flags().StringVarP(&oiName, "name", "n", "", "The name of the operational intent that you want to transition.")
+		transitionCmd.Flags().BoolVar(&force, "force", false, "Send the request even if the local state says the transition is not allowed.")
+	}
+
+	for _, readCmd := range []*cobra.Command{
+		uspace_client.Query4dVolume,
+		uss_client.GetOperationalIntentDetails,
+		uss_client.UssClientFetchTelemetry,
+		uss_client.GetConstraintDetails,
+		uss_client.GetLogs,
+		oi.List,
+		oi.Show,
+		oi.History,
+	} {
+		readCmd.Flags().StringVarP(&outputFormat, "output", "o", "", output.FlagUsage)
+		readCmd.PreRunE = output.ValidateFlag
+	}
 
 	riddp.RidDP.Flags().BoolVarP(&writeRequestsToHttpFile, "dump-requests", "d", false, "Specify true/false to enable/disable writing requests to http files.")
+	riddp.RidDP.Flags().StringVar(&dssUrl, "dss", "", "The base URL of the F3411 DSS to create the identification service areas of the flights in, e.g. http://localhost:8082/rid/v2.")
+	riddp.RidDP.Flags().IntVar(&trailLength, "trail-length", riddp_server.DefaultTrailLength, "The number of positions of each aircraft sent to SSE clients when they connect.")
+	riddp.RidDP.Flags().StringVar(&bindAddress, "bind", "", "The address to bind the server to, defaults to bind_address of rid_dp_server in the config, or every interface.")
+	riddp.RidDP.Flags().StringSliceVar(&allowedOrigins, "allowed-origin", nil, "A browser origin allowed to call the server, or * for any. Replaces allowed_origins of rid_dp_server in the config.")
+	riddp.RidDP.Flags().StringVar(&tlsCertFile, "tls-cert", "", "The PEM certificate chain to serve HTTPS with, overriding tls.cert_file of rid_dp_server in the config.")
+	riddp.RidDP.Flags().StringVar(&tlsKeyFile, "tls-key", "", "The PEM key to serve HTTPS with, overriding tls.key_file of rid_dp_server in the config.")
+	riddp.RidDP.Flags().Int64Var(&maxRequestBytes, "max-request-bytes", 0, "The largest request body accepted, overriding max_request_bytes of rid_dp_server in the config.")
+	riddp.RidDP.Flags().IntVar(&maxHeaderBytes, "max-header-bytes", 0, "The largest request headers accepted, overriding max_header_bytes of rid_dp_server in the config.")
+	riddp.RidDP.Flags().DurationVar(&readTimeout, "read-timeout", 0, "The time allowed to read a request, overriding read_timeout of rid_dp_server in the config.")
+	riddp.RidDP.Flags().DurationVar(&writeTimeout, "write-timeout", 0, "The time allowed to write a response, overriding write_timeout of rid_dp_server in the config.")
+	riddp.RidDP.Flags().DurationVar(&idleTimeout, "idle-timeout", 0, "The time a keep-alive connection waits for the next request, overriding idle_timeout of rid_dp_server in the config.")
+	riddp.RidDP.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", 0, "The time allowed for requests to finish when the server shuts down, overriding shutdown_timeout of rid_dp_server in the config, defaults to 10s.")
+	riddp.RidDP.Flags().StringVar(&baseUrl, "base-url", "", "The URL that Display Providers fetch the flights from, defaults to http://localhost:<port>, or https with TLS.")
 }
 
 func configureLogging(level string) {
@@ -69,19 +176,110 @@ func configureLogging(level string) {
 
 func main() {
 	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "l", "info", "The log level that you want to run your command with.")
+	rootCmd.PersistentFlags().StringVarP(&envName, "env", "e", "", "The environment in the config to point client commands at, defaults to default_environment.")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", fmt.Sprintf("The config file to use, defaults to $%s or the first of: %s.", config.ConfigPathEnvVar, strings.Join(config.SearchPaths(), ", ")))
+	rootCmd.PersistentFlags().StringVar(&metricsFile, "metrics-file", "", "Write the metrics of the requests sent by the command to this file when it exits, in the Prometheus text format, e.g. to push to a Pushgateway.")
+	rootCmd.PersistentFlags().StringVar(&traceFile, "trace-file", "", "Append the spans of the requests sent by the command to this file, as OTLP JSON.")
+	rootCmd.PersistentFlags().StringVar(&otlpEndpoint, "otlp-endpoint", "", fmt.Sprintf("Export the spans of the requests sent by the command to the OTLP/HTTP collector at this URL, e.g. http://localhost:4318, defaults to $%s.", tracing.EndpointEnvVar))
+	rootCmd.PersistentFlags().StringVar(&traceparent, "traceparent", "", fmt.Sprintf("The W3C traceparent of the span that the command is part of, defaults to $%s.", tracing.TraceparentEnvVar))
 	cobra.OnInitialize(func() { configureLogging(logLevel) })
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := cli_error.ValidateFlags(cmd); err != nil {
+			return err
+		}
+		ctx, err := startTracing(cmd)
+		if err != nil {
+			return err
+		}
+		cmd.SetContext(config.NewContext(ctx, config.NewLoader(configPath, envName)))
+		return nil
+	}
 	rootCmd.AddCommand(riddp.RidDP)
 	rootCmd.AddCommand(cmd.Data)
+	rootCmd.AddCommand(mock_utm.MockUtm)
+	rootCmd.AddCommand(fake_uss.FakeUss)
+	rootCmd.AddCommand(dss_emulator.DssEmulator)
 
 	rootCmd.AddCommand(uss_client.UssClientFetchTelemetry)
 	rootCmd.AddCommand(uss_client.GetOperationalIntentDetails)
+	rootCmd.AddCommand(uss_client.GetConstraintDetails)
+	rootCmd.AddCommand(uss_client.GetLogs)
 
 	rootCmd.AddCommand(uspace_client.Query4dVolume)
 	rootCmd.AddCommand(uspace_client.CreateOperationalIntent)
+	rootCmd.AddCommand(uspace_client.UpdateOperationalIntent)
 	rootCmd.AddCommand(uspace_client.EndOperationalIntent)
 	rootCmd.AddCommand(uspace_client.CancelOperationalIntent)
 
-	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
+	oi.Oi.AddCommand(oi.List)
+	oi.Oi.AddCommand(oi.Show)
+	oi.Oi.AddCommand(oi.History)
+	rootCmd.AddCommand(oi.Oi)
+
+	cli_error.TypeArgsErrors(rootCmd)
+	rootCmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return &cli_error.UsageError{Err: fmt.Errorf("%w\nRun '%s --help' for usage.", err, c.CommandPath())}
+	})
+
+	err := rootCmd.Execute()
+	stopTracing(err)
+	if metricsFile != "" {
+		if writeErr := writeMetrics(metricsFile); writeErr != nil {
+			log.Errorf("unable to write metrics to %s: %v", metricsFile, writeErr)
+		}
+	}
+	if err != nil {
+		log.Error(err)
+		os.Exit(cli_error.ExitCode(err))
+	}
+}
+
+// writeMetrics writes the metrics of the requests sent by the clients to
+// <path>.
+func writeMetrics(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := transport.WriteMetrics(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// startTracing sets up the export of the spans of <cmd> and starts its span,
+// as a child of the span of --traceparent if it is set, returning a context
+// carrying the span.
+func startTracing(cmd *cobra.Command) (context.Context, error) {
+	if otlpEndpoint == "" {
+		otlpEndpoint = os.Getenv(tracing.EndpointEnvVar)
+	}
+	if err := tracing.Setup(tracing.Options{File: traceFile, Endpoint: otlpEndpoint}); err != nil {
+		return nil, &cli_error.UsageError{Err: err}
+	}
+
+	ctx := cmd.Context()
+	if traceparent == "" {
+		traceparent = os.Getenv(tracing.TraceparentEnvVar)
+	}
+	if traceparent != "" {
+		var err error
+		if ctx, err = tracing.ContextWithTraceparent(ctx, traceparent); err != nil {
+			return nil, &cli_error.UsageError{Err: err}
+		}
+	}
+	ctx, commandSpan = tracing.Start(ctx, cmd.CommandPath(), tracing.SpanKindInternal)
+	return ctx, nil
+}
+
+// stopTracing ends the span of the command, which failed with <err> if it is
+// not nil, and exports the spans that are left.
+func stopTracing(err error) {
+	commandSpan.End(err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := tracing.Shutdown(ctx); err != nil {
+		log.Errorf("unable to export spans: %v", err)
 	}
 }
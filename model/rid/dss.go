@@ -0,0 +1,102 @@
+package rid
+
+import (
+	"manna.aero/manna.utm.cli/model/utm"
+)
+
+// The types of the ASTM F3411-22a DSS API, and of the notifications that a
+// Service Provider sends to the subscribers to its Identification Service
+// Areas. F3411 volumes are on the wire in the same way as F3548 volumes.
+
+// IdentificationServiceArea is an area and time in which a Service Provider
+// has flights, which Display Providers fetch from its <UssBaseUrl>. Its
+// <Version> is opaque.
+type IdentificationServiceArea struct {
+	Id         string   `json:"id"`
+	UssBaseUrl string   `json:"uss_base_url"`
+	Owner      string   `json:"owner"`
+	TimeStart  utm.Time `json:"time_start"`
+	TimeEnd    utm.Time `json:"time_end"`
+	Version    string   `json:"version"`
+}
+
+type CreateIdentificationServiceAreaParameters struct {
+	Extents    utm.Volume4d `json:"extents"`
+	UssBaseUrl string       `json:"uss_base_url"`
+}
+
+type SubscriptionState struct {
+	SubscriptionId    string `json:"subscription_id"`
+	NotificationIndex int    `json:"notification_index"`
+}
+
+// SubscriberToNotify is a USS to notify of a change to an Identification
+// Service Area at its <Url>, with the subscriptions of the USS that the change
+// is relevant to.
+type SubscriberToNotify struct {
+	Subscriptions []SubscriptionState `json:"subscriptions"`
+	Url           string              `json:"url"`
+}
+
+type PutIdentificationServiceAreaResponse struct {
+	Subscribers []SubscriberToNotify      `json:"subscribers"`
+	ServiceArea IdentificationServiceArea `json:"service_area"`
+}
+
+type DeleteIdentificationServiceAreaResponse struct {
+	Subscribers []SubscriberToNotify      `json:"subscribers"`
+	ServiceArea IdentificationServiceArea `json:"service_area"`
+}
+
+type GetIdentificationServiceAreaResponse struct {
+	ServiceArea IdentificationServiceArea `json:"service_area"`
+}
+
+type SearchIdentificationServiceAreasResponse struct {
+	ServiceAreas []IdentificationServiceArea `json:"service_areas"`
+}
+
+// PutIdentificationServiceAreaNotificationParameters notifies a subscriber
+// that an Identification Service Area has changed. <ServiceArea> and
+// <Extents> are nil when it has been removed.
+type PutIdentificationServiceAreaNotificationParameters struct {
+	Subscriptions []SubscriptionState        `json:"subscriptions"`
+	ServiceArea   *IdentificationServiceArea `json:"service_area,omitempty"`
+	Extents       *utm.Volume4d              `json:"extents,omitempty"`
+}
+
+type CreateSubscriptionParameters struct {
+	Extents    utm.Volume4d `json:"extents"`
+	UssBaseUrl string       `json:"uss_base_url"`
+}
+
+// Subscription is a request of a Display Provider to be notified of the
+// changes to Identification Service Areas in an area.
+type Subscription struct {
+	Id                string   `json:"id"`
+	UssBaseUrl        string   `json:"uss_base_url"`
+	Owner             string   `json:"owner"`
+	NotificationIndex int      `json:"notification_index"`
+	TimeStart         utm.Time `json:"time_start"`
+	TimeEnd           utm.Time `json:"time_end"`
+	Version           string   `json:"version"`
+}
+
+// PutSubscriptionResponse is the subscription that was created or updated,
+// along with the Identification Service Areas already in its area.
+type PutSubscriptionResponse struct {
+	ServiceAreas []IdentificationServiceArea `json:"service_areas"`
+	Subscription Subscription                `json:"subscription"`
+}
+
+type GetSubscriptionResponse struct {
+	Subscription Subscription `json:"subscription"`
+}
+
+type DeleteSubscriptionResponse struct {
+	Subscription Subscription `json:"subscription"`
+}
+
+type SearchSubscriptionsResponse struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+}
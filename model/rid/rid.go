@@ -0,0 +1,109 @@
+// Package rid holds the types of the ASTM F3411-22a Network Remote ID API, as
+// they are sent on the wire.
+//
+// see https://github.com/uastech/standards/blob/astm_rid_api_2.1/remoteid/canonical.yaml
+package rid
+
+import (
+	"time"
+
+	"manna.aero/manna.utm.cli/model/utm"
+)
+
+const (
+	// NetMaxNearRealTimeDataPeriod is how far back the recent positions of a
+	// flight go.
+	NetMaxNearRealTimeDataPeriod = 60 * time.Second
+	// NetMaxDisplayAreaDiagonal is the diagonal, in metres, of the largest
+	// view that flights are returned for.
+	NetMaxDisplayAreaDiagonal = 7000.0
+	// NetDetailsMaxDisplayAreaDiagonal is the diagonal, in metres, of the
+	// largest view that individual flights are returned for. Wider views get
+	// clusters.
+	NetDetailsMaxDisplayAreaDiagonal = 2000.0
+	// NetMinClusterSize is the smallest a cluster may be, as a fraction of the
+	// diagonal of the view.
+	NetMinClusterSize = 0.15
+	// AircraftTypeOther is the UA type of a flight whose type is unknown.
+	AircraftTypeOther = "Other"
+)
+
+type RIDAircraftPosition struct {
+	Lat          float64 `json:"lat"`
+	Lng          float64 `json:"lng"`
+	Alt          float64 `json:"alt"`
+	AccuracyH    string  `json:"accuracy_h,omitempty"`
+	AccuracyV    string  `json:"accuracy_v,omitempty"`
+	Extrapolated bool    `json:"extrapolated,omitempty"`
+}
+
+// RIDAircraftState is the state of an aircraft at <Timestamp>. <Track> is in
+// degrees clockwise from true north and <Speed> in metres per second.
+type RIDAircraftState struct {
+	Timestamp         utm.Time            `json:"timestamp"`
+	TimestampAccuracy float64             `json:"timestamp_accuracy"`
+	OperationalStatus string              `json:"operational_status,omitempty"`
+	Position          RIDAircraftPosition `json:"position"`
+	Track             float64             `json:"track"`
+	Speed             float64             `json:"speed"`
+	SpeedAccuracy     string              `json:"speed_accuracy"`
+	VerticalSpeed     float64             `json:"vertical_speed"`
+}
+
+type RIDRecentAircraftPosition struct {
+	Time     utm.Time            `json:"time"`
+	Position RIDAircraftPosition `json:"position"`
+}
+
+type RIDFlight struct {
+	Id              string                      `json:"id"`
+	AircraftType    string                      `json:"aircraft_type"`
+	CurrentState    *RIDAircraftState           `json:"current_state,omitempty"`
+	Simulated       bool                        `json:"simulated"`
+	RecentPositions []RIDRecentAircraftPosition `json:"recent_positions,omitempty"`
+}
+
+// Cluster stands in for the flights within <Corners> in views too wide to
+// show them individually. It is not part of F3411, but of the display
+// provider observation API used by the InterUSS automated tests.
+type Cluster struct {
+	Corners         [2]utm.LatLngPoint `json:"corners"`
+	AreaSqm         float64            `json:"area_sqm"`
+	NumberOfFlights int                `json:"number_of_flights"`
+}
+
+// GetFlightsResponse is the flights in a view, or the clusters of them if the
+// view is too wide.
+type GetFlightsResponse struct {
+	Timestamp utm.Time    `json:"timestamp"`
+	Flights   []RIDFlight `json:"flights"`
+	Clusters  []Cluster   `json:"clusters,omitempty"`
+}
+
+// UASID identifies an aircraft. <SerialNumber> is in the ANSI/CTA-2063-A
+// format.
+type UASID struct {
+	SerialNumber   string `json:"serial_number,omitempty"`
+	RegistrationId string `json:"registration_id,omitempty"`
+	UtmId          string `json:"utm_id,omitempty"`
+}
+
+type OperatorLocation struct {
+	Position utm.LatLngPoint `json:"position"`
+}
+
+type RIDFlightDetails struct {
+	Id                   string            `json:"id"`
+	OperatorId           string            `json:"operator_id,omitempty"`
+	OperatorLocation     *OperatorLocation `json:"operator_location,omitempty"`
+	OperationDescription string            `json:"operation_description,omitempty"`
+	UasId                *UASID            `json:"uas_id,omitempty"`
+}
+
+type GetFlightDetailsResponse struct {
+	Details RIDFlightDetails `json:"details"`
+}
+
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
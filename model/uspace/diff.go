@@ -0,0 +1,100 @@
+package uspace
+
+import (
+	"fmt"
+	"time"
+)
+
+// DiffOperationalIntents describes each change between <old> and <new>, one
+// line per change, comparing volumes and waypoints by index. Times are
+// compared to the millisecond, as that is all that manna-utm keeps.
+func DiffOperationalIntents(old *OperationalIntent, new *OperationalIntent) []string {
+	var diff []string
+
+	if old.Priority != new.Priority {
+		diff = append(diff, fmt.Sprintf("priority: %d -> %d", old.Priority, new.Priority))
+	}
+	if !sameMilli(old.DepartureTime, new.DepartureTime) {
+		diff = append(diff, fmt.Sprintf("departure_time: %s -> %s", formatTime(old.DepartureTime), formatTime(new.DepartureTime)))
+	}
+
+	for i := 0; i < max(len(old.Volumes), len(new.Volumes)); i++ {
+		switch {
+		case i >= len(old.Volumes):
+			diff = append(diff, fmt.Sprintf("volume %d: added %s", i, describeVolume(new.Volumes[i])))
+		case i >= len(new.Volumes):
+			diff = append(diff, fmt.Sprintf("volume %d: removed %s", i, describeVolume(old.Volumes[i])))
+		default:
+			diff = append(diff, diffVolumes(i, old.Volumes[i], new.Volumes[i])...)
+		}
+	}
+
+	for i := 0; i < max(len(old.Waypoints), len(new.Waypoints)); i++ {
+		switch {
+		case i >= len(old.Waypoints):
+			diff = append(diff, fmt.Sprintf("waypoint %d: added %s", i, describeWaypoint(new.Waypoints[i])))
+		case i >= len(new.Waypoints):
+			diff = append(diff, fmt.Sprintf("waypoint %d: removed %s", i, describeWaypoint(old.Waypoints[i])))
+		default:
+			o, n := old.Waypoints[i], new.Waypoints[i]
+			if o.Latitude != n.Latitude || o.Longitude != n.Longitude || o.Altitude != n.Altitude || !sameMilli(o.Time, n.Time) {
+				diff = append(diff, fmt.Sprintf("waypoint %d: %s -> %s", i, describeWaypoint(o), describeWaypoint(n)))
+			}
+		}
+	}
+
+	return diff
+}
+
+func diffVolumes(i int, old Volume4d, new Volume4d) []string {
+	var diff []string
+	if !sameMilli(old.TimeStart, new.TimeStart) || !sameMilli(old.TimeEnd, new.TimeEnd) {
+		diff = append(diff, fmt.Sprintf("volume %d: time %s–%s -> %s–%s", i,
+			formatTime(old.TimeStart), formatTime(old.TimeEnd), formatTime(new.TimeStart), formatTime(new.TimeEnd)))
+	}
+	if old.AltitudeLower != new.AltitudeLower || old.AltitudeUpper != new.AltitudeUpper {
+		diff = append(diff, fmt.Sprintf("volume %d: altitude %g–%g -> %g–%g", i,
+			old.AltitudeLower, old.AltitudeUpper, new.AltitudeLower, new.AltitudeUpper))
+	}
+	if !samePolygon(old, new) {
+		diff = append(diff, fmt.Sprintf("volume %d: polygon changed", i))
+	}
+	return diff
+}
+
+// samePolygon compares the polygons of <a> and <b> at the float32 precision
+// that they are sent to manna-utm with.
+func samePolygon(a Volume4d, b Volume4d) bool {
+	if len(a.Polygon) != len(b.Polygon) {
+		return false
+	}
+	for r := range a.Polygon {
+		if len(a.Polygon[r]) != len(b.Polygon[r]) {
+			return false
+		}
+		for p := range a.Polygon[r] {
+			for axis := 0; axis < 2; axis++ {
+				if float32(a.Polygon[r][p][axis]) != float32(b.Polygon[r][p][axis]) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func describeVolume(vol Volume4d) string {
+	return fmt.Sprintf("%s–%s at %g–%g", formatTime(vol.TimeStart), formatTime(vol.TimeEnd), vol.AltitudeLower, vol.AltitudeUpper)
+}
+
+func describeWaypoint(wp Waypoint) string {
+	return fmt.Sprintf("(%g, %g) at %g, %s", wp.Latitude, wp.Longitude, wp.Altitude, formatTime(wp.Time))
+}
+
+func sameMilli(a time.Time, b time.Time) bool {
+	return a.UnixMilli() == b.UnixMilli()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("15:04:05.000")
+}
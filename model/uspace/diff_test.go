@@ -0,0 +1,34 @@
+package uspace
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffOperationalIntents(t *testing.T) {
+	start := time.Now()
+	end := start.Add(time.Minute)
+	old := &OperationalIntent{
+		Priority:      0,
+		DepartureTime: start,
+		Volumes:       []Volume4d{squareVolume(46.19, 6.12, 0.01, start, end)},
+	}
+
+	assert.Empty(t, DiffOperationalIntents(old, old))
+
+	higher := squareVolume(46.19, 6.12, 0.01, start, end)
+	higher.AltitudeUpper = 250
+	updated := &OperationalIntent{
+		Priority:      10,
+		DepartureTime: start,
+		Volumes:       []Volume4d{higher, squareVolume(46.2, 6.13, 0.01, end, end.Add(time.Minute))},
+	}
+
+	diff := DiffOperationalIntents(old, updated)
+	assert.Len(t, diff, 3)
+	assert.Equal(t, "priority: 0 -> 10", diff[0])
+	assert.Equal(t, "volume 0: altitude 100–200 -> 100–250", diff[1])
+	assert.Contains(t, diff[2], "volume 1: added")
+}
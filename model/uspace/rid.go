@@ -19,7 +19,7 @@ type Telemetry struct {
 }
 
 func (t Telemetry) GeoJsonFeature() *geojson.Feature {
-	f := geojson.NewFeature(orb.Point{t.Latitude, t.Longitude})
+	f := geojson.NewFeature(orb.Point{t.Longitude, t.Latitude})
 	f.Properties["time_measured"] = t.TimeMeasured
 	f.Properties["altitude"] = t.Altitude
 	return f
@@ -61,8 +61,45 @@ func (vol Volume4d) MarshalJSON() ([]byte, error) {
 	})
 }
 
+func (vol *Volume4d) UnmarshalJSON(data []byte) error {
+	var in volume4dJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	ring := make(orb.Ring, 0, len(in.Polygon)+1)
+	for _, v := range in.Polygon {
+		ring = append(ring, orb.Point{float64(v.Latitude), float64(v.Longitude)})
+	}
+	if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+		ring = append(ring, ring[0])
+	}
+
+	vol.TimeStart = time.UnixMilli(in.TimeStart)
+	vol.TimeEnd = time.UnixMilli(in.TimeEnd)
+	vol.AltitudeLower = float64(in.AltitudeLower)
+	vol.AltitudeUpper = float64(in.AltitudeUpper)
+	vol.Polygon = orb.Polygon{ring}
+	vol.Wsg84 = in.Wsg84
+	return nil
+}
+
+// Intersects reports whether <vol> and <other> overlap in time, altitude and
+// in the horizontal plane.
+func (vol Volume4d) Intersects(other Volume4d) bool {
+	if vol.TimeStart.After(other.TimeEnd) || other.TimeStart.After(vol.TimeEnd) {
+		return false
+	}
+	if vol.AltitudeLower > other.AltitudeUpper || other.AltitudeLower > vol.AltitudeUpper {
+		return false
+	}
+	return geo.PolygonsIntersect(vol.Polygon, other.Polygon)
+}
+
+// GeoJsonFeature returns <vol> as a GeoJSON feature. The polygon of <vol> is
+// ordered [lat, lng], so its axes are swapped for GeoJSON.
 func (vol Volume4d) GeoJsonFeature() *geojson.Feature {
-	f := geojson.NewFeature(vol.Polygon)
+	f := geojson.NewFeature(geo.SwapPolygonAxes(vol.Polygon))
 	f.Properties["altitude_lower"] = vol.AltitudeLower
 	f.Properties["altitude_upper"] = vol.AltitudeUpper
 	f.Properties["time_start"] = vol.TimeStart
@@ -103,7 +140,7 @@ func (wp Waypoint) ToPoint() orb.Point {
 }
 
 func (wp Waypoint) GeoJsonFeature() *geojson.Feature {
-	f := geojson.NewFeature(wp.ToPoint())
+	f := geojson.NewFeature(orb.Point{wp.Longitude, wp.Latitude})
 	f.Properties["altitude"] = wp.Altitude
 	f.Properties["time"] = wp.Time
 	return f
@@ -130,6 +167,20 @@ func (wp Waypoint) MarshalJSON() ([]byte, error) {
 	return json.Marshal(out)
 }
 
+func (wp *Waypoint) UnmarshalJSON(data []byte) error {
+	var in waypointJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	wp.Altitude = in.Altitude
+	wp.Latitude = in.Latitude
+	wp.Longitude = in.Longitude
+	wp.Delta = in.Delta
+	wp.Time = time.UnixMilli(in.Time)
+	return nil
+}
+
 // OperationalIntent is equivalent to the type [MannaUspaceOperationalIntent]
 // in manna-utm.
 //
@@ -157,6 +208,29 @@ func (oi *OperationalIntent) MarshalJSON() ([]byte, error) {
 	}, "", "  ")
 }
 
+func (oi *OperationalIntent) UnmarshalJSON(data []byte) error {
+	var in operationalIntentJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	oi.Priority = in.Priority
+	oi.DepartureTime = time.UnixMilli(in.DepartureTime)
+	oi.Volumes = in.Volumes
+	oi.Waypoints = in.Waypoints
+	return nil
+}
+
+// Intersects reports whether any of the volumes of <oi> intersect <vol>.
+func (oi *OperationalIntent) Intersects(vol Volume4d) bool {
+	for _, v := range oi.Volumes {
+		if v.Intersects(vol) {
+			return true
+		}
+	}
+	return false
+}
+
 func (oi *OperationalIntent) ToReader() (*bytes.Reader, error) {
 	jsonBytes, err := oi.MarshalJSON()
 	if err != nil {
@@ -165,3 +239,22 @@ func (oi *OperationalIntent) ToReader() (*bytes.Reader, error) {
 
 	return bytes.NewReader(jsonBytes), nil
 }
+
+// OperationalIntentUpdate is the body of a request to replace the volumes and
+// priority of an existing operational intent. <Ovn> and <Version> are those of
+// the operational intent being replaced, so that the update is rejected if it
+// has changed since.
+type OperationalIntentUpdate struct {
+	Ovn               string             `json:"ovn"`
+	Version           int                `json:"version"`
+	OperationalIntent *OperationalIntent `json:"operational_intent"`
+}
+
+func (oiu *OperationalIntentUpdate) ToReader() (*bytes.Reader, error) {
+	jsonBytes, err := json.MarshalIndent(oiu, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(jsonBytes), nil
+}
@@ -0,0 +1,64 @@
+package uspace
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"manna.aero/manna.utm.cli/pkg/geo"
+)
+
+func squareVolume(lat float64, lng float64, size float64, start time.Time, end time.Time) Volume4d {
+	return Volume4d{
+		TimeStart:     start,
+		TimeEnd:       end,
+		AltitudeLower: 100,
+		AltitudeUpper: 200,
+		Polygon: geo.PolygonFromCoords([][2]float64{
+			{lat, lng},
+			{lat + size, lng},
+			{lat + size, lng + size},
+			{lat, lng + size},
+		}),
+	}
+}
+
+func TestVolume4d_Intersects(t *testing.T) {
+	start := time.Now()
+	end := start.Add(time.Minute)
+	vol := squareVolume(46.19, 6.12, 0.01, start, end)
+
+	assert.True(t, vol.Intersects(squareVolume(46.195, 6.125, 0.01, start, end)), "overlapping squares")
+	assert.True(t, vol.Intersects(squareVolume(46.192, 6.122, 0.001, start, end)), "contained square")
+	assert.False(t, vol.Intersects(squareVolume(46.3, 6.3, 0.01, start, end)), "disjoint squares")
+	assert.False(t, vol.Intersects(squareVolume(46.195, 6.125, 0.01, end.Add(time.Second), end.Add(time.Minute))), "disjoint in time")
+
+	higher := squareVolume(46.195, 6.125, 0.01, start, end)
+	higher.AltitudeLower, higher.AltitudeUpper = 300, 400
+	assert.False(t, vol.Intersects(higher), "disjoint in altitude")
+}
+
+func TestOperationalIntent_JSONRoundTrip(t *testing.T) {
+	start := time.UnixMilli(time.Now().UnixMilli())
+	oi := &OperationalIntent{
+		Priority:      3,
+		DepartureTime: start,
+		Volumes:       []Volume4d{squareVolume(46.19, 6.12, 0.01, start, start.Add(time.Minute))},
+		Waypoints:     []Waypoint{{Altitude: 150, Latitude: 46.19, Longitude: 6.12, Time: start}},
+	}
+
+	data, err := oi.MarshalJSON()
+	assert.NoError(t, err)
+
+	var decoded OperationalIntent
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+
+	assert.Equal(t, oi.Priority, decoded.Priority)
+	assert.True(t, oi.DepartureTime.Equal(decoded.DepartureTime))
+	assert.Len(t, decoded.Volumes, 1)
+	assert.Len(t, decoded.Volumes[0].Polygon[0], 5)
+	assert.InDelta(t, 46.19, decoded.Volumes[0].Polygon[0][0][0], 0.0001)
+	assert.InDelta(t, 6.12, decoded.Volumes[0].Polygon[0][0][1], 0.0001)
+	assert.True(t, decoded.Waypoints[0].Time.Equal(start))
+}
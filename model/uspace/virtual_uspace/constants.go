@@ -3,4 +3,8 @@ package virtual_uspace
 const (
 	AltLower = 100
 	AltUpper = 200
+
+	// DefaultDetailFactor is the number of telemetry messages interpolated
+	// between each pair of configured waypoints.
+	DefaultDetailFactor = 10
 )
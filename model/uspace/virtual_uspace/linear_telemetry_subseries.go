@@ -54,10 +54,11 @@ func transformTelemetry(input uspace.Telemetry, transformation uspace.Telemetry,
 func createTelemetryMessage(twoDimensionalPoint orb.Point, timeMeasured time.Time) uspace.Telemetry {
 	altitudeDelta := AltUpper - AltLower
 	altitude := float64(AltLower + (altitudeDelta / 2))
+	// waypoints are ordered [lat, lng], unlike orb's [lng, lat]
 	return uspace.Telemetry{
 		Altitude:      altitude,
-		Latitude:      twoDimensionalPoint.Lat(),
-		Longitude:     twoDimensionalPoint.Lon(),
+		Latitude:      twoDimensionalPoint[0],
+		Longitude:     twoDimensionalPoint[1],
 		Heading:       0,
 		Speed:         100,
 		VerticalSpeed: 0,
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/paulmach/orb"
 	"github.com/stretchr/testify/assert"
 	"manna.aero/manna.utm.cli/pkg/config"
 )
@@ -24,7 +26,7 @@ func TestTelemetrySubSeries(t *testing.T) {
 }
 
 func TestVirtualTelemetrySeries_IncreaseDetail(t *testing.T) {
-	appCnf, err := config.LoadConfig("/Users/michael.fennelly/projects/manna-utm-cli/config.yaml")
+	appCnf, err := config.LoadConfig("../../../config.yaml")
 	assert.NoError(t, err)
 
 	oicnf, err := appCnf.GetOperationalIntentConfigByName("SWITZERLAND1")
@@ -39,3 +41,15 @@ func TestVirtualTelemetrySeries_IncreaseDetail(t *testing.T) {
 
 	err = os.WriteFile("./test.geojson", jsonData, os.ModePerm)
 }
+
+func TestCreateTelemetryMessage(t *testing.T) {
+	measured := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	// a [lat, lng] waypoint in Switzerland
+	telemetry := createTelemetryMessage(orb.Point{46.41, 6.48}, measured)
+	assert.Equal(t, 46.41, telemetry.Latitude)
+	assert.Equal(t, 6.48, telemetry.Longitude)
+	assert.Equal(t, measured.UnixMilli(), telemetry.TimeMeasured)
+
+	f := telemetry.GeoJsonFeature()
+	assert.Equal(t, orb.Point{6.48, 46.41}, f.Geometry)
+}
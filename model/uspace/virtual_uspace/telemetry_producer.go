@@ -3,6 +3,7 @@ package virtual_uspace
 import (
 	"time"
 
+	"github.com/paulmach/orb/geojson"
 	log "github.com/sirupsen/logrus"
 	"manna.aero/manna.utm.cli/model/uspace"
 )
@@ -10,17 +11,38 @@ import (
 func (oim *OperationalIntentManager) ProduceTelemetryMessagesToBus(bus *TelemetryBus, interval time.Duration) {
 	for _, t := range oim.telemetry {
 		log.Tracef("sending telemetry message to bus for operational intent.")
-		bus.TelemetryEvents <- t
+		bus.TelemetryEvents <- TelemetryMessage{missionId: oim.missionId, telemetry: t}
 		time.Sleep(interval)
 	}
 }
 
+// TelemetryMessage is a telemetry message produced for the operational
+// intent of a mission.
+type TelemetryMessage struct {
+	missionId string
+	telemetry uspace.Telemetry
+}
+
+func (tm TelemetryMessage) GetMissionId() string {
+	return tm.missionId
+}
+
+func (tm TelemetryMessage) GetTelemetry() uspace.Telemetry {
+	return tm.telemetry
+}
+
+func (tm TelemetryMessage) ToGeoJsonFeature() *geojson.Feature {
+	f := tm.telemetry.GeoJsonFeature()
+	f.Properties["mission_id"] = tm.missionId
+	return f
+}
+
 type TelemetryBus struct {
-	TelemetryEvents chan uspace.Telemetry
+	TelemetryEvents chan TelemetryMessage
 }
 
 func (tb TelemetryBus) NewBus(bufferSize int) *TelemetryBus {
 	return &TelemetryBus{
-		TelemetryEvents: make(chan uspace.Telemetry, bufferSize),
+		TelemetryEvents: make(chan TelemetryMessage, bufferSize),
 	}
 }
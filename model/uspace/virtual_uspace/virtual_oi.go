@@ -40,6 +40,9 @@ import (
 // `virtualSubOperationalIntent` instances. Each of these instances is
 // the aforementioned 'subpart' of this OperationalIntentManager.
 type OperationalIntentManager struct {
+	missionId string
+	priority  uint16
+
 	// telemetry series
 	df int
 
@@ -60,9 +63,17 @@ type OperationalIntentManager struct {
 //
 // [UTMController]: https://github.com/m4a3/manna-utm/blob/persistence/src/main/java/manna/aero/utm/controller/UTMController.java#L73-L91
 func NewOperationalIntentManager(oiCnf *config.OperationalIntentConfig, df int) *OperationalIntentManager {
+	return NewOperationalIntentManagerAt(oiCnf, df, time.Now())
+}
+
+// NewOperationalIntentManagerAt is [NewOperationalIntentManager] with the
+// operational intent departing at <departure> rather than now.
+func NewOperationalIntentManagerAt(oiCnf *config.OperationalIntentConfig, df int, departure time.Time) *OperationalIntentManager {
 	nP := len(oiCnf.WaypointCoordinates) - 1
 
 	voi := OperationalIntentManager{
+		missionId: oiCnf.MissionId.String(),
+		priority:  oiCnf.Priority,
 		df:        df,
 		telemetry: make([]uspace.Telemetry, nP*df),
 		waypoints: make([]uspace.Waypoint, nP),
@@ -70,7 +81,7 @@ func NewOperationalIntentManager(oiCnf *config.OperationalIntentConfig, df int)
 	}
 
 	var wg sync.WaitGroup
-	curTime := time.Now()
+	curTime := departure
 	timeIncrement := oiCnf.Duration / time.Duration(len(oiCnf.WaypointCoordinates))
 	for i := 0; i+1 <= nP; i++ {
 		nextTime := curTime.Add(timeIncrement)
@@ -86,18 +97,33 @@ func NewOperationalIntentManager(oiCnf *config.OperationalIntentConfig, df int)
 				log.Errorf("an error occurred interpolating features for subarray at index %d: %v", vsoi.index, err)
 			}
 		}()
+		curTime = nextTime
 	}
 
 	wg.Wait()
 
-	voi.departureTime = time.Now()
+	voi.departureTime = departure
 
 	return &voi
 }
 
+// OperationalIntentFromConfig constructs the manna-utm U-Space operational
+// intent for <oiCnf>, interpolated with the default detail factor.
+func OperationalIntentFromConfig(oiCnf *config.OperationalIntentConfig) *uspace.OperationalIntent {
+	oi := NewOperationalIntentManager(oiCnf, DefaultDetailFactor).getOi()
+	return &oi
+}
+
+// OperationalIntentFromConfigAt is [OperationalIntentFromConfig] with the
+// operational intent departing at <departure>.
+func OperationalIntentFromConfigAt(oiCnf *config.OperationalIntentConfig, departure time.Time) *uspace.OperationalIntent {
+	oi := NewOperationalIntentManagerAt(oiCnf, DefaultDetailFactor, departure).getOi()
+	return &oi
+}
+
 func (oim *OperationalIntentManager) getOi() uspace.OperationalIntent {
 	return uspace.OperationalIntent{
-		Priority:      0,
+		Priority:      oim.priority,
 		DepartureTime: oim.departureTime,
 		Volumes:       oim.volumes,
 		Waypoints:     oim.waypoints,
@@ -125,3 +151,20 @@ func (oim *OperationalIntentManager) GeoJson(includeVols bool, includeWaypoints
 
 	return fc
 }
+
+// Telemetry is the interpolated telemetry of the operational intent, in the
+// order it is measured.
+func (oim *OperationalIntentManager) Telemetry() []uspace.Telemetry {
+	oim.telemetryLock.Lock()
+	defer oim.telemetryLock.Unlock()
+	return append([]uspace.Telemetry(nil), oim.telemetry...)
+}
+
+// TelemetryInterval is the time between consecutive telemetry messages when
+// the interpolated telemetry is spread evenly over <duration>.
+func (oim *OperationalIntentManager) TelemetryInterval(duration time.Duration) time.Duration {
+	if len(oim.telemetry) == 0 {
+		return duration
+	}
+	return duration / time.Duration(len(oim.telemetry))
+}
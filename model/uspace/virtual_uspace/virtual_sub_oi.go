@@ -84,7 +84,10 @@ func (vsoi *virtualSubOi) initVolume4ds() {
 func (vsoi *virtualSubOi) initWaypoints() {
 	vsoi.parentOi.waypointLock.Lock()
 	vsoi.parentOi.waypoints[vsoi.index] = uspace.Waypoint{
-		Altitude: AltLower + ((AltUpper - AltLower) / 2),
+		Altitude:  AltLower + ((AltUpper - AltLower) / 2),
+		Latitude:  vsoi.p1[0],
+		Longitude: vsoi.p1[1],
+		Time:      vsoi.startTime,
 	}
 	vsoi.parentOi.waypointLock.Unlock()
 }
@@ -0,0 +1,168 @@
+package utm
+
+import (
+	"github.com/google/uuid"
+	"manna.aero/manna.utm.cli/pkg/geo"
+)
+
+// The types of the ASTM F3548-21 DSS API, as they are sent on the wire.
+//
+// see https://github.com/astm-utm/Protocol/blob/v1.0.0/utm.yaml
+
+// ImplicitSubscriptionParameters asks the DSS to create a subscription for an
+// operational intent, covering its extents.
+type ImplicitSubscriptionParameters struct {
+	UssBaseUrl           UssBaseURL `json:"uss_base_url"`
+	NotifyForConstraints bool       `json:"notify_for_constraints,omitempty"`
+}
+
+// PutOperationalIntentReferenceParameters creates or updates the reference to
+// an operational intent. <Key> holds the OVN of every operational intent that
+// the extents intersect, to prove that they were deconflicted against.
+type PutOperationalIntentReferenceParameters struct {
+	Extents         []Volume4d                      `json:"extents"`
+	Key             []EntityOvn                     `json:"key,omitempty"`
+	State           OperationalIntentState          `json:"state"`
+	UssBaseUrl      UssBaseURL                      `json:"uss_base_url"`
+	SubscriptionId  *uuid.UUID                      `json:"subscription_id,omitempty"`
+	NewSubscription *ImplicitSubscriptionParameters `json:"new_subscription,omitempty"`
+}
+
+// SubscriberToNotify is a USS to notify of a change, with the subscriptions of
+// the USS that the change is relevant to.
+type SubscriberToNotify struct {
+	Subscriptions []SubscriptionState `json:"subscriptions"`
+	UssBaseUrl    UssBaseURL          `json:"uss_base_url"`
+}
+
+type ChangeOperationalIntentReferenceResponse struct {
+	Subscribers                []SubscriberToNotify       `json:"subscribers"`
+	OperationalIntentReference OperationalIntentReference `json:"operational_intent_reference"`
+}
+
+type GetOperationalIntentReferenceResponse struct {
+	OperationalIntentReference OperationalIntentReference `json:"operational_intent_reference"`
+}
+
+type QueryOperationalIntentReferenceParameters struct {
+	AreaOfInterest *Volume4d `json:"area_of_interest,omitempty"`
+}
+
+type QueryOperationalIntentReferenceResponse struct {
+	OperationalIntentReferences []OperationalIntentReference `json:"operational_intent_references"`
+}
+
+// AirspaceConflictResponse is the body of the 409 response to a change made
+// without the OVNs of all of the operational intents and constraints it
+// intersects.
+type AirspaceConflictResponse struct {
+	Message                   string                       `json:"message"`
+	MissingOperationalIntents []OperationalIntentReference `json:"missing_operational_intents,omitempty"`
+	MissingConstraints        []ConstraintReference        `json:"missing_constraints,omitempty"`
+}
+
+// Subscription is a request of a USS to be notified of the changes to
+// operational intents and constraints in an area. Its <Version> is opaque.
+type Subscription struct {
+	Id                          uuid.UUID   `json:"id"`
+	Version                     string      `json:"version"`
+	NotificationIndex           int         `json:"notification_index"`
+	TimeStart                   *Time       `json:"time_start,omitempty"`
+	TimeEnd                     *Time       `json:"time_end,omitempty"`
+	UssBaseUrl                  UssBaseURL  `json:"uss_base_url"`
+	NotifyForOperationalIntents bool        `json:"notify_for_operational_intents"`
+	NotifyForConstraints        bool        `json:"notify_for_constraints"`
+	ImplicitSubscription        bool        `json:"implicit_subscription"`
+	DependentOperationalIntents []uuid.UUID `json:"dependent_operational_intents"`
+}
+
+type PutSubscriptionParameters struct {
+	Extents                     Volume4d   `json:"extents"`
+	UssBaseUrl                  UssBaseURL `json:"uss_base_url"`
+	NotifyForOperationalIntents bool       `json:"notify_for_operational_intents"`
+	NotifyForConstraints        bool       `json:"notify_for_constraints"`
+}
+
+// PutSubscriptionResponse is the subscription that was created or updated,
+// along with the operational intents and constraints already in its area.
+type PutSubscriptionResponse struct {
+	Subscription                Subscription                 `json:"subscription"`
+	OperationalIntentReferences []OperationalIntentReference `json:"operational_intent_references,omitempty"`
+	ConstraintReferences        []ConstraintReference        `json:"constraint_references,omitempty"`
+}
+
+type GetSubscriptionResponse struct {
+	Subscription Subscription `json:"subscription"`
+}
+
+type DeleteSubscriptionResponse struct {
+	Subscription Subscription `json:"subscription"`
+}
+
+type QuerySubscriptionParameters struct {
+	AreaOfInterest *Volume4d `json:"area_of_interest,omitempty"`
+}
+
+type QuerySubscriptionsResponse struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+type PutConstraintReferenceParameters struct {
+	Extents    []Volume4d `json:"extents"`
+	UssBaseUrl UssBaseURL `json:"uss_base_url"`
+}
+
+type ChangeConstraintReferenceResponse struct {
+	Subscribers         []SubscriberToNotify `json:"subscribers"`
+	ConstraintReference ConstraintReference  `json:"constraint_reference"`
+}
+
+type GetConstraintReferenceResponse struct {
+	ConstraintReference ConstraintReference `json:"constraint_reference"`
+}
+
+type QueryConstraintReferenceParameters struct {
+	AreaOfInterest *Volume4d `json:"area_of_interest,omitempty"`
+}
+
+type QueryConstraintReferencesResponse struct {
+	ConstraintReferences []ConstraintReference `json:"constraint_references"`
+}
+
+// seededOvnNamespace namespaces the OVNs of SeededOvn.
+var seededOvnNamespace = uuid.MustParse("6f1c1d55-4d0e-4b5a-9f0e-2f1f3c9b7a10")
+
+// SeededOvn is the OVN of the first version of the operational intent <id>
+// seeded from the config. The fake USS and the DSS emulator both derive it
+// from <id>, so that a USS can get it from one and present it to the other.
+func SeededOvn(id uuid.UUID) EntityOvn {
+	return EntityOvn(uuid.NewSHA1(seededOvnNamespace, id[:]).String())
+}
+
+// Intersects reports whether <vol> and <other> overlap in time, altitude and
+// in the horizontal plane. Volumes without a start or end time are unbounded
+// in that direction.
+func (vol Volume4d) Intersects(other Volume4d) bool {
+	if !vol.TimeEnd.IsZero() && !other.TimeStart.IsZero() && other.TimeStart.After(vol.TimeEnd) {
+		return false
+	}
+	if !other.TimeEnd.IsZero() && !vol.TimeStart.IsZero() && vol.TimeStart.After(other.TimeEnd) {
+		return false
+	}
+	if vol.Volume.AltitudeLower > other.Volume.AltitudeUpper || other.Volume.AltitudeLower > vol.Volume.AltitudeUpper {
+		return false
+	}
+	return geo.PolygonsIntersect(vol.Volume.Outline(), other.Volume.Outline())
+}
+
+// AnyIntersect reports whether any of <vols> intersects any of <others>.
+func AnyIntersect(vols []Volume4d, others []Volume4d) bool {
+	for _, v := range vols {
+		for _, o := range others {
+			if v.Intersects(o) {
+				return true
+			}
+		}
+	}
+	return false
+}
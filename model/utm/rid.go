@@ -4,26 +4,60 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"manna.aero/manna.utm.cli/model/uspace"
 )
 
+// OperationalIntentTelemetry is the telemetry that a USS returns for one of
+// its operational intents. <Telemetry> is nil when the USS has none yet, and
+// <NextTelemetryOpportunity> is nil when it does not know when it will next
+// have any.
 type OperationalIntentTelemetry struct {
-	OperationalIntentId      uuid.UUID `json:"operational_intent_id"`
-	Telemetry                Telemetry `json:"telemetry"`
-	NextTelemetryOpportunity Time      `json:"next_telemetry_opportunity"`
+	OperationalIntentId      uuid.UUID  `json:"operational_intent_id"`
+	Telemetry                *Telemetry `json:"telemetry,omitempty"`
+	NextTelemetryOpportunity *Time      `json:"next_telemetry_opportunity,omitempty"`
 }
 
 type OperationalIntentTelemetryJson struct {
-	OperationalIntentId      string    `json:"operational_intent_id"`
-	Telemetry                Telemetry `json:"telemetry"`
-	NextTelemetryOpportunity timeJson  `json:"next_telemetry_opportunity"`
+	OperationalIntentId      string     `json:"operational_intent_id"`
+	Telemetry                *Telemetry `json:"telemetry,omitempty"`
+	NextTelemetryOpportunity *timeJson  `json:"next_telemetry_opportunity,omitempty"`
 }
 
 func (oit OperationalIntentTelemetry) ToJson() OperationalIntentTelemetryJson {
 	// conver the uuid to string
-	return OperationalIntentTelemetryJson{
-		OperationalIntentId:      oit.OperationalIntentId.String(),
-		Telemetry:                oit.Telemetry,
-		NextTelemetryOpportunity: oit.NextTelemetryOpportunity.toJson(),
+	oitJson := OperationalIntentTelemetryJson{
+		OperationalIntentId: oit.OperationalIntentId.String(),
+		Telemetry:           oit.Telemetry,
+	}
+	if oit.NextTelemetryOpportunity != nil {
+		next := oit.NextTelemetryOpportunity.toJson()
+		oitJson.NextTelemetryOpportunity = &next
+	}
+	return oitJson
+}
+
+// NextPoll is when the telemetry should next be fetched after <now>: the next
+// telemetry opportunity if it is still to come, otherwise <now> + <fallback>.
+func (oit OperationalIntentTelemetry) NextPoll(now time.Time, fallback time.Duration) time.Time {
+	if oit.NextTelemetryOpportunity != nil && oit.NextTelemetryOpportunity.Value.After(now) {
+		return oit.NextTelemetryOpportunity.Value
+	}
+	return now.Add(fallback)
+}
+
+// TelemetryFromUspace converts a manna-utm U-Space telemetry message into
+// UTM telemetry.
+func TelemetryFromUspace(t uspace.Telemetry) Telemetry {
+	return Telemetry{
+		TimeMeasured: NewTime(time.UnixMilli(t.TimeMeasured).UTC()),
+		Position: Position{
+			Longitude: t.Longitude,
+			Latitude:  t.Latitude,
+			AccuracyH: "HAUnknown",
+			AccuracyV: "VAUnknown",
+			Altitude:  Altitude{Value: t.Altitude, Reference: "W84", Units: "M"},
+		},
+		Velocity: Velocity{Speed: t.Speed, UnitsSpeed: "MetersPerSecond", Track: t.Heading},
 	}
 }
 
@@ -43,15 +77,15 @@ type Position struct {
 }
 
 type Altitude struct {
-	Value     int64  `json:"value"`
-	Reference string `json:"reference"`
-	Units     string `json:"units"`
+	Value     float64 `json:"value"`
+	Reference string  `json:"reference"`
+	Units     string  `json:"units"`
 }
 
 type Velocity struct {
 	Speed      float64 `json:"speed"`
 	UnitsSpeed string  `json:"units_speed"`
-	Track      int     `json:"track"`
+	Track      float64 `json:"track"`
 }
 
 type Time struct {
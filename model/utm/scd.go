@@ -3,11 +3,14 @@ package utm
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
 	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/model/uspace"
 	"manna.aero/manna.utm.cli/pkg/config"
 	"manna.aero/manna.utm.cli/pkg/geo"
 )
@@ -29,8 +32,86 @@ func (oi OperationalIntent) toReader() *bytes.Reader {
 	return bytes.NewReader(jsonBytes)
 }
 
+// OperationalIntentState is the state of an operational intent, as defined by
+// ASTM F3548-21. Ended is not an F3548 state, it marks an operational intent
+// that has been removed from the DSS.
+type OperationalIntentState string
+
+const (
+	StateAccepted      OperationalIntentState = "Accepted"
+	StateActivated     OperationalIntentState = "Activated"
+	StateNonconforming OperationalIntentState = "Nonconforming"
+	StateContingent    OperationalIntentState = "Contingent"
+	StateEnded         OperationalIntentState = "Ended"
+)
+
+// operationalIntentTransitions are the states that an operational intent in
+// each state may move to, per ASTM F3548-21.
+var operationalIntentTransitions = map[OperationalIntentState][]OperationalIntentState{
+	StateAccepted:      {StateActivated, StateEnded},
+	StateActivated:     {StateNonconforming, StateContingent, StateEnded},
+	StateNonconforming: {StateActivated, StateContingent, StateEnded},
+	StateContingent:    {StateEnded},
+}
+
+// InvalidTransitionError is returned for a change of state that ASTM F3548-21
+// does not allow.
+type InvalidTransitionError struct {
+	From   OperationalIntentState
+	To     OperationalIntentState
+	Reason string
+}
+
+func (e *InvalidTransitionError) Error() string {
+	msg := fmt.Sprintf("an operational intent cannot transition from %s to %s", e.From, e.To)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// ValidateTransition returns an InvalidTransitionError if an operational
+// intent in state <from> may not move to state <to>.
+func ValidateTransition(from OperationalIntentState, to OperationalIntentState) error {
+	for _, allowed := range operationalIntentTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return &InvalidTransitionError{From: from, To: to}
+}
+
+// ValidateCancellation returns an InvalidTransitionError if an operational
+// intent in state <from> may not be cancelled. Only operational intents that
+// have not been activated can be cancelled, activated ones must be ended.
+func ValidateCancellation(from OperationalIntentState) error {
+	if from != StateAccepted {
+		return &InvalidTransitionError{From: from, To: StateEnded, Reason: "only accepted operational intents can be cancelled"}
+	}
+	return nil
+}
+
+// ValidateUpdate returns an InvalidTransitionError if the volumes or priority
+// of an operational intent in state <from> may not be changed. Any operational
+// intent that has not ended may be updated, without changing its state.
+func ValidateUpdate(from OperationalIntentState) error {
+	if from == StateEnded {
+		return &InvalidTransitionError{From: from, To: from, Reason: "ended operational intents cannot be updated"}
+	}
+	return nil
+}
+
 type OperationalIntentReference struct {
-	ID uuid.UUID `json:"id"`
+	ID              uuid.UUID              `json:"id"`
+	Manager         string                 `json:"manager"`
+	UssAvailability UssAvailabilityState   `json:"uss_availability"`
+	Version         int                    `json:"version"`
+	State           OperationalIntentState `json:"state"`
+	Ovn             EntityOvn              `json:"ovn,omitempty"`
+	TimeStart       Time                   `json:"time_start"`
+	TimeEnd         Time                   `json:"time_end"`
+	UssBaseUrl      UssBaseURL             `json:"uss_base_url"`
+	SubscriptionId  uuid.UUID              `json:"subscription_id"`
 }
 
 type OperationalIntentDetails struct {
@@ -39,6 +120,25 @@ type OperationalIntentDetails struct {
 	Priority          uint16     `json:"priority"`
 }
 
+// GeoJsonFeatures returns a feature for each of the nominal and off-nominal
+// volumes of the operational intent.
+func (d OperationalIntentDetails) GeoJsonFeatures() []*geojson.Feature {
+	features := make([]*geojson.Feature, 0, len(d.Volumes)+len(d.OffNominalVolumes))
+	for _, vol := range d.Volumes {
+		f := vol.GeoJsonFeature()
+		f.Properties["priority"] = d.Priority
+		f.Properties["off_nominal"] = false
+		features = append(features, f)
+	}
+	for _, vol := range d.OffNominalVolumes {
+		f := vol.GeoJsonFeature()
+		f.Properties["priority"] = d.Priority
+		f.Properties["off_nominal"] = true
+		features = append(features, f)
+	}
+	return features
+}
+
 // Volume4d is the equivalent of the manna-utm type UtmVolume4d
 //
 // see https://github.com/m4a3/manna-utm/blob/persistence/src/main/java/astm/dss/model/operationalintent/UtmVolume4D.java
@@ -48,30 +148,169 @@ type Volume4d struct {
 	TimeEnd   time.Time `json:"time_end"`
 }
 
+type volume4dJson struct {
+	Volume    Volume3d `json:"volume"`
+	TimeStart *Time    `json:"time_start,omitempty"`
+	TimeEnd   *Time    `json:"time_end,omitempty"`
+}
+
+// MarshalJSON encodes the volume as an ASTM F3548-21 Volume4D.
+func (vol Volume4d) MarshalJSON() ([]byte, error) {
+	v := volume4dJson{Volume: vol.Volume}
+	if !vol.TimeStart.IsZero() {
+		t := NewTime(vol.TimeStart)
+		v.TimeStart = &t
+	}
+	if !vol.TimeEnd.IsZero() {
+		t := NewTime(vol.TimeEnd)
+		v.TimeEnd = &t
+	}
+	return json.Marshal(v)
+}
+
+func (vol *Volume4d) UnmarshalJSON(data []byte) error {
+	var v volume4dJson
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*vol = Volume4d{Volume: v.Volume}
+	if v.TimeStart != nil {
+		vol.TimeStart = v.TimeStart.Value
+	}
+	if v.TimeEnd != nil {
+		vol.TimeEnd = v.TimeEnd.Value
+	}
+	return nil
+}
+
+func (vol Volume4d) GeoJsonFeature() *geojson.Feature {
+	f := geojson.NewFeature(vol.Volume.Outline())
+	f.Properties["altitude_lower"] = vol.Volume.AltitudeLower
+	f.Properties["altitude_upper"] = vol.Volume.AltitudeUpper
+	f.Properties["time_start"] = vol.TimeStart
+	f.Properties["time_end"] = vol.TimeEnd
+	return f
+}
+
 // Volume3d is the equivalent of the manna-utm type UtmVolume3d
 //
 // see https://github.com/m4a3/manna-utm/blob/persistence/src/main/java/astm/dss/model/operationalintent/UtmVolume3D.java
+//
+// The polygon is ordered [lng, lat] and the altitudes are metres above the WGS84
+// ellipsoid. A volume received with a circular outline has <OutlineCircle> set
+// instead of <OutlinePolygon>.
 type Volume3d struct {
-	OutlinePolygon orb.Polygon `json:"outline_polygon"`
-	AltitudeLower  float64     `json:"altitude_lower"`
-	AltitudeUpper  float64     `json:"altitude_upper"`
+	OutlinePolygon orb.Polygon
+	OutlineCircle  *Circle
+	AltitudeLower  float64
+	AltitudeUpper  float64
+}
+
+// Outline is the outline polygon of the volume, approximating its outline
+// circle if it has one.
+func (vol Volume3d) Outline() orb.Polygon {
+	if vol.OutlineCircle != nil && len(vol.OutlinePolygon) == 0 {
+		c := vol.OutlineCircle
+		return geo.CirclePlanar(orb.Point{c.Center.Lng, c.Center.Lat}, c.Radius.Value, 32)
+	}
+	return vol.OutlinePolygon
+}
+
+type LatLngPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Polygon is an ASTM F3548-21 polygon, whose last vertex is implicitly joined
+// to the first.
+type Polygon struct {
+	Vertices []LatLngPoint `json:"vertices"`
+}
+
+type Circle struct {
+	Center LatLngPoint `json:"center"`
+	Radius Radius      `json:"radius"`
+}
+
+// Radius is in metres.
+type Radius struct {
+	Value float64 `json:"value"`
+	Units string  `json:"units"`
+}
+
+type volume3dJson struct {
+	OutlineCircle  *Circle   `json:"outline_circle,omitempty"`
+	OutlinePolygon *Polygon  `json:"outline_polygon,omitempty"`
+	AltitudeLower  *Altitude `json:"altitude_lower,omitempty"`
+	AltitudeUpper  *Altitude `json:"altitude_upper,omitempty"`
+}
+
+// MarshalJSON encodes the volume as an ASTM F3548-21 Volume3D.
+func (vol Volume3d) MarshalJSON() ([]byte, error) {
+	v := volume3dJson{
+		OutlineCircle: vol.OutlineCircle,
+		AltitudeLower: &Altitude{Value: vol.AltitudeLower, Reference: "W84", Units: "M"},
+		AltitudeUpper: &Altitude{Value: vol.AltitudeUpper, Reference: "W84", Units: "M"},
+	}
+	if len(vol.OutlinePolygon) > 0 {
+		ring := vol.OutlinePolygon[0]
+		if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+			ring = ring[:len(ring)-1]
+		}
+		v.OutlinePolygon = &Polygon{Vertices: make([]LatLngPoint, 0, len(ring))}
+		for _, p := range ring {
+			v.OutlinePolygon.Vertices = append(v.OutlinePolygon.Vertices, LatLngPoint{Lat: p[1], Lng: p[0]})
+		}
+	}
+	return json.Marshal(v)
+}
+
+func (vol *Volume3d) UnmarshalJSON(data []byte) error {
+	var v volume3dJson
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*vol = Volume3d{OutlineCircle: v.OutlineCircle}
+	if v.OutlinePolygon != nil && len(v.OutlinePolygon.Vertices) > 0 {
+		coords := make([][2]float64, 0, len(v.OutlinePolygon.Vertices))
+		for _, p := range v.OutlinePolygon.Vertices {
+			coords = append(coords, [2]float64{p.Lng, p.Lat})
+		}
+		vol.OutlinePolygon = geo.PolygonFromCoords(coords)
+	}
+	if v.AltitudeLower != nil {
+		vol.AltitudeLower = v.AltitudeLower.Value
+	}
+	if v.AltitudeUpper != nil {
+		vol.AltitudeUpper = v.AltitudeUpper.Value
+	}
+	return nil
 }
 
 func OperationalIntentFromConfig(oicnf *config.OperationalIntentConfig) *OperationalIntent {
+	return OperationalIntentFromConfigAt(oicnf, time.Now())
+}
+
+// OperationalIntentFromConfigAt is [OperationalIntentFromConfig] with the
+// operational intent departing at <departure> rather than now.
+func OperationalIntentFromConfigAt(oicnf *config.OperationalIntentConfig, departure time.Time) *OperationalIntent {
 	log.Tracef("constructing UTM operational intent for operational intent config: %s", oicnf.Name)
 	// construct the volumes
 	var vols []Volume4d
-	startTime := time.Now()
+	startTime := departure
 	volDuration := oicnf.Duration / time.Duration(len(oicnf.WaypointCoordinates))
 	for _, coord := range oicnf.WaypointCoordinates {
 		vols = append(vols, *getVolume4dFromCoordinate(coord[0], coord[1], startTime, volDuration))
-		startTime.Add(volDuration)
+		startTime = startTime.Add(volDuration)
 	}
 
 	log.Tracef("returning UTM operational intent: %s", oicnf.Name)
 	return &OperationalIntent{
 		Reference: OperationalIntentReference{
-			ID: oicnf.MissionId,
+			ID:        oicnf.MissionId,
+			State:     StateAccepted,
+			TimeStart: NewTime(vols[0].TimeStart),
+			TimeEnd:   NewTime(startTime),
 		},
 		Details: OperationalIntentDetails{
 			Volumes:  vols,
@@ -80,6 +319,34 @@ func OperationalIntentFromConfig(oicnf *config.OperationalIntentConfig) *Operati
 	}
 }
 
+// OperationalIntentDetailsFromUspace converts a manna-utm U-Space operational
+// intent into UTM operational intent details. U-Space polygons are ordered
+// [lat, lng], whereas UTM polygons are ordered [lng, lat].
+func OperationalIntentDetailsFromUspace(oi *uspace.OperationalIntent) OperationalIntentDetails {
+	vols := make([]Volume4d, 0, len(oi.Volumes))
+	for _, v := range oi.Volumes {
+		vols = append(vols, Volume4dFromUspace(v))
+	}
+
+	return OperationalIntentDetails{
+		Volumes:  vols,
+		Priority: oi.Priority,
+	}
+}
+
+// Volume4dFromUspace converts a manna-utm U-Space 4d volume into a UTM 4d volume.
+func Volume4dFromUspace(v uspace.Volume4d) Volume4d {
+	return Volume4d{
+		Volume: Volume3d{
+			OutlinePolygon: geo.SwapPolygonAxes(v.Polygon),
+			AltitudeLower:  v.AltitudeLower,
+			AltitudeUpper:  v.AltitudeUpper,
+		},
+		TimeStart: v.TimeStart,
+		TimeEnd:   v.TimeEnd,
+	}
+}
+
 func getVolume4dFromCoordinate(lat float64, lng float64, startTime time.Time, duration time.Duration) *Volume4d {
 	polygon := geo.HexagonPlanar(orb.Point{lng, lat})
 	var vol3d Volume3d
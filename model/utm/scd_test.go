@@ -0,0 +1,114 @@
+package utm
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTransition(t *testing.T) {
+	allowed := []struct{ from, to OperationalIntentState }{
+		{StateAccepted, StateActivated},
+		{StateAccepted, StateEnded},
+		{StateActivated, StateNonconforming},
+		{StateActivated, StateContingent},
+		{StateActivated, StateEnded},
+		{StateNonconforming, StateActivated},
+		{StateNonconforming, StateContingent},
+		{StateContingent, StateEnded},
+	}
+	for _, tr := range allowed {
+		assert.NoError(t, ValidateTransition(tr.from, tr.to), "%s -> %s", tr.from, tr.to)
+	}
+
+	rejected := []struct{ from, to OperationalIntentState }{
+		{StateAccepted, StateNonconforming},
+		{StateAccepted, StateContingent},
+		{StateContingent, StateActivated},
+		{StateEnded, StateActivated},
+		{StateEnded, StateEnded},
+	}
+	for _, tr := range rejected {
+		var invalid *InvalidTransitionError
+		assert.ErrorAs(t, ValidateTransition(tr.from, tr.to), &invalid, "%s -> %s", tr.from, tr.to)
+	}
+}
+
+func TestValidateCancellation(t *testing.T) {
+	assert.NoError(t, ValidateCancellation(StateAccepted))
+	assert.Error(t, ValidateCancellation(StateActivated))
+	assert.Error(t, ValidateCancellation(StateEnded))
+}
+
+func TestVolume4dJson(t *testing.T) {
+	data := `{
+		"volume": {
+			"outline_polygon": {"vertices": [{"lat": 46.19, "lng": 6.12}, {"lat": 46.20, "lng": 6.12}, {"lat": 46.20, "lng": 6.13}]},
+			"altitude_lower": {"value": 0, "reference": "W84", "units": "M"},
+			"altitude_upper": {"value": 120.5, "reference": "W84", "units": "M"}
+		},
+		"time_start": {"value": "2026-10-18T10:00:00Z", "format": "RFC3339"},
+		"time_end": {"value": "2026-10-18T10:05:00Z", "format": "RFC3339"}
+	}`
+
+	var vol Volume4d
+	require.NoError(t, json.Unmarshal([]byte(data), &vol))
+	assert.Equal(t, orb.Polygon{{{6.12, 46.19}, {6.12, 46.20}, {6.13, 46.20}, {6.12, 46.19}}}, vol.Volume.OutlinePolygon)
+	assert.Equal(t, 120.5, vol.Volume.AltitudeUpper)
+	assert.Equal(t, 5*time.Minute, vol.TimeEnd.Sub(vol.TimeStart))
+
+	encoded, err := json.Marshal(vol)
+	require.NoError(t, err)
+	assert.JSONEq(t, data, string(encoded))
+
+	var circle Volume4d
+	require.NoError(t, json.Unmarshal([]byte(`{"volume": {"outline_circle": {"center": {"lat": 46.19, "lng": 6.12}, "radius": {"value": 100, "units": "M"}}}}`), &circle))
+	assert.Len(t, circle.Volume.Outline()[0], 33)
+	assert.True(t, circle.TimeStart.IsZero())
+}
+
+func TestQueryResponseJson(t *testing.T) {
+	// the operational intents that manna-utm returns for a 4d volume query
+	data := `[
+		{
+			"volumes": [{
+				"volume": {
+					"outline_polygon": {"vertices": [{"lat": 46.19, "lng": 6.12}, {"lat": 46.20, "lng": 6.12}, {"lat": 46.20, "lng": 6.13}]},
+					"altitude_lower": {"value": 0, "reference": "W84", "units": "M"},
+					"altitude_upper": {"value": 120, "reference": "W84", "units": "M"}
+				},
+				"time_start": {"value": "2026-10-18T10:00:00Z", "format": "RFC3339"},
+				"time_end": {"value": "2026-10-18T10:10:00Z", "format": "RFC3339"}
+			}],
+			"off_nominal_volumes": [],
+			"priority": 10
+		},
+		{
+			"volumes": [{
+				"volume": {
+					"outline_circle": {"center": {"lat": 46.195, "lng": 6.125}, "radius": {"value": 300, "units": "M"}},
+					"altitude_lower": {"value": 0, "reference": "W84", "units": "M"},
+					"altitude_upper": {"value": 60, "reference": "W84", "units": "M"}
+				},
+				"time_start": {"value": "2026-10-18T10:05:00Z", "format": "RFC3339"},
+				"time_end": {"value": "2026-10-18T10:15:00Z", "format": "RFC3339"}
+			}],
+			"off_nominal_volumes": [],
+			"priority": 20
+		}
+	]`
+
+	var ois []OperationalIntentDetails
+	require.NoError(t, json.Unmarshal([]byte(data), &ois))
+	require.Len(t, ois, 2)
+	assert.Equal(t, uint16(20), ois[1].Priority)
+	require.NotNil(t, ois[1].Volumes[0].Volume.OutlineCircle)
+
+	encoded, err := json.Marshal(ois)
+	require.NoError(t, err)
+	assert.JSONEq(t, data, string(encoded))
+}
@@ -0,0 +1,131 @@
+package utm
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The types of the ASTM F3548-21 USS-to-USS API, as they are sent on the wire.
+//
+// see https://github.com/astm-utm/Protocol/blob/v1.0.0/utm.yaml
+
+// EntityOvn is the opaque version number of an operational intent or
+// constraint, which proves that a USS knows its current version.
+type EntityOvn string
+
+// UssBaseURL is the base URL of the USS API of a USS, e.g.
+// https://uss.example.com/prefix, to which the /uss/v1/ paths are appended.
+type UssBaseURL string
+
+type UssAvailabilityState string
+
+const (
+	UssAvailabilityUnknown UssAvailabilityState = "Unknown"
+	UssAvailabilityNormal  UssAvailabilityState = "Normal"
+	UssAvailabilityDown    UssAvailabilityState = "Down"
+)
+
+// NewTime is <t> in the RFC3339 format that ASTM F3548-21 times are sent in.
+func NewTime(t time.Time) Time {
+	return Time{Value: t, Format: "RFC3339"}
+}
+
+// SubscriptionState is a subscription notified of a change, along with the
+// index of the notification, which increases with each one sent to it.
+type SubscriptionState struct {
+	SubscriptionId    uuid.UUID `json:"subscription_id"`
+	NotificationIndex int       `json:"notification_index"`
+}
+
+type GetOperationalIntentDetailsResponse struct {
+	OperationalIntent OperationalIntent `json:"operational_intent"`
+}
+
+// PutOperationalIntentDetailsParameters notifies a USS that the operational
+// intent <OperationalIntentId> has changed. <OperationalIntent> is nil when it
+// has been removed.
+type PutOperationalIntentDetailsParameters struct {
+	OperationalIntentId uuid.UUID           `json:"operational_intent_id"`
+	OperationalIntent   *OperationalIntent  `json:"operational_intent,omitempty"`
+	Subscriptions       []SubscriptionState `json:"subscriptions"`
+}
+
+type Constraint struct {
+	Reference ConstraintReference `json:"reference"`
+	Details   ConstraintDetails   `json:"details"`
+}
+
+type ConstraintReference struct {
+	ID              uuid.UUID            `json:"id"`
+	Manager         string               `json:"manager"`
+	UssAvailability UssAvailabilityState `json:"uss_availability"`
+	Version         int                  `json:"version"`
+	Ovn             EntityOvn            `json:"ovn,omitempty"`
+	TimeStart       Time                 `json:"time_start"`
+	TimeEnd         Time                 `json:"time_end"`
+	UssBaseUrl      UssBaseURL           `json:"uss_base_url"`
+}
+
+// ConstraintDetails are the volumes a constraint applies to. <Geozone> is the
+// ED-269 geozone the constraint was made from, if any, which is passed through
+// as is.
+type ConstraintDetails struct {
+	Volumes []Volume4d      `json:"volumes"`
+	Type    string          `json:"type,omitempty"`
+	Geozone json.RawMessage `json:"geozone,omitempty"`
+}
+
+type GetConstraintDetailsResponse struct {
+	Constraint Constraint `json:"constraint"`
+}
+
+// PutConstraintDetailsParameters notifies a USS that the constraint
+// <ConstraintId> has changed. <Constraint> is nil when it has been removed.
+type PutConstraintDetailsParameters struct {
+	ConstraintId  uuid.UUID           `json:"constraint_id"`
+	Constraint    *Constraint         `json:"constraint,omitempty"`
+	Subscriptions []SubscriptionState `json:"subscriptions"`
+}
+
+type RecorderRole string
+
+const (
+	RecorderRoleClient RecorderRole = "Client"
+	RecorderRoleServer RecorderRole = "Server"
+)
+
+// ExchangeRecord is a request made to or by a USS and its response, as
+// recorded by the <RecorderRole> of the exchange.
+type ExchangeRecord struct {
+	Url          string       `json:"url"`
+	Method       string       `json:"method"`
+	Headers      []string     `json:"headers,omitempty"`
+	RecorderRole RecorderRole `json:"recorder_role"`
+	RequestTime  Time         `json:"request_time"`
+	RequestBody  string       `json:"request_body,omitempty"`
+	ResponseTime *Time        `json:"response_time,omitempty"`
+	ResponseBody string       `json:"response_body,omitempty"`
+	ResponseCode int          `json:"response_code,omitempty"`
+	Problem      string       `json:"problem,omitempty"`
+}
+
+// ErrorReport reports an exchange with a USS that went wrong. The USS
+// receiving the report assigns its <ReportId>.
+type ErrorReport struct {
+	ReportId string         `json:"report_id,omitempty"`
+	Exchange ExchangeRecord `json:"exchange"`
+}
+
+// GetLogsResponse is the exchanges that a USS has recorded. Retrieving logs is
+// not part of the F3548-21 USS API, manna-utm and the USSs it works with
+// expose them under /uss/v1/logs.
+type GetLogsResponse struct {
+	Logs []ExchangeRecord `json:"logs"`
+}
+
+// ErrorResponse is the body of the error responses of the USS API.
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,167 @@
+// Package auth provides the bearer tokens sent by the manna-utm and USS
+// clients, scoped per request as required by ASTM F3548 and F3411.
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/pkg/config"
+)
+
+// Scopes defined by ASTM F3548-21 and ASTM F3411-22a.
+const (
+	ScopeStrategicCoordination   = "utm.strategic_coordination"
+	ScopeConstraintManagement    = "utm.constraint_management"
+	ScopeConstraintProcessing    = "utm.constraint_processing"
+	ScopeConformanceMonitoringSA = "utm.conformance_monitoring_sa"
+	ScopeAvailabilityArbitration = "utm.availability_arbitration"
+	ScopeRidServiceProvider      = "rid.service_provider"
+	ScopeRidDisplayProvider      = "rid.display_provider"
+)
+
+// expiryMargin is how long before its expiry a cached token is replaced, so
+// that a token is never sent just as it expires.
+const expiryMargin = 30 * time.Second
+
+// TokenSource provides access tokens for a scope.
+type TokenSource interface {
+	Token(ctx context.Context, scope string) (string, error)
+}
+
+// Token is an access token and the time it expires. A zero Expiry never expires.
+type Token struct {
+	AccessToken string
+	Expiry      time.Time
+}
+
+func (t *Token) valid() bool {
+	return t != nil && (t.Expiry.IsZero() || time.Now().Add(expiryMargin).Before(t.Expiry))
+}
+
+type tokenFetcher interface {
+	fetch(ctx context.Context, scope string) (*Token, error)
+}
+
+// cachingTokenSource caches the tokens of a tokenFetcher per scope, until
+// they expire.
+type cachingTokenSource struct {
+	fetcher tokenFetcher
+	lock    sync.Mutex
+	tokens  map[string]*Token
+}
+
+func newCachingTokenSource(fetcher tokenFetcher) *cachingTokenSource {
+	return &cachingTokenSource{
+		fetcher: fetcher,
+		tokens:  map[string]*Token{},
+	}
+}
+
+func (cts *cachingTokenSource) Token(ctx context.Context, scope string) (string, error) {
+	cts.lock.Lock()
+	defer cts.lock.Unlock()
+
+	if t := cts.tokens[scope]; t.valid() {
+		return t.AccessToken, nil
+	}
+
+	log.Debugf("fetching access token for scope: %s", scope)
+	t, err := cts.fetcher.fetch(ctx, scope)
+	if err != nil {
+		return "", fmt.Errorf("fetch access token for scope %s: %w", scope, err)
+	}
+	cts.tokens[scope] = t
+	return t.AccessToken, nil
+}
+
+type staticTokenSource struct {
+	token string
+}
+
+// NewStaticTokenSource returns a TokenSource that always returns <token>,
+// whatever the scope.
+func NewStaticTokenSource(token string) TokenSource {
+	return &staticTokenSource{token: token}
+}
+
+func (sts *staticTokenSource) Token(_ context.Context, _ string) (string, error) {
+	return sts.token, nil
+}
+
+// SetAuthorizationHeader sets the bearer token for <scope> on <req>. It does
+// nothing when <ts> is nil.
+func SetAuthorizationHeader(ctx context.Context, req *http.Request, ts TokenSource, scope string) error {
+	if ts == nil {
+		return nil
+	}
+	token, err := ts.Token(ctx, scope)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// FromEnvironment builds the TokenSource configured for <env>, with tokens
+// intended for the host of the environment's base URL by default.
+func FromEnvironment(env *config.EnvironmentConfig, tlsConfig *tls.Config) (TokenSource, error) {
+	u, err := url.Parse(env.BaseURL)
+	if err != nil {
+		return nil, &config.Error{Err: err}
+	}
+	ts, err := FromConfig(env.Auth, u.Hostname(), tlsConfig)
+	if err != nil {
+		return nil, &config.Error{Err: fmt.Errorf("auth of environment %s: %w", env.Name, err)}
+	}
+	return ts, nil
+}
+
+// FromConfig builds the TokenSource described by <cnf>, returning nil when no
+// authentication is configured. <defaultAudience> is used when <cnf> does not
+// set an audience, and <tlsConfig> is used to talk to the token endpoint.
+func FromConfig(cnf config.AuthConfig, defaultAudience string, tlsConfig *tls.Config) (TokenSource, error) {
+	audience := cnf.Audience
+	if audience == "" {
+		audience = defaultAudience
+	}
+
+	switch cnf.Type {
+	case "":
+		return nil, nil
+	case config.AuthTypeStatic:
+		token := cnf.Token
+		if cnf.TokenEnvVar != "" {
+			token = os.Getenv(cnf.TokenEnvVar)
+		}
+		if token == "" {
+			return nil, fmt.Errorf("static auth requires a token or a non-empty token_env_var")
+		}
+		return NewStaticTokenSource(token), nil
+	case config.AuthTypeClientCredentials:
+		if cnf.TokenURL == "" || cnf.ClientID == "" {
+			return nil, fmt.Errorf("client_credentials auth requires token_url and client_id")
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		return NewClientCredentialsTokenSource(cnf.TokenURL, cnf.ClientID, cnf.ClientSecret, audience, &http.Client{
+			Timeout:   15 * time.Second,
+			Transport: transport,
+		}), nil
+	case config.AuthTypeDummyOAuth:
+		key, err := loadRSAPrivateKey(cnf.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return NewDummyOAuthTokenSource(key, cnf.Issuer, cnf.Subject, audience), nil
+	default:
+		return nil, fmt.Errorf("unknown auth type: %s", cnf.Type)
+	}
+}
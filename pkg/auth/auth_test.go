@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyOAuthTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	ts := NewDummyOAuthTokenSource(key, "", "", "localhost")
+	token, err := ts.Token(context.Background(), ScopeStrategicCoordination)
+	assert.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	assert.Len(t, parts, 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+	claimBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NoError(t, err)
+	var claims dummyOAuthClaims
+	assert.NoError(t, json.Unmarshal(claimBytes, &claims))
+	assert.Equal(t, "localhost", claims.Audience)
+	assert.Equal(t, ScopeStrategicCoordination, claims.Scope)
+	assert.Equal(t, defaultDummyIssuer, claims.Issuer)
+
+	cached, err := ts.Token(context.Background(), ScopeStrategicCoordination)
+	assert.NoError(t, err)
+	assert.Equal(t, token, cached, "token is cached per scope")
+
+	other, err := ts.Token(context.Background(), ScopeConformanceMonitoringSA)
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestClientCredentialsTokenSource(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		clientId, clientSecret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "cli", clientId)
+		assert.Equal(t, "secret", clientSecret)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%s","token_type":"Bearer","expires_in":3600}`, r.PostForm.Get("scope"))
+	}))
+	defer server.Close()
+
+	ts := NewClientCredentialsTokenSource(server.URL, "cli", "secret", "", server.Client())
+	for i := 0; i < 2; i++ {
+		token, err := ts.Token(context.Background(), ScopeStrategicCoordination)
+		assert.NoError(t, err)
+		assert.Equal(t, "token-"+ScopeStrategicCoordination, token)
+	}
+	assert.Equal(t, 1, requests)
+}
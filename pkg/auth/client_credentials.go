@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type clientCredentialsFetcher struct {
+	tokenUrl     string
+	clientId     string
+	clientSecret string
+	audience     string
+	c            *http.Client
+}
+
+// NewClientCredentialsTokenSource returns a TokenSource that requests tokens
+// from the OAuth2 token endpoint <tokenUrl> using the client credentials
+// grant, caching each token until it expires.
+func NewClientCredentialsTokenSource(tokenUrl string, clientId string, clientSecret string, audience string, c *http.Client) TokenSource {
+	return newCachingTokenSource(&clientCredentialsFetcher{
+		tokenUrl:     tokenUrl,
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		audience:     audience,
+		c:            c,
+	})
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (f *clientCredentialsFetcher) fetch(ctx context.Context, scope string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("scope", scope)
+	if f.audience != "" {
+		form.Set("audience", f.audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", f.tokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(f.clientId), url.QueryEscape(f.clientSecret))
+
+	resp, err := f.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, fmt.Errorf("token endpoint returned status=%d body=%q", resp.StatusCode, string(b))
+	}
+
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return nil, fmt.Errorf("decode token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned no access_token")
+	}
+
+	t := &Token{AccessToken: tr.AccessToken}
+	if tr.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
@@ -0,0 +1,106 @@
+// Package cli_error maps the errors returned by commands to the exit code of
+// the CLI, so that scripts wrapping it can branch on the outcome of a command
+// without scraping its logs.
+package cli_error
+
+import (
+	"errors"
+	"net/http"
+
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/state"
+	"manna.aero/manna.utm.cli/pkg/transport"
+)
+
+// The exit codes of the CLI. They are part of its interface, so existing
+// codes must not be renumbered.
+const (
+	ExitOK = 0
+	// ExitFailure is any error not covered by another exit code.
+	ExitFailure = 1
+	// ExitUsage is an unknown command, or an unknown or malformed flag.
+	ExitUsage = 2
+	// ExitConfig is a missing or invalid config file, or a name that is not
+	// in it.
+	ExitConfig = 3
+	// ExitValidation is a request that was rejected locally, before it was
+	// sent, e.g. a lifecycle transition that is not allowed.
+	ExitValidation = 4
+	// ExitConflict is a 409 Conflict response.
+	ExitConflict = 5
+	// ExitClientError is any other 4xx response.
+	ExitClientError = 6
+	// ExitServerError is a 5xx response, after any retries.
+	ExitServerError = 7
+	// ExitNetwork is a request that received no response, or that was not
+	// sent because the circuit breaker of its host was open.
+	ExitNetwork = 8
+	// ExitTimeout is a request that received no response in time.
+	ExitTimeout = 9
+)
+
+// UsageError is an error in the way that a command was invoked.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is a request that was rejected before it was sent.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode is the exit code of the CLI for <err>, which is ExitOK if <err> is
+// nil.
+func ExitCode(err error) int {
+	var usageErr *UsageError
+	var configErr *config.Error
+	var validationErr *ValidationError
+	var invalidTransition *utm.InvalidTransitionError
+	var notFound *state.NotFoundError
+	var environmentErr *state.EnvironmentError
+	var clientErr *transport.ClientError
+	var serverErr *transport.ServerError
+	var timeoutErr *transport.TimeoutError
+	var networkErr *transport.NetworkError
+	var circuitOpenErr *transport.CircuitOpenError
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageErr):
+		return ExitUsage
+	case errors.As(err, &configErr):
+		return ExitConfig
+	case errors.As(err, &validationErr), errors.As(err, &invalidTransition), errors.As(err, &notFound), errors.As(err, &environmentErr):
+		return ExitValidation
+	case errors.As(err, &clientErr) && clientErr.StatusCode == http.StatusConflict:
+		return ExitConflict
+	case errors.As(err, &clientErr):
+		return ExitClientError
+	case errors.As(err, &serverErr):
+		return ExitServerError
+	case errors.As(err, &timeoutErr):
+		return ExitTimeout
+	case errors.As(err, &networkErr), errors.As(err, &circuitOpenErr):
+		return ExitNetwork
+	default:
+		return ExitFailure
+	}
+}
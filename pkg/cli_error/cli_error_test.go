@@ -0,0 +1,37 @@
+package cli_error
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/state"
+	"manna.aero/manna.utm.cli/pkg/transport"
+	"manna.aero/manna.utm.cli/pkg/uspace_client"
+)
+
+func TestExitCode(t *testing.T) {
+	wrap := func(err error) error {
+		return fmt.Errorf("failed to create operational intent: %w", err)
+	}
+	remote := func(statusCode int) error {
+		return wrap(&uspace_client.MannaUtmError{StatusCode: statusCode, Err: transport.ResponseError(statusCode, "")})
+	}
+
+	assert.Equal(t, ExitOK, ExitCode(nil))
+	assert.Equal(t, ExitFailure, ExitCode(errors.New("unexpected")))
+	assert.Equal(t, ExitUsage, ExitCode(&UsageError{Err: errors.New("unknown flag")}))
+	assert.Equal(t, ExitConfig, ExitCode(wrap(&config.Error{Err: errors.New("no config file found")})))
+	assert.Equal(t, ExitValidation, ExitCode(&utm.InvalidTransitionError{From: utm.StateEnded, To: utm.StateActivated}))
+	assert.Equal(t, ExitValidation, ExitCode(&state.EnvironmentError{Name: "A", Environment: "local", Selected: "staging"}))
+	assert.Equal(t, ExitConflict, ExitCode(remote(http.StatusConflict)))
+	assert.Equal(t, ExitClientError, ExitCode(remote(http.StatusForbidden)))
+	assert.Equal(t, ExitServerError, ExitCode(remote(http.StatusServiceUnavailable)))
+	assert.Equal(t, ExitTimeout, ExitCode(wrap(&uspace_client.MannaUtmError{Err: &transport.TimeoutError{Err: errors.New("deadline")}})))
+	assert.Equal(t, ExitNetwork, ExitCode(wrap(&uspace_client.MannaUtmError{Err: &transport.NetworkError{Err: errors.New("connection refused")}})))
+	assert.Equal(t, ExitNetwork, ExitCode(&transport.CircuitOpenError{Host: "localhost"}))
+}
@@ -0,0 +1,61 @@
+package cli_error
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// TypeArgsErrors makes the errors of the positional argument validators of
+// <cmd> and its subcommands UsageErrors, including the unknown command error
+// of <cmd> if it is a root command with subcommands.
+func TypeArgsErrors(cmd *cobra.Command) {
+	args := cmd.Args
+	if args == nil && cmd.HasSubCommands() && !cmd.HasParent() {
+		args = unknownCommand
+	}
+	if args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			if err := args(cmd, a); err != nil {
+				return &UsageError{Err: err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		TypeArgsErrors(sub)
+	}
+}
+
+// unknownCommand is the positional argument validator that cobra uses for a
+// root command with subcommands, which rejects any argument as an unknown
+// command.
+func unknownCommand(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	err := fmt.Sprintf("unknown command %q for %q", args[0], cmd.CommandPath())
+	if cmd.SuggestionsMinimumDistance <= 0 {
+		// the default of cobra
+		cmd.SuggestionsMinimumDistance = 2
+	}
+	if suggestions := cmd.SuggestionsFor(args[0]); !cmd.DisableSuggestions && len(suggestions) > 0 {
+		err += "\n\nDid you mean this?\n\t" + strings.Join(suggestions, "\n\t")
+	}
+	return fmt.Errorf("%s", err)
+}
+
+// ValidateFlags checks the required flags and flag groups of <cmd>, returning
+// a UsageError if any are not satisfied. cobra checks them itself only after
+// the pre-run hooks, and without typing the error, so it is called from the
+// persistent pre-run hook of the root command.
+func ValidateFlags(cmd *cobra.Command) error {
+	if err := cmd.ValidateRequiredFlags(); err != nil {
+		return &UsageError{Err: err}
+	}
+	if err := cmd.ValidateFlagGroups(); err != nil {
+		return &UsageError{Err: err}
+	}
+	return nil
+}
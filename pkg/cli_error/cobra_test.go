@@ -0,0 +1,49 @@
+package cli_error
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRoot is a root command with a subcommand <sub> that takes one argument
+// and requires the flag --name.
+func newRoot() *cobra.Command {
+	root := &cobra.Command{Use: "root", SilenceErrors: true, SilenceUsage: true, RunE: func(*cobra.Command, []string) error { return nil }}
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return ValidateFlags(cmd)
+	}
+	sub := &cobra.Command{Use: "sub", Args: cobra.ExactArgs(1), RunE: func(*cobra.Command, []string) error { return nil }}
+	sub.Flags().String("name", "", "")
+	sub.MarkFlagRequired("name")
+	root.AddCommand(sub)
+	TypeArgsErrors(root)
+	return root
+}
+
+func TestUsageErrorsOfCobra(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		exit int
+	}{
+		{"valid", []string{"sub", "--name", "a", "x"}, ExitOK},
+		{"no command", nil, ExitOK},
+		{"unknown command", []string{"sbu"}, ExitUsage},
+		{"too many arguments", []string{"sub", "--name", "a", "x", "y"}, ExitUsage},
+		{"missing required flag", []string{"sub", "x"}, ExitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newRoot()
+			root.SetArgs(tt.args)
+			err := root.Execute()
+			assert.Equal(t, tt.exit, ExitCode(err), "%v", err)
+		})
+	}
+
+	root := newRoot()
+	root.SetArgs([]string{"sbu"})
+	assert.ErrorContains(t, root.Execute(), "Did you mean this?\n\tsub")
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -17,6 +18,10 @@ type Config struct {
 	Name                     string                    `yaml:"name"`
 	MannaUtmPort             int                       `yaml:"manna_utm_port"`
 	RidDpPort                int                       `yaml:"rid_dp_port"`
+	RidDpServer              ServerConfig              `yaml:"rid_dp_server"`
+	DssPort                  int                       `yaml:"dss_port"`
+	DefaultEnvironment       string                    `yaml:"default_environment"`
+	Environments             []EnvironmentConfig       `yaml:"environments"`
 	OperationalIntentConfigs []OperationalIntentConfig `yaml:"operational_intent_configs"`
 	FourDVolumes             []Volume4dConfig          `yaml:"4d_volumes"`
 }
@@ -28,7 +33,7 @@ func (appCnf *Config) GetOperationalIntentConfigByName(name string) (*Operationa
 		}
 	}
 
-	return nil, fmt.Errorf("no operational intent is configured by the name: %s", name)
+	return nil, &Error{Err: fmt.Errorf("no operational intent is configured by the name: %s", name)}
 }
 
 func (appCnf *Config) Get4dVolumeConfigByName(name string) (*Volume4dConfig, error) {
@@ -38,24 +43,35 @@ func (appCnf *Config) Get4dVolumeConfigByName(name string) (*Volume4dConfig, err
 		}
 	}
 
-	return nil, fmt.Errorf("no operational intent is configured by the name: %s", name)
+	return nil, &Error{Err: fmt.Errorf("no 4d volume is configured by the name: %s", name)}
+}
+
+// Error is a problem with the config file, or with a setting selected from
+// it, that the user has to fix before running the command again.
+type Error struct {
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
 }
 
 func LoadConfig(path string) (*Config, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("read config: %w", err)
+		return nil, &Error{Err: fmt.Errorf("read config: %w", err)}
 	}
 
 	var cfg Config
 	if err := yaml.Unmarshal(b, &cfg); err != nil {
-		return nil, fmt.Errorf("parse yaml: %w", err)
+		return nil, &Error{Err: fmt.Errorf("parse yaml: %w", err)}
 	}
 
-	var cfgName string
-	cfgName = strings.TrimSuffix(path, ".yaml")
-	cfgName = strings.TrimPrefix(cfgName, "./.libconfig/")
-	cfg.Name = cfgName
+	cfg.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 
 	return &cfg, nil
 }
@@ -63,9 +79,7 @@ func LoadConfig(path string) (*Config, error) {
 func (appCnf *Config) ToGeoJson() *geojson.FeatureCollection {
 	var featureCollection geojson.FeatureCollection
 	for _, intent := range appCnf.OperationalIntentConfigs {
-		for _, feature := range *intent.geoJsonFeatureSlice() {
-			featureCollection.Append(&feature)
-		}
+		featureCollection.Features = append(featureCollection.Features, intent.ToGeoJson().Features...)
 	}
 	return &featureCollection
 }
@@ -79,6 +93,10 @@ type OperationalIntentConfig struct {
 	UavId               int           `yaml:"uav_id"`
 	Duration            time.Duration `yaml:"duration"`
 	WaypointCoordinates [][2]float64  `yaml:"waypoint_coordinates"`
+	// AircraftType is the ASTM F3411 UA type the flight is reported as by
+	// riddp, e.g. Aeroplane or Helicopter. It is reported as Other if it is
+	// not set.
+	AircraftType string `yaml:"aircraft_type"`
 }
 
 type Volume4dConfig struct {
@@ -89,6 +107,15 @@ type Volume4dConfig struct {
 	PolygonCoords [][2]float64  `yaml:"polygon_coords"`
 }
 
+// ToGeoJson is the 4d volumes of the operational intent, starting now.
+func (oic OperationalIntentConfig) ToGeoJson() *geojson.FeatureCollection {
+	featureCollection := geojson.NewFeatureCollection()
+	for _, feature := range *oic.geoJsonFeatureSlice() {
+		featureCollection.Append(&feature)
+	}
+	return featureCollection
+}
+
 func (oic OperationalIntentConfig) geoJsonFeatureSlice() *[]geojson.Feature {
 	// Create all the 4d Volumes
 	var fc []geojson.Feature
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// ConfigPathEnvVar is the environment variable used to point the CLI at a
+	// config file when --config is not set.
+	ConfigPathEnvVar = "MANNA_UTM_CLI_CONFIG"
+	// ConfigFileName is the name of the config file searched for when neither
+	// --config nor ConfigPathEnvVar are set.
+	ConfigFileName = "config.yaml"
+	// LibConfigDir is the directory, relative to the working directory, that
+	// the CLI keeps local simulations and generated data in.
+	LibConfigDir = ".libconfig"
+)
+
+// SearchPaths returns the paths searched, in order, for ConfigFileName.
+func SearchPaths() []string {
+	paths := []string{
+		ConfigFileName,
+		filepath.Join(LibConfigDir, ConfigFileName),
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "manna-utm-cli", ConfigFileName))
+	}
+	return paths
+}
+
+// ResolvePath returns the path of the config file to load. An explicit
+// <path> takes precedence over ConfigPathEnvVar, which takes precedence over
+// the first existing file in SearchPaths.
+func ResolvePath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if envPath := os.Getenv(ConfigPathEnvVar); envPath != "" {
+		return envPath, nil
+	}
+
+	searched := SearchPaths()
+	for _, candidate := range searched {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", &Error{Err: fmt.Errorf("stat config %s: %w", candidate, err)}
+		}
+	}
+
+	return "", &Error{Err: fmt.Errorf("no config file found, set --config or %s, or create one of: %s", ConfigPathEnvVar, strings.Join(searched, ", "))}
+}
+
+// Loader resolves and loads the config file on first use, and returns the
+// same config for every subsequent use.
+type Loader struct {
+	path        string
+	environment string
+	once        sync.Once
+	cfg         *Config
+	err         error
+}
+
+// NewLoader returns a Loader for the config at <path>, which may be empty to
+// fall back to ConfigPathEnvVar and SearchPaths. <environment> selects the
+// environment returned by EnvironmentFromContext, and may be empty to use the
+// default environment.
+func NewLoader(path string, environment string) *Loader {
+	return &Loader{path: path, environment: environment}
+}
+
+func (l *Loader) Load() (*Config, error) {
+	l.once.Do(func() {
+		var path string
+		path, l.err = ResolvePath(l.path)
+		if l.err != nil {
+			return
+		}
+		l.cfg, l.err = LoadConfig(path)
+	})
+	return l.cfg, l.err
+}
+
+type loaderContextKey struct{}
+
+// NewContext returns a copy of <ctx> carrying <loader>.
+func NewContext(ctx context.Context, loader *Loader) context.Context {
+	return context.WithValue(ctx, loaderContextKey{}, loader)
+}
+
+func loaderFromContext(ctx context.Context) (*Loader, error) {
+	loader, ok := ctx.Value(loaderContextKey{}).(*Loader)
+	if !ok {
+		return nil, errors.New("no config loader in context")
+	}
+	return loader, nil
+}
+
+// FromContext loads the config using the Loader carried by <ctx>.
+func FromContext(ctx context.Context) (*Config, error) {
+	loader, err := loaderFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return loader.Load()
+}
+
+// EnvironmentFromContext loads the config using the Loader carried by <ctx>,
+// and returns the environment selected by the Loader.
+func EnvironmentFromContext(ctx context.Context) (*EnvironmentConfig, error) {
+	loader, err := loaderFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.GetEnvironmentConfigByName(loader.environment)
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePath(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	t.Setenv(ConfigPathEnvVar, "")
+
+	_, err := ResolvePath("")
+	assert.Error(t, err, "no config anywhere")
+
+	xdgPath := filepath.Join(dir, "xdg", "manna-utm-cli", ConfigFileName)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(xdgPath), os.ModePerm))
+	assert.NoError(t, os.WriteFile(xdgPath, []byte("name: xdg"), 0644))
+	path, err := ResolvePath("")
+	assert.NoError(t, err)
+	assert.Equal(t, xdgPath, path)
+
+	libPath := filepath.Join(LibConfigDir, ConfigFileName)
+	assert.NoError(t, os.MkdirAll(LibConfigDir, os.ModePerm))
+	assert.NoError(t, os.WriteFile(libPath, []byte("name: lib"), 0644))
+	path, err = ResolvePath("")
+	assert.NoError(t, err)
+	assert.Equal(t, libPath, path)
+
+	assert.NoError(t, os.WriteFile(ConfigFileName, []byte("name: cwd"), 0644))
+	path, err = ResolvePath("")
+	assert.NoError(t, err)
+	assert.Equal(t, ConfigFileName, path)
+
+	t.Setenv(ConfigPathEnvVar, "env.yaml")
+	path, err = ResolvePath("")
+	assert.NoError(t, err)
+	assert.Equal(t, "env.yaml", path)
+
+	path, err = ResolvePath("flag.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "flag.yaml", path)
+}
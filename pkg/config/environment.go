@@ -0,0 +1,158 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+)
+
+// EnvironmentConfig is a named manna-utm deployment that the client commands
+// can be pointed at.
+type EnvironmentConfig struct {
+	Name string `yaml:"name"`
+	// BaseURL is the full URL of the manna-utm deployment, including the
+	// scheme and any path prefix, e.g. https://utm.example.com/api.
+	BaseURL string     `yaml:"base_url"`
+	TLS     TLSConfig  `yaml:"tls"`
+	Auth    AuthConfig `yaml:"auth"`
+	// Timeout bounds each attempt at a request, defaulting to 15s.
+	Timeout        time.Duration        `yaml:"timeout"`
+	Retry          RetryConfig          `yaml:"retry"`
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+}
+
+// RetryConfig configures how the clients retry requests that fail with a
+// network error or a 429, 502, 503 or 504 response. Zero values use the
+// defaults of the transport package.
+type RetryConfig struct {
+	// MaxAttempts is the number of times a request is sent, including the
+	// first, so 1 disables retries.
+	MaxAttempts int `yaml:"max_attempts"`
+	// InitialBackoff is the upper bound of the wait before the first retry,
+	// which doubles for each retry up to MaxBackoff.
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+// CircuitBreakerConfig configures the circuit breaker kept for each host.
+// Zero values use the defaults of the transport package.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed attempts that
+	// opens the circuit, a negative value disables the circuit breaker.
+	FailureThreshold int `yaml:"failure_threshold"`
+	// Cooldown is how long the circuit stays open before a single request is
+	// let through to test the host again.
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+const (
+	AuthTypeStatic            = "static"
+	AuthTypeClientCredentials = "client_credentials"
+	AuthTypeDummyOAuth        = "dummy_oauth"
+)
+
+// AuthConfig configures how the clients obtain the bearer tokens sent to an
+// environment. An empty Type sends no Authorization header.
+type AuthConfig struct {
+	// Type is one of AuthTypeStatic, AuthTypeClientCredentials or AuthTypeDummyOAuth.
+	Type string `yaml:"type"`
+
+	// Token is the bearer token sent by static auth, or TokenEnvVar names the
+	// environment variable that holds it.
+	Token       string `yaml:"token"`
+	TokenEnvVar string `yaml:"token_env_var"`
+
+	// TokenURL, ClientID and ClientSecret are used by client_credentials auth.
+	TokenURL     string `yaml:"token_url"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+
+	// PrivateKeyFile, Issuer and Subject are used by dummy_oauth auth to sign
+	// tokens locally.
+	PrivateKeyFile string `yaml:"private_key_file"`
+	Issuer         string `yaml:"issuer"`
+	Subject        string `yaml:"subject"`
+
+	// Audience is the intended audience of the tokens, defaulting to the host
+	// of the environment's base_url.
+	Audience string `yaml:"audience"`
+}
+
+// TLSConfig configures the TLS client used to talk to an environment.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of CAs trusted in addition to the system pool.
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile are the PEM client certificate and key used for mTLS.
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// GetEnvironmentConfigByName returns the environment called <name>, or the
+// default environment when <name> is empty. When no environments are
+// configured, the default environment is manna-utm on localhost:<manna_utm_port>.
+func (appCnf *Config) GetEnvironmentConfigByName(name string) (*EnvironmentConfig, error) {
+	if name == "" {
+		name = appCnf.DefaultEnvironment
+	}
+
+	if len(appCnf.Environments) == 0 && name == "" {
+		return &EnvironmentConfig{
+			Name:    "local",
+			BaseURL: fmt.Sprintf("http://localhost:%d", appCnf.MannaUtmPort),
+		}, nil
+	}
+	if name == "" {
+		return nil, &Error{Err: fmt.Errorf("no environment selected, set --env or default_environment")}
+	}
+
+	for _, envCnf := range appCnf.Environments {
+		if envCnf.Name == name {
+			return &envCnf, nil
+		}
+	}
+
+	return nil, &Error{Err: fmt.Errorf("no environment is configured by the name: %s", name)}
+}
+
+// ClientConfig builds the TLS client config described by <t>, returning nil
+// when no TLS settings are configured.
+func (t TLSConfig) ClientConfig() (*tls.Config, error) {
+	if t == (TLSConfig{}) {
+		return nil, nil
+	}
+
+	tlsCnf := &tls.Config{
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, &Error{Err: fmt.Errorf("read ca bundle: %w", err)}
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, &Error{Err: fmt.Errorf("no certificates found in ca bundle: %s", t.CAFile)}
+		}
+		tlsCnf.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, &Error{Err: fmt.Errorf("both cert_file and key_file must be set to use a client certificate")}
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, &Error{Err: fmt.Errorf("load client certificate: %w", err)}
+		}
+		tlsCnf.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCnf, nil
+}
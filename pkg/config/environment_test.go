@@ -0,0 +1,159 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEnvironmentConfigByName(t *testing.T) {
+	local := EnvironmentConfig{Name: "local", BaseURL: "http://localhost:8080"}
+	staging := EnvironmentConfig{Name: "staging", BaseURL: "https://staging.example.com/api"}
+
+	tests := []struct {
+		name    string
+		cnf     Config
+		env     string
+		want    *EnvironmentConfig
+		wantErr bool
+	}{
+		{
+			name: "no environments",
+			cnf:  Config{MannaUtmPort: 8090},
+			want: &EnvironmentConfig{Name: "local", BaseURL: "http://localhost:8090"},
+		},
+		{
+			name:    "no environments, unknown name",
+			cnf:     Config{MannaUtmPort: 8090},
+			env:     "staging",
+			wantErr: true,
+		},
+		{
+			name: "by name",
+			cnf:  Config{Environments: []EnvironmentConfig{local, staging}},
+			env:  "staging",
+			want: &staging,
+		},
+		{
+			name: "default environment",
+			cnf:  Config{Environments: []EnvironmentConfig{local, staging}, DefaultEnvironment: "staging"},
+			want: &staging,
+		},
+		{
+			name: "name over default environment",
+			cnf:  Config{Environments: []EnvironmentConfig{local, staging}, DefaultEnvironment: "staging"},
+			env:  "local",
+			want: &local,
+		},
+		{
+			name:    "no name and no default environment",
+			cnf:     Config{Environments: []EnvironmentConfig{local, staging}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown name",
+			cnf:     Config{Environments: []EnvironmentConfig{local, staging}},
+			env:     "prod",
+			wantErr: true,
+		},
+		{
+			name:    "unknown default environment",
+			cnf:     Config{Environments: []EnvironmentConfig{local, staging}, DefaultEnvironment: "prod"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cnf.GetEnvironmentConfigByName(tt.env)
+			if tt.wantErr {
+				var cnfErr *Error
+				assert.True(t, errors.As(err, &cnfErr), "expected a config error, got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// writeCert writes a self-signed PEM certificate and its key to <dir>,
+// returning their paths.
+func writeCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "manna-utm-cli test"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestTLSClientConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir)
+	notPem := filepath.Join(dir, "not.pem")
+	require.NoError(t, os.WriteFile(notPem, []byte("not a certificate"), 0644))
+
+	tests := []struct {
+		name      string
+		tls       TLSConfig
+		wantNil   bool
+		wantErr   bool
+		wantCAs   bool
+		wantCerts int
+	}{
+		{name: "not configured", tls: TLSConfig{}, wantNil: true},
+		{name: "insecure", tls: TLSConfig{InsecureSkipVerify: true}},
+		{name: "ca bundle", tls: TLSConfig{CAFile: certFile}, wantCAs: true},
+		{name: "missing ca bundle", tls: TLSConfig{CAFile: filepath.Join(dir, "missing.pem")}, wantErr: true},
+		{name: "ca bundle without certificates", tls: TLSConfig{CAFile: notPem}, wantErr: true},
+		{name: "client certificate", tls: TLSConfig{CertFile: certFile, KeyFile: keyFile}, wantCerts: 1},
+		{name: "cert without key", tls: TLSConfig{CertFile: certFile}, wantErr: true},
+		{name: "key without cert", tls: TLSConfig{KeyFile: keyFile}, wantErr: true},
+		{name: "invalid client certificate", tls: TLSConfig{CertFile: notPem, KeyFile: keyFile}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.tls.ClientConfig()
+			if tt.wantErr {
+				var cnfErr *Error
+				assert.True(t, errors.As(err, &cnfErr), "expected a config error, got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantNil {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, tt.tls.InsecureSkipVerify, got.InsecureSkipVerify)
+			assert.Equal(t, tt.wantCAs, got.RootCAs != nil)
+			assert.Len(t, got.Certificates, tt.wantCerts)
+		})
+	}
+}
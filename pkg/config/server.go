@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ServerConfig configures the HTTP server of the riddp command. Zero values
+// use the defaults of the server.
+type ServerConfig struct {
+	// BindAddress is the host or IP the server listens on, every interface if
+	// it is not set.
+	BindAddress string `yaml:"bind_address"`
+	// AllowedOrigins are the browser origins allowed to call the server and
+	// stream the flights from it, "*" allowing any origin. The server's own
+	// origin is always allowed.
+	AllowedOrigins []string        `yaml:"allowed_origins"`
+	TLS            ServerTLSConfig `yaml:"tls"`
+	// MaxRequestBytes bounds the size of request bodies, and MaxHeaderBytes
+	// the size of request headers.
+	MaxRequestBytes int64 `yaml:"max_request_bytes"`
+	MaxHeaderBytes  int   `yaml:"max_header_bytes"`
+	// ReadTimeout bounds reading a request, and WriteTimeout writing its
+	// response. The event streams are not bound by WriteTimeout.
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// IdleTimeout is how long a keep-alive connection waits for the next
+	// request.
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout is how long the server waits for its requests to
+	// finish when it shuts down.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// ServerTLSConfig is the certificate a server is served with over HTTPS.
+type ServerTLSConfig struct {
+	// CertFile and KeyFile are the PEM certificate chain and key of the
+	// server, it is served over plain HTTP if neither is set.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// Enabled is whether the server is served over HTTPS.
+func (t ServerTLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// Validate checks that <s> can be served, returning an Error if not.
+func (s ServerConfig) Validate() error {
+	if s.TLS.Enabled() && (s.TLS.CertFile == "" || s.TLS.KeyFile == "") {
+		return &Error{Err: fmt.Errorf("both cert_file and key_file must be set to serve over TLS")}
+	}
+	if s.MaxRequestBytes < 0 || s.MaxHeaderBytes < 0 {
+		return &Error{Err: fmt.Errorf("max_request_bytes and max_header_bytes must not be negative")}
+	}
+	if s.ReadTimeout < 0 || s.WriteTimeout < 0 || s.IdleTimeout < 0 || s.ShutdownTimeout < 0 {
+		return &Error{Err: fmt.Errorf("read_timeout, write_timeout, idle_timeout and shutdown_timeout must not be negative")}
+	}
+	return nil
+}
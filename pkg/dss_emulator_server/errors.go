@@ -0,0 +1,74 @@
+package dss_emulator_server
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"manna.aero/manna.utm.cli/model/utm"
+)
+
+type notFoundError struct {
+	kind string
+	id   uuid.UUID
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("%s %s does not exist", e.kind, e.id)
+}
+
+type existsError struct {
+	kind string
+	id   uuid.UUID
+}
+
+func (e *existsError) Error() string {
+	return fmt.Sprintf("%s %s already exists", e.kind, e.id)
+}
+
+// forbiddenError is returned for a change to an entity by a USS that does not
+// manage it.
+type forbiddenError struct {
+	kind    string
+	id      uuid.UUID
+	manager string
+}
+
+func (e *forbiddenError) Error() string {
+	return fmt.Sprintf("%s %s is managed by %q", e.kind, e.id, e.manager)
+}
+
+// staleVersionError is returned for a change that was not made against the
+// current OVN, or version of a subscription, of an entity.
+type staleVersionError struct {
+	kind    string
+	id      uuid.UUID
+	version string
+}
+
+func (e *staleVersionError) Error() string {
+	return fmt.Sprintf("%s is not the current version of %s %s", e.version, e.kind, e.id)
+}
+
+type badRequestError struct {
+	err error
+}
+
+func (e *badRequestError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(format string, args ...any) error {
+	return &badRequestError{err: fmt.Errorf(format, args...)}
+}
+
+// airspaceConflictError is returned for a change whose key is missing the OVNs
+// of operational intents or constraints that its extents intersect.
+type airspaceConflictError struct {
+	missingOperationalIntents []utm.OperationalIntentReference
+	missingConstraints        []utm.ConstraintReference
+}
+
+func (e *airspaceConflictError) Error() string {
+	return fmt.Sprintf("the key is missing the OVNs of %d operational intents and %d constraints in the airspace",
+		len(e.missingOperationalIntents), len(e.missingConstraints))
+}
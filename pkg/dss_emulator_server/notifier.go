@@ -0,0 +1,167 @@
+package dss_emulator_server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/transport"
+	"manna.aero/manna.utm.cli/pkg/uss_client"
+)
+
+const notifyTimeout = 30 * time.Second
+
+// notifier notifies the subscribers to a change on behalf of the USS that
+// made it, for scenarios where that USS does not notify them itself. The
+// details sent are fetched from the uss_base_url of the changed entity.
+//
+// The notifications are sent in the background, so that the change is not
+// held up by slow subscribers, and are waited for by shutdown.
+type notifier struct {
+	policy transport.Policy
+
+	// ctx is that of every notification, cancelled when shutdown gives up
+	// waiting for them.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock     sync.Mutex
+	wg       sync.WaitGroup
+	shutting bool
+}
+
+func newNotifier(policy transport.Policy) *notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &notifier{policy: policy, ctx: ctx, cancel: cancel}
+}
+
+// notify runs <send> in the background, with a context bounded by
+// notifyTimeout. Once shutdown has started, <send> is run before notify
+// returns instead, so that the request that made the change is held in flight
+// until its subscribers have been notified.
+func (n *notifier) notify(send func(ctx context.Context)) {
+	run := func() {
+		ctx, cancel := context.WithTimeout(n.ctx, notifyTimeout)
+		defer cancel()
+		send(ctx)
+	}
+
+	n.lock.Lock()
+	if n.shutting {
+		n.lock.Unlock()
+		run()
+		return
+	}
+	n.wg.Add(1)
+	n.lock.Unlock()
+
+	go func() {
+		defer n.wg.Done()
+		run()
+	}()
+}
+
+// shutdown waits for the notifications being sent in the background to
+// finish, until <ctx> is done, when those left are cancelled.
+func (n *notifier) shutdown(ctx context.Context) error {
+	n.lock.Lock()
+	n.shutting = true
+	n.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		n.cancel()
+		return fmt.Errorf("error occurred notifying subscribers: %w", ctx.Err())
+	}
+}
+
+// operationalIntentChanged notifies <subscribers> that the operational intent
+// <entityId> changed to <ref>, or was removed if <ref> is nil.
+func (n *notifier) operationalIntentChanged(entityId uuid.UUID, ref *utm.OperationalIntentReference, subscribers []utm.SubscriberToNotify) {
+	if len(subscribers) == 0 {
+		return
+	}
+	n.notify(func(ctx context.Context) {
+		var oi *utm.OperationalIntent
+		if ref != nil {
+			client, err := uss_client.NewUssClient(string(ref.UssBaseUrl), nil, n.policy)
+			if err != nil {
+				log.Warnf("could not notify subscribers of operational intent %s: %v", entityId, err)
+				return
+			}
+			oi, err = client.GetOperationalIntentDetails(ctx, entityId.String())
+			if err != nil {
+				log.Warnf("could not notify subscribers of operational intent %s, fetching its details failed: %v", entityId, err)
+				return
+			}
+			oi.Reference = *ref
+		}
+
+		for _, subscriber := range subscribers {
+			client, err := uss_client.NewUssClient(string(subscriber.UssBaseUrl), nil, n.policy)
+			if err == nil {
+				err = client.NotifyOperationalIntentDetailsChanged(ctx, utm.PutOperationalIntentDetailsParameters{
+					OperationalIntentId: entityId,
+					OperationalIntent:   oi,
+					Subscriptions:       subscriber.Subscriptions,
+				})
+			}
+			if err != nil {
+				log.Warnf("could not notify %s of operational intent %s: %v", subscriber.UssBaseUrl, entityId, err)
+				continue
+			}
+			log.Infof("notified %s of operational intent %s", subscriber.UssBaseUrl, entityId)
+		}
+	})
+}
+
+// constraintChanged notifies <subscribers> that the constraint <entityId>
+// changed to <ref>, or was removed if <ref> is nil.
+func (n *notifier) constraintChanged(entityId uuid.UUID, ref *utm.ConstraintReference, subscribers []utm.SubscriberToNotify) {
+	if len(subscribers) == 0 {
+		return
+	}
+	n.notify(func(ctx context.Context) {
+		var constraint *utm.Constraint
+		if ref != nil {
+			client, err := uss_client.NewUssClient(string(ref.UssBaseUrl), nil, n.policy)
+			if err != nil {
+				log.Warnf("could not notify subscribers of constraint %s: %v", entityId, err)
+				return
+			}
+			constraint, err = client.GetConstraintDetails(ctx, entityId.String())
+			if err != nil {
+				log.Warnf("could not notify subscribers of constraint %s, fetching its details failed: %v", entityId, err)
+				return
+			}
+			constraint.Reference = *ref
+		}
+
+		for _, subscriber := range subscribers {
+			client, err := uss_client.NewUssClient(string(subscriber.UssBaseUrl), nil, n.policy)
+			if err == nil {
+				err = client.NotifyConstraintDetailsChanged(ctx, utm.PutConstraintDetailsParameters{
+					ConstraintId:  entityId,
+					Constraint:    constraint,
+					Subscriptions: subscriber.Subscriptions,
+				})
+			}
+			if err != nil {
+				log.Warnf("could not notify %s of constraint %s: %v", subscriber.UssBaseUrl, entityId, err)
+				continue
+			}
+			log.Infof("notified %s of constraint %s", subscriber.UssBaseUrl, entityId)
+		}
+	})
+}
@@ -0,0 +1,331 @@
+package dss_emulator_server
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/paulmach/orb"
+	"manna.aero/manna.utm.cli/model/rid"
+	"manna.aero/manna.utm.cli/model/utm"
+)
+
+const (
+	kindIdentificationServiceArea = "identification service area"
+	kindRidSubscription           = "RID subscription"
+)
+
+type identificationServiceAreaEntry struct {
+	isa     rid.IdentificationServiceArea
+	version int
+	extents utm.Volume4d
+}
+
+type ridSubscriptionEntry struct {
+	sub     rid.Subscription
+	version int
+	extents utm.Volume4d
+}
+
+// ridStore is the ASTM F3411-22a airspace of the DSS emulator: the
+// Identification Service Areas of Service Providers, and the subscriptions of
+// Display Providers to them.
+type ridStore struct {
+	lock          sync.Mutex
+	isas          map[uuid.UUID]*identificationServiceAreaEntry
+	subscriptions map[uuid.UUID]*ridSubscriptionEntry
+}
+
+func newRidStore() *ridStore {
+	return &ridStore{
+		isas:          map[uuid.UUID]*identificationServiceAreaEntry{},
+		subscriptions: map[uuid.UUID]*ridSubscriptionEntry{},
+	}
+}
+
+// putIdentificationServiceArea creates the Identification Service Area <id>
+// of <owner>, or updates it if <version> is set, returning the subscribers to
+// notify of it.
+func (s *ridStore) putIdentificationServiceArea(id uuid.UUID, version string, owner string, params rid.CreateIdentificationServiceAreaParameters, now time.Time) (*rid.PutIdentificationServiceAreaResponse, error) {
+	extents, err := ridExtents(params.Extents, now)
+	if err != nil {
+		return nil, err
+	}
+	if params.UssBaseUrl == "" {
+		return nil, badRequest("uss_base_url is required")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.isas[id]
+	if err := checkVersionedChange(kindIdentificationServiceArea, id, version, owner, ok, func() (string, string) {
+		return entry.isa.Owner, entry.isa.Version
+	}); err != nil {
+		return nil, err
+	}
+	affected := []utm.Volume4d{extents}
+	if ok {
+		affected = append(affected, entry.extents)
+	} else {
+		entry = &identificationServiceAreaEntry{isa: rid.IdentificationServiceArea{Id: id.String(), Owner: owner}}
+		s.isas[id] = entry
+	}
+	entry.version++
+	entry.extents = extents
+	entry.isa.Version = strconv.Itoa(entry.version)
+	entry.isa.UssBaseUrl = params.UssBaseUrl
+	entry.isa.TimeStart = utm.NewTime(extents.TimeStart)
+	entry.isa.TimeEnd = utm.NewTime(extents.TimeEnd)
+
+	return &rid.PutIdentificationServiceAreaResponse{
+		Subscribers: s.subscribers(affected),
+		ServiceArea: entry.isa,
+	}, nil
+}
+
+func (s *ridStore) deleteIdentificationServiceArea(id uuid.UUID, version string, owner string) (*rid.DeleteIdentificationServiceAreaResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.isas[id]
+	if err := checkVersionedChange(kindIdentificationServiceArea, id, version, owner, ok, func() (string, string) {
+		return entry.isa.Owner, entry.isa.Version
+	}); err != nil {
+		return nil, err
+	}
+	delete(s.isas, id)
+	return &rid.DeleteIdentificationServiceAreaResponse{
+		Subscribers: s.subscribers([]utm.Volume4d{entry.extents}),
+		ServiceArea: entry.isa,
+	}, nil
+}
+
+func (s *ridStore) getIdentificationServiceArea(id uuid.UUID) (*rid.IdentificationServiceArea, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.isas[id]
+	if !ok {
+		return nil, &notFoundError{kind: kindIdentificationServiceArea, id: id}
+	}
+	isa := entry.isa
+	return &isa, nil
+}
+
+// searchIdentificationServiceAreas is the Identification Service Areas that
+// intersect <area>.
+func (s *ridStore) searchIdentificationServiceAreas(area utm.Volume4d) []rid.IdentificationServiceArea {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.identificationServiceAreasIn(area)
+}
+
+func (s *ridStore) identificationServiceAreasIn(area utm.Volume4d) []rid.IdentificationServiceArea {
+	isas := []rid.IdentificationServiceArea{}
+	for _, entry := range s.isas {
+		if entry.extents.Intersects(area) {
+			isas = append(isas, entry.isa)
+		}
+	}
+	sort.Slice(isas, func(i, j int) bool { return isas[i].Id < isas[j].Id })
+	return isas
+}
+
+// putSubscription creates the subscription <id> of <owner>, or updates it if
+// <version> is set.
+func (s *ridStore) putSubscription(id uuid.UUID, version string, owner string, params rid.CreateSubscriptionParameters, now time.Time) (*rid.PutSubscriptionResponse, error) {
+	extents, err := ridExtents(params.Extents, now)
+	if err != nil {
+		return nil, err
+	}
+	if params.UssBaseUrl == "" {
+		return nil, badRequest("uss_base_url is required")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.subscriptions[id]
+	if err := checkVersionedChange(kindRidSubscription, id, version, owner, ok, func() (string, string) {
+		return entry.sub.Owner, entry.sub.Version
+	}); err != nil {
+		return nil, err
+	}
+	if !ok {
+		entry = &ridSubscriptionEntry{sub: rid.Subscription{Id: id.String(), Owner: owner}}
+		s.subscriptions[id] = entry
+	}
+	entry.version++
+	entry.extents = extents
+	entry.sub.Version = strconv.Itoa(entry.version)
+	entry.sub.UssBaseUrl = params.UssBaseUrl
+	entry.sub.TimeStart = utm.NewTime(extents.TimeStart)
+	entry.sub.TimeEnd = utm.NewTime(extents.TimeEnd)
+
+	return &rid.PutSubscriptionResponse{
+		ServiceAreas: s.identificationServiceAreasIn(extents),
+		Subscription: entry.sub,
+	}, nil
+}
+
+func (s *ridStore) deleteSubscription(id uuid.UUID, version string, owner string) (*rid.Subscription, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.subscriptions[id]
+	if err := checkVersionedChange(kindRidSubscription, id, version, owner, ok, func() (string, string) {
+		return entry.sub.Owner, entry.sub.Version
+	}); err != nil {
+		return nil, err
+	}
+	delete(s.subscriptions, id)
+	return &entry.sub, nil
+}
+
+func (s *ridStore) getSubscription(id uuid.UUID, requester string) (*rid.Subscription, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.subscriptions[id]
+	if !ok {
+		return nil, &notFoundError{kind: kindRidSubscription, id: id}
+	}
+	if entry.sub.Owner != requester {
+		return nil, &forbiddenError{kind: kindRidSubscription, id: id, manager: entry.sub.Owner}
+	}
+	sub := entry.sub
+	return &sub, nil
+}
+
+// searchSubscriptions is the subscriptions of <requester> that intersect
+// <area>.
+func (s *ridStore) searchSubscriptions(area utm.Volume4d, requester string) []rid.Subscription {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	subs := []rid.Subscription{}
+	for _, entry := range s.subscriptions {
+		if entry.sub.Owner == requester && entry.extents.Intersects(area) {
+			subs = append(subs, entry.sub)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Id < subs[j].Id })
+	return subs
+}
+
+// subscribers is the Display Providers to notify of a change to <extents>,
+// with each of their subscriptions to it. The notification index of each is
+// incremented.
+func (s *ridStore) subscribers(extents []utm.Volume4d) []rid.SubscriberToNotify {
+	ids := make([]uuid.UUID, 0, len(s.subscriptions))
+	for id := range s.subscriptions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+
+	subscribers := []rid.SubscriberToNotify{}
+	for _, id := range ids {
+		entry := s.subscriptions[id]
+		if !utm.AnyIntersect([]utm.Volume4d{entry.extents}, extents) {
+			continue
+		}
+		entry.sub.NotificationIndex++
+		state := rid.SubscriptionState{SubscriptionId: entry.sub.Id, NotificationIndex: entry.sub.NotificationIndex}
+
+		i := slices.IndexFunc(subscribers, func(sub rid.SubscriberToNotify) bool { return sub.Url == entry.sub.UssBaseUrl })
+		if i < 0 {
+			subscribers = append(subscribers, rid.SubscriberToNotify{Url: entry.sub.UssBaseUrl})
+			i = len(subscribers) - 1
+		}
+		subscribers[i].Subscriptions = append(subscribers[i].Subscriptions, state)
+	}
+	return subscribers
+}
+
+// checkVersionedChange checks that a change by <owner> to the entity <id> at
+// <version> is allowed: it creates the entity if <version> is empty, otherwise
+// it updates an existing entity that <owner> owns and is currently at
+// <version>.
+func checkVersionedChange(kind string, id uuid.UUID, version string, owner string, exists bool, current func() (string, string)) error {
+	switch {
+	case version == "" && exists:
+		return &existsError{kind: kind, id: id}
+	case version == "":
+		return nil
+	case !exists:
+		return &notFoundError{kind: kind, id: id}
+	}
+	currentOwner, currentVersion := current()
+	if currentOwner != owner {
+		return &forbiddenError{kind: kind, id: id, manager: currentOwner}
+	}
+	if currentVersion != version {
+		return &staleVersionError{kind: kind, id: id, version: version}
+	}
+	return nil
+}
+
+// ridExtents validates the <extents> of an Identification Service Area or a
+// subscription, which start at <now> if they have no start time.
+func ridExtents(extents utm.Volume4d, now time.Time) (utm.Volume4d, error) {
+	if err := validateExtents([]utm.Volume4d{extents}); err != nil {
+		return extents, err
+	}
+	if extents.TimeEnd.IsZero() {
+		return extents, badRequest("extents.time_end is required")
+	}
+	if extents.TimeStart.IsZero() {
+		extents.TimeStart = now
+	}
+	return extents, nil
+}
+
+// parseArea parses an area of the form lat1,lng1,lat2,lng2,lat3,lng3,... into
+// a volume over all altitudes, between <earliest> and <latest> given as
+// RFC3339 times, either of which may be empty.
+func parseArea(area string, earliest string, latest string) (utm.Volume4d, error) {
+	parts := strings.Split(area, ",")
+	if len(parts) < 6 || len(parts)%2 != 0 {
+		return utm.Volume4d{}, badRequest("area must be at least 3 points as lat1,lng1,lat2,lng2,lat3,lng3")
+	}
+	ring := orb.Ring{}
+	for i := 0; i < len(parts); i += 2 {
+		lat, err := strconv.ParseFloat(strings.TrimSpace(parts[i]), 64)
+		if err != nil {
+			return utm.Volume4d{}, badRequest("area: %v", err)
+		}
+		lng, err := strconv.ParseFloat(strings.TrimSpace(parts[i+1]), 64)
+		if err != nil {
+			return utm.Volume4d{}, badRequest("area: %v", err)
+		}
+		ring = append(ring, orb.Point{lng, lat})
+	}
+	ring = append(ring, ring[0])
+
+	vol := utm.Volume4d{Volume: utm.Volume3d{
+		OutlinePolygon: orb.Polygon{ring},
+		AltitudeLower:  math.Inf(-1),
+		AltitudeUpper:  math.Inf(1),
+	}}
+	for _, bound := range []struct {
+		name  string
+		value string
+		t     *time.Time
+	}{{"earliest_time", earliest, &vol.TimeStart}, {"latest_time", latest, &vol.TimeEnd}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return utm.Volume4d{}, badRequest("%s: %v", bound.name, err)
+		}
+		*bound.t = t
+	}
+	return vol, nil
+}
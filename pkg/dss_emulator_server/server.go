@@ -0,0 +1,435 @@
+// Package dss_emulator_server is a local DSS that serves the ASTM F3548-21 DSS
+// API, seeded with the operational intents in the config, so that strategic
+// deconfliction can be tested against pre-existing traffic without a DSS
+// deployment. It also serves the ASTM F3411-22a DSS API, for Network Remote
+// ID.
+package dss_emulator_server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"manna.aero/manna.utm.cli/model/rid"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/auth"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/transport"
+)
+
+// Options configures the DSS emulator.
+type Options struct {
+	// BaseUrl is the URL of the emulator, which seeded operational intents
+	// without an owner_baseurl are published at.
+	BaseUrl string
+	// Departure is when the seeded operational intents depart.
+	Departure time.Time
+	// NotifySubscribers has the emulator notify the subscribers to each
+	// change, instead of leaving that to the USS that made it.
+	NotifySubscribers bool
+}
+
+// Server is the DSS emulator, which has to be shut down with Shutdown for the
+// subscribers to be notified of every change.
+type Server struct {
+	*gin.Engine
+	notifier *notifier
+}
+
+// Shutdown waits for the notifications of subscribers that are being sent to
+// finish, until <ctx> is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.notifier == nil {
+		return nil
+	}
+	return s.notifier.shutdown(ctx)
+}
+
+// GetServer serves a DSS seeded with the operational intents of <oiCnfs>.
+//
+// The USS making each request is told apart by the subject of its bearer
+// token, which is not verified.
+func GetServer(oiCnfs []config.OperationalIntentConfig, opts Options) *Server {
+	router := gin.Default()
+	s := newStore()
+	s.seed(oiCnfs, utm.UssBaseURL(opts.BaseUrl), opts.Departure)
+
+	var n *notifier
+	if opts.NotifySubscribers {
+		n = newNotifier(transport.DefaultPolicy())
+	}
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "manna-utm-cli DSS emulator is healthy.",
+		})
+	})
+
+	// see https://github.com/astm-utm/Protocol/blob/v1.0.0/utm.yaml
+	dss := router.Group("/dss/v1")
+
+	putOperationalIntent := func(c *gin.Context) {
+		entityId, ok := uuidParam(c, "entityid")
+		if !ok {
+			return
+		}
+		var params utm.PutOperationalIntentReferenceParameters
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+			return
+		}
+		ovn := utm.EntityOvn(c.Param("ovn"))
+		resp, err := s.putOperationalIntent(entityId, ovn, auth.UnverifiedSubject(c.Request), params)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		if n != nil {
+			n.operationalIntentChanged(entityId, &resp.OperationalIntentReference, resp.Subscribers)
+		}
+		if ovn == "" {
+			c.JSON(http.StatusCreated, resp)
+		} else {
+			c.JSON(http.StatusOK, resp)
+		}
+	}
+	dss.PUT("/operational_intent_references/:entityid", putOperationalIntent)
+	dss.PUT("/operational_intent_references/:entityid/:ovn", putOperationalIntent)
+
+	dss.DELETE("/operational_intent_references/:entityid/:ovn", func(c *gin.Context) {
+		entityId, ok := uuidParam(c, "entityid")
+		if !ok {
+			return
+		}
+		resp, err := s.deleteOperationalIntent(entityId, utm.EntityOvn(c.Param("ovn")), auth.UnverifiedSubject(c.Request))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		if n != nil {
+			n.operationalIntentChanged(entityId, nil, resp.Subscribers)
+		}
+		c.JSON(http.StatusOK, resp)
+	})
+
+	dss.GET("/operational_intent_references/:entityid", func(c *gin.Context) {
+		entityId, ok := uuidParam(c, "entityid")
+		if !ok {
+			return
+		}
+		ref, err := s.getOperationalIntent(entityId, auth.UnverifiedSubject(c.Request))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, utm.GetOperationalIntentReferenceResponse{OperationalIntentReference: *ref})
+	})
+
+	dss.POST("/operational_intent_references/query", func(c *gin.Context) {
+		var params utm.QueryOperationalIntentReferenceParameters
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+			return
+		}
+		if params.AreaOfInterest == nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: "area_of_interest is required"})
+			return
+		}
+		c.JSON(http.StatusOK, utm.QueryOperationalIntentReferenceResponse{
+			OperationalIntentReferences: s.queryOperationalIntents(*params.AreaOfInterest, auth.UnverifiedSubject(c.Request)),
+		})
+	})
+
+	putConstraint := func(c *gin.Context) {
+		entityId, ok := uuidParam(c, "entityid")
+		if !ok {
+			return
+		}
+		var params utm.PutConstraintReferenceParameters
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+			return
+		}
+		ovn := utm.EntityOvn(c.Param("ovn"))
+		resp, err := s.putConstraint(entityId, ovn, auth.UnverifiedSubject(c.Request), params)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		if n != nil {
+			n.constraintChanged(entityId, &resp.ConstraintReference, resp.Subscribers)
+		}
+		if ovn == "" {
+			c.JSON(http.StatusCreated, resp)
+		} else {
+			c.JSON(http.StatusOK, resp)
+		}
+	}
+	dss.PUT("/constraint_references/:entityid", putConstraint)
+	dss.PUT("/constraint_references/:entityid/:ovn", putConstraint)
+
+	dss.DELETE("/constraint_references/:entityid/:ovn", func(c *gin.Context) {
+		entityId, ok := uuidParam(c, "entityid")
+		if !ok {
+			return
+		}
+		resp, err := s.deleteConstraint(entityId, utm.EntityOvn(c.Param("ovn")), auth.UnverifiedSubject(c.Request))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		if n != nil {
+			n.constraintChanged(entityId, nil, resp.Subscribers)
+		}
+		c.JSON(http.StatusOK, resp)
+	})
+
+	dss.GET("/constraint_references/:entityid", func(c *gin.Context) {
+		entityId, ok := uuidParam(c, "entityid")
+		if !ok {
+			return
+		}
+		ref, err := s.getConstraint(entityId, auth.UnverifiedSubject(c.Request))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, utm.GetConstraintReferenceResponse{ConstraintReference: *ref})
+	})
+
+	dss.POST("/constraint_references/query", func(c *gin.Context) {
+		var params utm.QueryConstraintReferenceParameters
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+			return
+		}
+		if params.AreaOfInterest == nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: "area_of_interest is required"})
+			return
+		}
+		c.JSON(http.StatusOK, utm.QueryConstraintReferencesResponse{
+			ConstraintReferences: s.queryConstraints(*params.AreaOfInterest, auth.UnverifiedSubject(c.Request)),
+		})
+	})
+
+	putSubscription := func(c *gin.Context) {
+		id, ok := uuidParam(c, "subscriptionid")
+		if !ok {
+			return
+		}
+		var params utm.PutSubscriptionParameters
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+			return
+		}
+		resp, err := s.putSubscription(id, c.Param("version"), auth.UnverifiedSubject(c.Request), params)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+	dss.PUT("/subscriptions/:subscriptionid", putSubscription)
+	dss.PUT("/subscriptions/:subscriptionid/:version", putSubscription)
+
+	dss.DELETE("/subscriptions/:subscriptionid/:version", func(c *gin.Context) {
+		id, ok := uuidParam(c, "subscriptionid")
+		if !ok {
+			return
+		}
+		sub, err := s.deleteSubscription(id, c.Param("version"), auth.UnverifiedSubject(c.Request))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, utm.DeleteSubscriptionResponse{Subscription: *sub})
+	})
+
+	dss.GET("/subscriptions/:subscriptionid", func(c *gin.Context) {
+		id, ok := uuidParam(c, "subscriptionid")
+		if !ok {
+			return
+		}
+		sub, err := s.getSubscription(id, auth.UnverifiedSubject(c.Request))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, utm.GetSubscriptionResponse{Subscription: *sub})
+	})
+
+	dss.POST("/subscriptions/query", func(c *gin.Context) {
+		var params utm.QuerySubscriptionParameters
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+			return
+		}
+		if params.AreaOfInterest == nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: "area_of_interest is required"})
+			return
+		}
+		c.JSON(http.StatusOK, utm.QuerySubscriptionsResponse{
+			Subscriptions: s.querySubscriptions(*params.AreaOfInterest, auth.UnverifiedSubject(c.Request)),
+		})
+	})
+
+	registerRidDss(router.Group("/rid/v2/dss"), newRidStore())
+
+	return &Server{Engine: router, notifier: n}
+}
+
+// registerRidDss serves the ASTM F3411-22a DSS API of <s> on <dss>.
+//
+// see https://github.com/uastech/standards/blob/astm_rid_api_2.1/remoteid/canonical.yaml
+func registerRidDss(dss *gin.RouterGroup, s *ridStore) {
+	putIdentificationServiceArea := func(c *gin.Context) {
+		id, ok := uuidParam(c, "id")
+		if !ok {
+			return
+		}
+		var params rid.CreateIdentificationServiceAreaParameters
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+			return
+		}
+		resp, err := s.putIdentificationServiceArea(id, c.Param("version"), auth.UnverifiedSubject(c.Request), params, time.Now())
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+	dss.PUT("/identification_service_areas/:id", putIdentificationServiceArea)
+	dss.PUT("/identification_service_areas/:id/:version", putIdentificationServiceArea)
+
+	dss.DELETE("/identification_service_areas/:id/:version", func(c *gin.Context) {
+		id, ok := uuidParam(c, "id")
+		if !ok {
+			return
+		}
+		resp, err := s.deleteIdentificationServiceArea(id, c.Param("version"), auth.UnverifiedSubject(c.Request))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	})
+
+	dss.GET("/identification_service_areas/:id", func(c *gin.Context) {
+		id, ok := uuidParam(c, "id")
+		if !ok {
+			return
+		}
+		isa, err := s.getIdentificationServiceArea(id)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rid.GetIdentificationServiceAreaResponse{ServiceArea: *isa})
+	})
+
+	dss.GET("/identification_service_areas", func(c *gin.Context) {
+		area, err := parseArea(c.Query("area"), c.Query("earliest_time"), c.Query("latest_time"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rid.SearchIdentificationServiceAreasResponse{ServiceAreas: s.searchIdentificationServiceAreas(area)})
+	})
+
+	putSubscription := func(c *gin.Context) {
+		id, ok := uuidParam(c, "id")
+		if !ok {
+			return
+		}
+		var params rid.CreateSubscriptionParameters
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+			return
+		}
+		resp, err := s.putSubscription(id, c.Param("version"), auth.UnverifiedSubject(c.Request), params, time.Now())
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+	dss.PUT("/subscriptions/:id", putSubscription)
+	dss.PUT("/subscriptions/:id/:version", putSubscription)
+
+	dss.DELETE("/subscriptions/:id/:version", func(c *gin.Context) {
+		id, ok := uuidParam(c, "id")
+		if !ok {
+			return
+		}
+		sub, err := s.deleteSubscription(id, c.Param("version"), auth.UnverifiedSubject(c.Request))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rid.DeleteSubscriptionResponse{Subscription: *sub})
+	})
+
+	dss.GET("/subscriptions/:id", func(c *gin.Context) {
+		id, ok := uuidParam(c, "id")
+		if !ok {
+			return
+		}
+		sub, err := s.getSubscription(id, auth.UnverifiedSubject(c.Request))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rid.GetSubscriptionResponse{Subscription: *sub})
+	})
+
+	dss.GET("/subscriptions", func(c *gin.Context) {
+		area, err := parseArea(c.Query("area"), "", "")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rid.SearchSubscriptionsResponse{Subscriptions: s.searchSubscriptions(area, auth.UnverifiedSubject(c.Request))})
+	})
+}
+
+func uuidParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: name + " must be a UUID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func respondWithError(c *gin.Context, err error) {
+	var (
+		notFound   *notFoundError
+		exists     *existsError
+		forbidden  *forbiddenError
+		stale      *staleVersionError
+		badRequest *badRequestError
+		conflict   *airspaceConflictError
+	)
+	switch {
+	case errors.As(err, &conflict):
+		c.JSON(http.StatusConflict, utm.AirspaceConflictResponse{
+			Message:                   err.Error(),
+			MissingOperationalIntents: conflict.missingOperationalIntents,
+			MissingConstraints:        conflict.missingConstraints,
+		})
+	case errors.As(err, &notFound):
+		c.JSON(http.StatusNotFound, utm.ErrorResponse{Message: err.Error()})
+	case errors.As(err, &exists), errors.As(err, &stale):
+		c.JSON(http.StatusConflict, utm.ErrorResponse{Message: err.Error()})
+	case errors.As(err, &forbidden):
+		c.JSON(http.StatusForbidden, utm.ErrorResponse{Message: err.Error()})
+	case errors.As(err, &badRequest):
+		c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, utm.ErrorResponse{Message: err.Error()})
+	}
+}
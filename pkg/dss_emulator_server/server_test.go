@@ -0,0 +1,174 @@
+package dss_emulator_server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/transport"
+)
+
+func TestDssEmulator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	seeded := config.OperationalIntentConfig{
+		Name:                "A",
+		OwnerName:           "uss1",
+		OwnerBaseURL:        "http://uss1.example.com",
+		MissionId:           uuid.New(),
+		Duration:            30 * time.Second,
+		WaypointCoordinates: [][2]float64{{46.19128, 6.12335}, {46.19165, 6.12464}, {46.19205, 6.12571}},
+	}
+	departure := time.Now().Add(time.Minute)
+	srv := httptest.NewServer(GetServer([]config.OperationalIntentConfig{seeded}, Options{BaseUrl: "http://localhost", Departure: departure}))
+	defer srv.Close()
+	oirs := srv.URL + "/dss/v1/operational_intent_references/"
+
+	extents := utm.OperationalIntentFromConfigAt(&seeded, departure).Details.Volumes
+	require.NotEmpty(t, extents)
+
+	// the seeded operational intent is in the airspace, with its OVN only
+	// visible to its manager
+	var query utm.QueryOperationalIntentReferenceResponse
+	status := call(t, http.MethodPost, oirs+"query", "uss2", utm.QueryOperationalIntentReferenceParameters{AreaOfInterest: &extents[0]}, &query)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, query.OperationalIntentReferences, 1)
+	assert.Equal(t, seeded.MissionId, query.OperationalIntentReferences[0].ID)
+	assert.Empty(t, query.OperationalIntentReferences[0].Ovn)
+	var got utm.GetOperationalIntentReferenceResponse
+	require.Equal(t, http.StatusOK, call(t, http.MethodGet, oirs+seeded.MissionId.String(), "uss1", nil, &got))
+	assert.Equal(t, utm.SeededOvn(seeded.MissionId), got.OperationalIntentReference.Ovn)
+
+	// a subscription of uss3 to the area
+	subscriptionId := uuid.New()
+	var sub utm.PutSubscriptionResponse
+	require.Equal(t, http.StatusOK, call(t, http.MethodPut, srv.URL+"/dss/v1/subscriptions/"+subscriptionId.String(), "uss3", utm.PutSubscriptionParameters{
+		Extents:                     extents[0],
+		UssBaseUrl:                  "http://uss3.example.com",
+		NotifyForOperationalIntents: true,
+	}, &sub))
+	assert.Equal(t, "1", sub.Subscription.Version)
+	assert.Len(t, sub.OperationalIntentReferences, 1)
+
+	// uss2 has to present the OVN of the seeded operational intent
+	entityId := uuid.New()
+	params := utm.PutOperationalIntentReferenceParameters{
+		Extents:         extents,
+		State:           utm.StateAccepted,
+		UssBaseUrl:      "http://uss2.example.com",
+		NewSubscription: &utm.ImplicitSubscriptionParameters{UssBaseUrl: "http://uss2.example.com"},
+	}
+	var conflict utm.AirspaceConflictResponse
+	require.Equal(t, http.StatusConflict, call(t, http.MethodPut, oirs+entityId.String(), "uss2", params, &conflict))
+	require.Len(t, conflict.MissingOperationalIntents, 1)
+	assert.Equal(t, seeded.MissionId, conflict.MissingOperationalIntents[0].ID)
+
+	params.Key = []utm.EntityOvn{utm.SeededOvn(seeded.MissionId)}
+	var created utm.ChangeOperationalIntentReferenceResponse
+	require.Equal(t, http.StatusCreated, call(t, http.MethodPut, oirs+entityId.String(), "uss2", params, &created))
+	assert.Equal(t, "uss2", created.OperationalIntentReference.Manager)
+	assert.NotEmpty(t, created.OperationalIntentReference.Ovn)
+	assert.NotEqual(t, uuid.Nil, created.OperationalIntentReference.SubscriptionId)
+	// the implicit subscription of the seeded operational intent and the
+	// subscription of uss3 are notified, but not the new one of uss2
+	require.Len(t, created.Subscribers, 2)
+	assert.ElementsMatch(t, []utm.UssBaseURL{"http://uss1.example.com", "http://uss3.example.com"},
+		[]utm.UssBaseURL{created.Subscribers[0].UssBaseUrl, created.Subscribers[1].UssBaseUrl})
+
+	// changes have to be made against the current OVN, by the manager
+	require.Equal(t, http.StatusConflict, call(t, http.MethodPut, oirs+entityId.String(), "uss2", params, nil))
+	require.Equal(t, http.StatusConflict, call(t, http.MethodPut, oirs+entityId.String()+"/stale", "uss2", params, nil))
+	ovn := string(created.OperationalIntentReference.Ovn)
+	require.Equal(t, http.StatusForbidden, call(t, http.MethodPut, oirs+entityId.String()+"/"+ovn, "uss3", params, nil))
+	params.State = utm.StateActivated
+	params.NewSubscription = nil
+	var updated utm.ChangeOperationalIntentReferenceResponse
+	require.Equal(t, http.StatusOK, call(t, http.MethodPut, oirs+entityId.String()+"/"+ovn, "uss2", params, &updated))
+	assert.Equal(t, 2, updated.OperationalIntentReference.Version)
+	assert.Equal(t, created.OperationalIntentReference.SubscriptionId, updated.OperationalIntentReference.SubscriptionId)
+	for _, subscriber := range updated.Subscribers {
+		if subscriber.UssBaseUrl == "http://uss3.example.com" {
+			assert.Equal(t, 2, subscriber.Subscriptions[0].NotificationIndex)
+		}
+	}
+
+	// the implicit subscription goes with the operational intent
+	var deleted utm.ChangeOperationalIntentReferenceResponse
+	require.Equal(t, http.StatusOK, call(t, http.MethodDelete, oirs+entityId.String()+"/"+string(updated.OperationalIntentReference.Ovn), "uss2", nil, &deleted))
+	assert.Len(t, deleted.Subscribers, 2)
+	require.Equal(t, http.StatusNotFound, call(t, http.MethodGet, oirs+entityId.String(), "uss2", nil, nil))
+	require.Equal(t, http.StatusNotFound, call(t, http.MethodGet, srv.URL+"/dss/v1/subscriptions/"+updated.OperationalIntentReference.SubscriptionId.String(), "uss2", nil, nil))
+
+	var subs utm.QuerySubscriptionsResponse
+	require.Equal(t, http.StatusOK, call(t, http.MethodPost, srv.URL+"/dss/v1/subscriptions/query", "uss3", utm.QuerySubscriptionParameters{AreaOfInterest: &extents[0]}, &subs))
+	require.Len(t, subs.Subscriptions, 1)
+	assert.Equal(t, 3, subs.Subscriptions[0].NotificationIndex)
+}
+
+// call makes a request to the DSS emulator as the USS <subject>, decoding the
+// response into <out> if it is not nil.
+func TestNotifierShutdown(t *testing.T) {
+	n := newNotifier(transport.DefaultPolicy())
+	release := make(chan struct{})
+	sent := make(chan struct{})
+	n.notify(func(ctx context.Context) {
+		<-release
+		close(sent)
+	})
+
+	// shutdown waits for the notification in flight
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- n.shutdown(context.Background()) }()
+	select {
+	case <-shutdown:
+		t.Fatal("shutdown returned before the notification was sent")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	assert.NoError(t, <-shutdown)
+	<-sent
+
+	// until its context is done, when the notifications left are cancelled
+	n = newNotifier(transport.DefaultPolicy())
+	cancelled := make(chan struct{})
+	n.notify(func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, n.shutdown(ctx), context.DeadlineExceeded)
+	<-cancelled
+}
+
+func call(t *testing.T, method string, url string, subject string, body any, out any) int {
+	t.Helper()
+	var reqBody bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&reqBody).Encode(body))
+	}
+	req, err := http.NewRequest(method, url, &reqBody)
+	require.NoError(t, err)
+	claims, err := json.Marshal(map[string]string{"sub": subject})
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer e30."+base64.RawURLEncoding.EncodeToString(claims)+".unsigned")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
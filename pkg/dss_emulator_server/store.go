@@ -0,0 +1,602 @@
+package dss_emulator_server
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+)
+
+const (
+	kindOperationalIntent = "operational intent"
+	kindConstraint        = "constraint"
+	kindSubscription      = "subscription"
+)
+
+type operationalIntentEntry struct {
+	ref     utm.OperationalIntentReference
+	extents []utm.Volume4d
+}
+
+type constraintEntry struct {
+	ref     utm.ConstraintReference
+	extents []utm.Volume4d
+}
+
+type subscriptionEntry struct {
+	sub     utm.Subscription
+	manager string
+	version int
+	extents []utm.Volume4d
+}
+
+// store is the airspace of the DSS emulator: the references to operational
+// intents and constraints, and the subscriptions to changes to them.
+type store struct {
+	lock          sync.Mutex
+	intents       map[uuid.UUID]*operationalIntentEntry
+	constraints   map[uuid.UUID]*constraintEntry
+	subscriptions map[uuid.UUID]*subscriptionEntry
+}
+
+func newStore() *store {
+	return &store{
+		intents:       map[uuid.UUID]*operationalIntentEntry{},
+		constraints:   map[uuid.UUID]*constraintEntry{},
+		subscriptions: map[uuid.UUID]*subscriptionEntry{},
+	}
+}
+
+// seed adds an Accepted operational intent for each of <oiCnfs>, departing at
+// <departure>, managed by its owner_name at its owner_baseurl, or at
+// <baseUrl> if it has none. Operational intents with an owner_baseurl get an
+// implicit subscription, so that their owner is notified of the changes
+// around them.
+func (s *store) seed(oiCnfs []config.OperationalIntentConfig, baseUrl utm.UssBaseURL, departure time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, oiCnf := range oiCnfs {
+		details := utm.OperationalIntentFromConfigAt(&oiCnf, departure).Details
+		ref := utm.OperationalIntentReference{
+			ID:              oiCnf.MissionId,
+			Manager:         oiCnf.OwnerName,
+			UssAvailability: utm.UssAvailabilityNormal,
+			Version:         1,
+			State:           utm.StateAccepted,
+			Ovn:             utm.SeededOvn(oiCnf.MissionId),
+			UssBaseUrl:      utm.UssBaseURL(oiCnf.OwnerBaseURL),
+		}
+		if ref.Manager == "" {
+			ref.Manager = "dss-emulator"
+		}
+		if ref.UssBaseUrl == "" {
+			ref.UssBaseUrl = baseUrl
+		} else {
+			ref.SubscriptionId = s.addImplicitSubscription(oiCnf.MissionId, ref.Manager, ref.UssBaseUrl, false, details.Volumes)
+		}
+		ref.TimeStart, ref.TimeEnd = timeBounds(details.Volumes)
+		s.intents[oiCnf.MissionId] = &operationalIntentEntry{ref: ref, extents: details.Volumes}
+	}
+}
+
+// putOperationalIntent creates the reference to the operational intent
+// <entityId> managed by <manager>, or updates it if <ovn> is set.
+func (s *store) putOperationalIntent(entityId uuid.UUID, ovn utm.EntityOvn, manager string, params utm.PutOperationalIntentReferenceParameters) (*utm.ChangeOperationalIntentReferenceResponse, error) {
+	if err := validateExtents(params.Extents); err != nil {
+		return nil, err
+	}
+	switch params.State {
+	case utm.StateAccepted, utm.StateActivated, utm.StateNonconforming, utm.StateContingent:
+	default:
+		return nil, badRequest("state must be one of Accepted, Activated, Nonconforming or Contingent, not %q", params.State)
+	}
+	if params.UssBaseUrl == "" {
+		return nil, badRequest("uss_base_url is required")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	existing := s.intents[entityId]
+	if err := checkChange(kindOperationalIntent, entityId, ovn, manager, existing != nil, func() (string, utm.EntityOvn) {
+		return existing.ref.Manager, existing.ref.Ovn
+	}); err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.ref.State != params.State {
+		if err := utm.ValidateTransition(existing.ref.State, params.State); err != nil {
+			return nil, &badRequestError{err: err}
+		}
+	}
+	if params.State == utm.StateAccepted || params.State == utm.StateActivated {
+		if err := s.checkKey(entityId, params.Extents, params.Key); err != nil {
+			return nil, err
+		}
+	}
+
+	subscriptionId, err := s.operationalIntentSubscription(entityId, manager, existing, params)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := utm.OperationalIntentReference{
+		ID:              entityId,
+		Manager:         manager,
+		UssAvailability: utm.UssAvailabilityNormal,
+		Version:         1,
+		State:           params.State,
+		Ovn:             newOvn(),
+		UssBaseUrl:      params.UssBaseUrl,
+		SubscriptionId:  subscriptionId,
+	}
+	ref.TimeStart, ref.TimeEnd = timeBounds(params.Extents)
+	affected := params.Extents
+	if existing != nil {
+		ref.Version = existing.ref.Version + 1
+		affected = append(slices.Clone(existing.extents), params.Extents...)
+	}
+	s.intents[entityId] = &operationalIntentEntry{ref: ref, extents: params.Extents}
+
+	return &utm.ChangeOperationalIntentReferenceResponse{
+		Subscribers:                s.subscribers(affected, subscriptionId, func(sub utm.Subscription) bool { return sub.NotifyForOperationalIntents }),
+		OperationalIntentReference: ref,
+	}, nil
+}
+
+// operationalIntentSubscription is the subscription that the operational
+// intent <entityId> is kept aware of its airspace by: the one that <params>
+// names, a new implicit one, or the one it already has.
+func (s *store) operationalIntentSubscription(entityId uuid.UUID, manager string, existing *operationalIntentEntry, params utm.PutOperationalIntentReferenceParameters) (uuid.UUID, error) {
+	previous := uuid.Nil
+	if existing != nil {
+		previous = existing.ref.SubscriptionId
+	}
+
+	var subscriptionId uuid.UUID
+	switch {
+	case params.SubscriptionId != nil:
+		entry, ok := s.subscriptions[*params.SubscriptionId]
+		if !ok {
+			return uuid.Nil, badRequest("subscription %s does not exist", *params.SubscriptionId)
+		}
+		if entry.manager != manager {
+			return uuid.Nil, &forbiddenError{kind: kindSubscription, id: entry.sub.Id, manager: entry.manager}
+		}
+		subscriptionId = entry.sub.Id
+		if !slices.Contains(entry.sub.DependentOperationalIntents, entityId) {
+			entry.sub.DependentOperationalIntents = append(entry.sub.DependentOperationalIntents, entityId)
+		}
+	case params.NewSubscription != nil:
+		subscriptionId = s.addImplicitSubscription(entityId, manager, params.NewSubscription.UssBaseUrl, params.NewSubscription.NotifyForConstraints, params.Extents)
+	case previous != uuid.Nil:
+		subscriptionId = previous
+		if entry := s.subscriptions[previous]; entry != nil && entry.sub.ImplicitSubscription {
+			entry.extents = params.Extents
+			entry.sub.TimeStart, entry.sub.TimeEnd = timeBoundPointers(params.Extents)
+		}
+	case params.State == utm.StateAccepted || params.State == utm.StateActivated:
+		return uuid.Nil, badRequest("subscription_id or new_subscription is required for an %s operational intent", params.State)
+	}
+
+	if previous != uuid.Nil && previous != subscriptionId {
+		s.removeDependent(previous, entityId)
+	}
+	return subscriptionId, nil
+}
+
+// deleteOperationalIntent removes the reference to the operational intent
+// <entityId>, which must be at <ovn> and managed by <manager>.
+func (s *store) deleteOperationalIntent(entityId uuid.UUID, ovn utm.EntityOvn, manager string) (*utm.ChangeOperationalIntentReferenceResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	existing := s.intents[entityId]
+	if err := checkChange(kindOperationalIntent, entityId, ovn, manager, existing != nil, func() (string, utm.EntityOvn) {
+		return existing.ref.Manager, existing.ref.Ovn
+	}); err != nil {
+		return nil, err
+	}
+
+	delete(s.intents, entityId)
+	if existing.ref.SubscriptionId != uuid.Nil {
+		s.removeDependent(existing.ref.SubscriptionId, entityId)
+	}
+	return &utm.ChangeOperationalIntentReferenceResponse{
+		Subscribers:                s.subscribers(existing.extents, existing.ref.SubscriptionId, func(sub utm.Subscription) bool { return sub.NotifyForOperationalIntents }),
+		OperationalIntentReference: existing.ref,
+	}, nil
+}
+
+// getOperationalIntent is the reference to the operational intent <entityId>,
+// with its OVN only if <requester> manages it.
+func (s *store) getOperationalIntent(entityId uuid.UUID, requester string) (*utm.OperationalIntentReference, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.intents[entityId]
+	if !ok {
+		return nil, &notFoundError{kind: kindOperationalIntent, id: entityId}
+	}
+	ref := visibleOperationalIntent(entry.ref, requester)
+	return &ref, nil
+}
+
+// queryOperationalIntents is the references to the operational intents that
+// intersect <area>, with their OVNs only if <requester> manages them.
+func (s *store) queryOperationalIntents(area utm.Volume4d, requester string) []utm.OperationalIntentReference {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	refs := []utm.OperationalIntentReference{}
+	for _, entry := range s.intents {
+		if utm.AnyIntersect(entry.extents, []utm.Volume4d{area}) {
+			refs = append(refs, visibleOperationalIntent(entry.ref, requester))
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].TimeStart.Value.Before(refs[j].TimeStart.Value) })
+	return refs
+}
+
+// putConstraint creates the reference to the constraint <entityId> managed by
+// <manager>, or updates it if <ovn> is set.
+func (s *store) putConstraint(entityId uuid.UUID, ovn utm.EntityOvn, manager string, params utm.PutConstraintReferenceParameters) (*utm.ChangeConstraintReferenceResponse, error) {
+	if err := validateExtents(params.Extents); err != nil {
+		return nil, err
+	}
+	if params.UssBaseUrl == "" {
+		return nil, badRequest("uss_base_url is required")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	existing := s.constraints[entityId]
+	if err := checkChange(kindConstraint, entityId, ovn, manager, existing != nil, func() (string, utm.EntityOvn) {
+		return existing.ref.Manager, existing.ref.Ovn
+	}); err != nil {
+		return nil, err
+	}
+
+	ref := utm.ConstraintReference{
+		ID:              entityId,
+		Manager:         manager,
+		UssAvailability: utm.UssAvailabilityNormal,
+		Version:         1,
+		Ovn:             newOvn(),
+		UssBaseUrl:      params.UssBaseUrl,
+	}
+	ref.TimeStart, ref.TimeEnd = timeBounds(params.Extents)
+	affected := params.Extents
+	if existing != nil {
+		ref.Version = existing.ref.Version + 1
+		affected = append(slices.Clone(existing.extents), params.Extents...)
+	}
+	s.constraints[entityId] = &constraintEntry{ref: ref, extents: params.Extents}
+
+	return &utm.ChangeConstraintReferenceResponse{
+		Subscribers:         s.subscribers(affected, uuid.Nil, func(sub utm.Subscription) bool { return sub.NotifyForConstraints }),
+		ConstraintReference: ref,
+	}, nil
+}
+
+func (s *store) deleteConstraint(entityId uuid.UUID, ovn utm.EntityOvn, manager string) (*utm.ChangeConstraintReferenceResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	existing := s.constraints[entityId]
+	if err := checkChange(kindConstraint, entityId, ovn, manager, existing != nil, func() (string, utm.EntityOvn) {
+		return existing.ref.Manager, existing.ref.Ovn
+	}); err != nil {
+		return nil, err
+	}
+
+	delete(s.constraints, entityId)
+	return &utm.ChangeConstraintReferenceResponse{
+		Subscribers:         s.subscribers(existing.extents, uuid.Nil, func(sub utm.Subscription) bool { return sub.NotifyForConstraints }),
+		ConstraintReference: existing.ref,
+	}, nil
+}
+
+func (s *store) getConstraint(entityId uuid.UUID, requester string) (*utm.ConstraintReference, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.constraints[entityId]
+	if !ok {
+		return nil, &notFoundError{kind: kindConstraint, id: entityId}
+	}
+	ref := visibleConstraint(entry.ref, requester)
+	return &ref, nil
+}
+
+func (s *store) queryConstraints(area utm.Volume4d, requester string) []utm.ConstraintReference {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	refs := []utm.ConstraintReference{}
+	for _, entry := range s.constraints {
+		if utm.AnyIntersect(entry.extents, []utm.Volume4d{area}) {
+			refs = append(refs, visibleConstraint(entry.ref, requester))
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].TimeStart.Value.Before(refs[j].TimeStart.Value) })
+	return refs
+}
+
+// putSubscription creates the subscription <id> of <manager>, or updates it
+// if <version> is set.
+func (s *store) putSubscription(id uuid.UUID, version string, manager string, params utm.PutSubscriptionParameters) (*utm.PutSubscriptionResponse, error) {
+	if err := validateExtents([]utm.Volume4d{params.Extents}); err != nil {
+		return nil, err
+	}
+	if params.UssBaseUrl == "" {
+		return nil, badRequest("uss_base_url is required")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, err := s.subscriptionToChange(id, version, manager)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		entry = &subscriptionEntry{
+			sub:     utm.Subscription{Id: id, DependentOperationalIntents: []uuid.UUID{}},
+			manager: manager,
+		}
+		s.subscriptions[id] = entry
+	}
+	entry.version++
+	entry.extents = []utm.Volume4d{params.Extents}
+	entry.sub.Version = strconv.Itoa(entry.version)
+	entry.sub.UssBaseUrl = params.UssBaseUrl
+	entry.sub.NotifyForOperationalIntents = params.NotifyForOperationalIntents
+	entry.sub.NotifyForConstraints = params.NotifyForConstraints
+	entry.sub.TimeStart, entry.sub.TimeEnd = timeBoundPointers(entry.extents)
+
+	resp := &utm.PutSubscriptionResponse{Subscription: entry.sub}
+	if params.NotifyForOperationalIntents {
+		for _, oi := range s.intents {
+			if utm.AnyIntersect(oi.extents, entry.extents) {
+				resp.OperationalIntentReferences = append(resp.OperationalIntentReferences, visibleOperationalIntent(oi.ref, manager))
+			}
+		}
+	}
+	if params.NotifyForConstraints {
+		for _, c := range s.constraints {
+			if utm.AnyIntersect(c.extents, entry.extents) {
+				resp.ConstraintReferences = append(resp.ConstraintReferences, visibleConstraint(c.ref, manager))
+			}
+		}
+	}
+	return resp, nil
+}
+
+// subscriptionToChange is the subscription <id> for <manager> to change at
+// <version>, or nil if it is to be created.
+func (s *store) subscriptionToChange(id uuid.UUID, version string, manager string) (*subscriptionEntry, error) {
+	entry, ok := s.subscriptions[id]
+	switch {
+	case version == "" && ok:
+		return nil, &existsError{kind: kindSubscription, id: id}
+	case version == "":
+		return nil, nil
+	case !ok:
+		return nil, &notFoundError{kind: kindSubscription, id: id}
+	case entry.manager != manager:
+		return nil, &forbiddenError{kind: kindSubscription, id: id, manager: entry.manager}
+	case entry.sub.Version != version:
+		return nil, &staleVersionError{kind: kindSubscription, id: id, version: version}
+	}
+	return entry, nil
+}
+
+func (s *store) deleteSubscription(id uuid.UUID, version string, manager string) (*utm.Subscription, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, err := s.subscriptionToChange(id, version, manager)
+	if err != nil {
+		return nil, err
+	}
+	if len(entry.sub.DependentOperationalIntents) > 0 {
+		return nil, badRequest("subscription %s is depended on by %d operational intents", id, len(entry.sub.DependentOperationalIntents))
+	}
+	delete(s.subscriptions, id)
+	return &entry.sub, nil
+}
+
+func (s *store) getSubscription(id uuid.UUID, requester string) (*utm.Subscription, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.subscriptions[id]
+	if !ok {
+		return nil, &notFoundError{kind: kindSubscription, id: id}
+	}
+	if entry.manager != requester {
+		return nil, &forbiddenError{kind: kindSubscription, id: id, manager: entry.manager}
+	}
+	sub := entry.sub
+	return &sub, nil
+}
+
+// querySubscriptions is the subscriptions of <requester> that intersect
+// <area>.
+func (s *store) querySubscriptions(area utm.Volume4d, requester string) []utm.Subscription {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	subs := []utm.Subscription{}
+	for _, entry := range s.subscriptions {
+		if entry.manager == requester && utm.AnyIntersect(entry.extents, []utm.Volume4d{area}) {
+			subs = append(subs, entry.sub)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Id.String() < subs[j].Id.String() })
+	return subs
+}
+
+func (s *store) addImplicitSubscription(entityId uuid.UUID, manager string, ussBaseUrl utm.UssBaseURL, notifyForConstraints bool, extents []utm.Volume4d) uuid.UUID {
+	sub := utm.Subscription{
+		Id:                          uuid.New(),
+		Version:                     "1",
+		UssBaseUrl:                  ussBaseUrl,
+		NotifyForOperationalIntents: true,
+		NotifyForConstraints:        notifyForConstraints,
+		ImplicitSubscription:        true,
+		DependentOperationalIntents: []uuid.UUID{entityId},
+	}
+	sub.TimeStart, sub.TimeEnd = timeBoundPointers(extents)
+	s.subscriptions[sub.Id] = &subscriptionEntry{sub: sub, manager: manager, version: 1, extents: extents}
+	return sub.Id
+}
+
+// removeDependent removes the operational intent <entityId> from the
+// dependents of the subscription <id>, and removes the subscription if it was
+// implicit and nothing depends on it anymore.
+func (s *store) removeDependent(id uuid.UUID, entityId uuid.UUID) {
+	entry, ok := s.subscriptions[id]
+	if !ok {
+		return
+	}
+	entry.sub.DependentOperationalIntents = slices.DeleteFunc(entry.sub.DependentOperationalIntents, func(dependent uuid.UUID) bool {
+		return dependent == entityId
+	})
+	if entry.sub.ImplicitSubscription && len(entry.sub.DependentOperationalIntents) == 0 {
+		delete(s.subscriptions, id)
+	}
+}
+
+// checkKey returns an airspaceConflictError if <key> is missing the OVN of an
+// operational intent, other than <entityId>, or a constraint that <extents>
+// intersect.
+func (s *store) checkKey(entityId uuid.UUID, extents []utm.Volume4d, key []utm.EntityOvn) error {
+	conflict := &airspaceConflictError{}
+	for id, entry := range s.intents {
+		if id != entityId && !slices.Contains(key, entry.ref.Ovn) && utm.AnyIntersect(entry.extents, extents) {
+			conflict.missingOperationalIntents = append(conflict.missingOperationalIntents, visibleOperationalIntent(entry.ref, ""))
+		}
+	}
+	for _, entry := range s.constraints {
+		if !slices.Contains(key, entry.ref.Ovn) && utm.AnyIntersect(entry.extents, extents) {
+			conflict.missingConstraints = append(conflict.missingConstraints, visibleConstraint(entry.ref, ""))
+		}
+	}
+	if len(conflict.missingOperationalIntents) > 0 || len(conflict.missingConstraints) > 0 {
+		return conflict
+	}
+	return nil
+}
+
+// subscribers is the USSs to notify of a change to <extents>, with each of
+// their relevant subscriptions other than <exclude>, the subscription of the
+// entity that changed. The notification index of each is incremented.
+func (s *store) subscribers(extents []utm.Volume4d, exclude uuid.UUID, relevant func(utm.Subscription) bool) []utm.SubscriberToNotify {
+	ids := make([]uuid.UUID, 0, len(s.subscriptions))
+	for id := range s.subscriptions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+
+	subscribers := []utm.SubscriberToNotify{}
+	for _, id := range ids {
+		entry := s.subscriptions[id]
+		if id == exclude || !relevant(entry.sub) || !utm.AnyIntersect(entry.extents, extents) {
+			continue
+		}
+		entry.sub.NotificationIndex++
+		state := utm.SubscriptionState{SubscriptionId: id, NotificationIndex: entry.sub.NotificationIndex}
+
+		i := slices.IndexFunc(subscribers, func(sub utm.SubscriberToNotify) bool { return sub.UssBaseUrl == entry.sub.UssBaseUrl })
+		if i < 0 {
+			subscribers = append(subscribers, utm.SubscriberToNotify{UssBaseUrl: entry.sub.UssBaseUrl})
+			i = len(subscribers) - 1
+		}
+		subscribers[i].Subscriptions = append(subscribers[i].Subscriptions, state)
+	}
+	return subscribers
+}
+
+// checkChange checks that a change by <manager> to the entity <id> at <ovn>
+// is allowed: it creates the entity if <ovn> is empty, otherwise it updates an
+// existing entity that <manager> manages and is currently at <ovn>.
+func checkChange(kind string, id uuid.UUID, ovn utm.EntityOvn, manager string, exists bool, current func() (string, utm.EntityOvn)) error {
+	switch {
+	case ovn == "" && exists:
+		return &existsError{kind: kind, id: id}
+	case ovn == "":
+		return nil
+	case !exists:
+		return &notFoundError{kind: kind, id: id}
+	}
+	currentManager, currentOvn := current()
+	if currentManager != manager {
+		return &forbiddenError{kind: kind, id: id, manager: currentManager}
+	}
+	if currentOvn != ovn {
+		return &staleVersionError{kind: kind, id: id, version: string(ovn)}
+	}
+	return nil
+}
+
+func validateExtents(extents []utm.Volume4d) error {
+	if len(extents) == 0 {
+		return badRequest("extents are required")
+	}
+	for i, vol := range extents {
+		if len(vol.Volume.Outline()) == 0 {
+			return badRequest("extent %d has no outline_polygon or outline_circle", i)
+		}
+		if !vol.TimeStart.IsZero() && !vol.TimeEnd.IsZero() && vol.TimeEnd.Before(vol.TimeStart) {
+			return badRequest("extent %d ends before it starts", i)
+		}
+	}
+	return nil
+}
+
+// visibleOperationalIntent is <ref> as <requester> may see it, without its
+// OVN unless <requester> manages it. Other USSs get the OVN from the USS that
+// manages the operational intent.
+func visibleOperationalIntent(ref utm.OperationalIntentReference, requester string) utm.OperationalIntentReference {
+	if ref.Manager != requester {
+		ref.Ovn = ""
+	}
+	return ref
+}
+
+func visibleConstraint(ref utm.ConstraintReference, requester string) utm.ConstraintReference {
+	if ref.Manager != requester {
+		ref.Ovn = ""
+	}
+	return ref
+}
+
+func timeBounds(extents []utm.Volume4d) (utm.Time, utm.Time) {
+	var start, end time.Time
+	for _, vol := range extents {
+		if start.IsZero() || vol.TimeStart.Before(start) {
+			start = vol.TimeStart
+		}
+		if vol.TimeEnd.After(end) {
+			end = vol.TimeEnd
+		}
+	}
+	return utm.NewTime(start), utm.NewTime(end)
+}
+
+func timeBoundPointers(extents []utm.Volume4d) (*utm.Time, *utm.Time) {
+	start, end := timeBounds(extents)
+	return &start, &end
+}
+
+func newOvn() utm.EntityOvn {
+	return utm.EntityOvn(uuid.NewString())
+}
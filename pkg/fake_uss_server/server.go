@@ -0,0 +1,215 @@
+// Package fake_uss_server is a peer USS that serves the ASTM F3548-21 USS API
+// for the operational intents in the config, as if they were managed by
+// another USS, so that the interactions of manna-utm with other USSs can be
+// tested locally.
+package fake_uss_server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+)
+
+// GetServer serves the operational intents of <oiCnfs>, departing at
+// <departure>, from the fake USS at <baseUrl>.
+func GetServer(oiCnfs []config.OperationalIntentConfig, baseUrl string, departure time.Time) *gin.Engine {
+	router := gin.Default()
+	s := newStore(oiCnfs, baseUrl, departure)
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "manna-utm-cli fake USS server is healthy.",
+		})
+	})
+
+	// see https://github.com/astm-utm/Protocol/blob/v1.0.0/utm.yaml
+	uss := router.Group("/uss/v1", recordExchanges(s))
+
+	uss.GET("/operational_intents/:entityid", func(c *gin.Context) {
+		entityId, ok := entityIdParam(c)
+		if !ok {
+			return
+		}
+		oi, err := s.operationalIntent(entityId, time.Now())
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, utm.GetOperationalIntentDetailsResponse{OperationalIntent: *oi})
+	})
+
+	uss.GET("/operational_intents/:entityid/telemetry", func(c *gin.Context) {
+		entityId, ok := entityIdParam(c)
+		if !ok {
+			return
+		}
+		telemetry, err := s.telemetry(entityId, time.Now())
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, telemetry)
+	})
+
+	uss.POST("/operational_intents", func(c *gin.Context) {
+		var params utm.PutOperationalIntentDetailsParameters
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+			return
+		}
+		if params.OperationalIntentId == uuid.Nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: "operational_intent_id is required"})
+			return
+		}
+		if params.OperationalIntent != nil && params.OperationalIntent.Reference.ID != params.OperationalIntentId {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: "operational_intent.reference.id does not match operational_intent_id"})
+			return
+		}
+
+		s.notify(Notification{ReceivedAt: time.Now(), OperationalIntent: &params})
+		if params.OperationalIntent == nil {
+			log.Infof("notified that operational intent %s was removed", params.OperationalIntentId)
+		} else {
+			log.Infof("notified of operational intent %s (version=%d, state=%s) with %d volumes", params.OperationalIntentId,
+				params.OperationalIntent.Reference.Version, params.OperationalIntent.Reference.State, len(params.OperationalIntent.Details.Volumes))
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// the fake USS manages no constraints, but records the notifications of
+	// the constraints of other USSs
+	uss.GET("/constraints/:entityid", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, utm.ErrorResponse{Message: "the fake USS does not manage any constraints"})
+	})
+
+	uss.POST("/constraints", func(c *gin.Context) {
+		var params utm.PutConstraintDetailsParameters
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+			return
+		}
+		if params.ConstraintId == uuid.Nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: "constraint_id is required"})
+			return
+		}
+
+		s.notify(Notification{ReceivedAt: time.Now(), Constraint: &params})
+		log.Infof("notified of constraint %s", params.ConstraintId)
+		c.Status(http.StatusNoContent)
+	})
+
+	uss.POST("/reports", func(c *gin.Context) {
+		var report utm.ErrorReport
+		if err := c.ShouldBindJSON(&report); err != nil {
+			c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+			return
+		}
+
+		report = s.report(report)
+		log.Infof("received report %s of %s %s", report.ReportId, report.Exchange.Method, report.Exchange.Url)
+		c.JSON(http.StatusCreated, report)
+	})
+
+	router.GET("/uss/v1/logs", func(c *gin.Context) {
+		c.JSON(http.StatusOK, utm.GetLogsResponse{Logs: s.getExchanges()})
+	})
+
+	// the notifications and reports received, for checking what manna-utm sent
+	router.GET("/fake/v1/notifications", func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.getNotifications())
+	})
+	router.GET("/fake/v1/reports", func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.getReports())
+	})
+
+	return router
+}
+
+func entityIdParam(c *gin.Context) (uuid.UUID, bool) {
+	entityId, err := uuid.Parse(c.Param("entityid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utm.ErrorResponse{Message: "entityid must be a UUID"})
+		return uuid.Nil, false
+	}
+	return entityId, true
+}
+
+func respondWithError(c *gin.Context, err error) {
+	var notFound *notFoundError
+	switch {
+	case errors.As(err, &notFound):
+		c.JSON(http.StatusNotFound, utm.ErrorResponse{Message: err.Error()})
+	case errors.Is(err, errNoTelemetry):
+		c.JSON(http.StatusPreconditionFailed, utm.ErrorResponse{Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, utm.ErrorResponse{Message: err.Error()})
+	}
+}
+
+// bodyRecorder keeps a copy of the body of a response.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// recordExchanges records each request to the USS API and its response in
+// <s>, for /uss/v1/logs.
+func recordExchanges(s *store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		exchange := utm.ExchangeRecord{
+			Url:          c.Request.URL.String(),
+			Method:       c.Request.Method,
+			RecorderRole: utm.RecorderRoleServer,
+			RequestTime:  utm.NewTime(time.Now().UTC()),
+		}
+		for name, values := range c.Request.Header {
+			if name == "Authorization" {
+				continue
+			}
+			for _, v := range values {
+				exchange.Headers = append(exchange.Headers, name+": "+v)
+			}
+		}
+		sort.Strings(exchange.Headers)
+		if c.Request.Body != nil {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, utm.ErrorResponse{Message: err.Error()})
+				return
+			}
+			exchange.RequestBody = string(body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		w := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		responseTime := utm.NewTime(time.Now().UTC())
+		exchange.ResponseTime = &responseTime
+		exchange.ResponseCode = w.Status()
+		exchange.ResponseBody = w.body.String()
+		if w.Status() >= 400 {
+			var errResp utm.ErrorResponse
+			if json.Unmarshal(w.body.Bytes(), &errResp) == nil {
+				exchange.Problem = errResp.Message
+			}
+		}
+		s.record(exchange)
+	}
+}
@@ -0,0 +1,85 @@
+package fake_uss_server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/transport"
+	"manna.aero/manna.utm.cli/pkg/uss_client"
+)
+
+func TestFakeUss(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	oiCnf := config.OperationalIntentConfig{
+		Name:                "A",
+		OwnerName:           "uss.example.com",
+		OwnerBaseURL:        "http://uss.example.com",
+		Priority:            1,
+		MissionId:           uuid.New(),
+		Duration:            30 * time.Second,
+		WaypointCoordinates: [][2]float64{{46.19128, 6.12335}, {46.19165, 6.12464}, {46.19205, 6.12571}},
+	}
+	later := config.OperationalIntentConfig{Name: "B", MissionId: uuid.New(), Duration: 30 * time.Second, WaypointCoordinates: oiCnf.WaypointCoordinates}
+
+	srv := httptest.NewServer(GetServer([]config.OperationalIntentConfig{oiCnf}, "http://localhost", time.Now().Add(-5*time.Second)))
+	defer srv.Close()
+	client, err := uss_client.NewUssClient(srv.URL, nil, transport.DefaultPolicy())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	oi, err := client.GetOperationalIntentDetails(ctx, oiCnf.MissionId.String())
+	require.NoError(t, err)
+	assert.Equal(t, utm.StateActivated, oi.Reference.State)
+	assert.Equal(t, "uss.example.com", oi.Reference.Manager)
+	assert.Equal(t, utm.UssBaseURL("http://uss.example.com"), oi.Reference.UssBaseUrl)
+	assert.Len(t, oi.Details.Volumes, 3)
+
+	telemetry, err := client.GetOperationalIntentTelemetry(ctx, oiCnf.MissionId.String())
+	require.NoError(t, err)
+	require.NotNil(t, telemetry.Telemetry)
+	require.NotNil(t, telemetry.NextTelemetryOpportunity)
+	assert.InDelta(t, 46.19, telemetry.Telemetry.Position.Latitude, 0.01)
+	assert.InDelta(t, 6.12, telemetry.Telemetry.Position.Longitude, 0.01)
+	assert.True(t, telemetry.NextTelemetryOpportunity.Value.After(telemetry.Telemetry.TimeMeasured.Value))
+
+	_, err = client.GetOperationalIntentDetails(ctx, later.MissionId.String())
+	var clientErr *transport.ClientError
+	require.True(t, errors.As(err, &clientErr))
+	assert.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+
+	require.NoError(t, client.NotifyOperationalIntentDetailsChanged(ctx, utm.PutOperationalIntentDetailsParameters{
+		OperationalIntentId: later.MissionId,
+		OperationalIntent:   utm.OperationalIntentFromConfig(&later),
+		Subscriptions:       []utm.SubscriptionState{{SubscriptionId: uuid.New(), NotificationIndex: 1}},
+	}))
+	err = client.NotifyOperationalIntentDetailsChanged(ctx, utm.PutOperationalIntentDetailsParameters{OperationalIntentId: oiCnf.MissionId, OperationalIntent: utm.OperationalIntentFromConfig(&later)})
+	require.True(t, errors.As(err, &clientErr))
+	assert.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+
+	resp, err := http.Get(srv.URL + "/fake/v1/notifications")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var notifications []Notification
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&notifications))
+	require.Len(t, notifications, 1)
+	assert.Equal(t, later.MissionId, notifications[0].OperationalIntent.OperationalIntentId)
+	assert.Len(t, notifications[0].OperationalIntent.OperationalIntent.Details.Volumes, 3)
+
+	logs, err := client.GetLogs(ctx)
+	require.NoError(t, err)
+	require.Len(t, logs.Logs, 5)
+	assert.Equal(t, http.MethodPost, logs.Logs[3].Method)
+	assert.Equal(t, http.StatusNoContent, logs.Logs[3].ResponseCode)
+	assert.Equal(t, "operational_intent.reference.id does not match operational_intent_id", logs.Logs[4].Problem)
+}
@@ -0,0 +1,170 @@
+package fake_uss_server
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/model/uspace/virtual_uspace"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+)
+
+// maxExchanges is the number of exchanges kept for /uss/v1/logs, older ones
+// are dropped.
+const maxExchanges = 1000
+
+// Notification is a notification of a change to an operational intent or a
+// constraint, received by the fake USS.
+type Notification struct {
+	ReceivedAt        time.Time                                  `json:"received_at"`
+	OperationalIntent *utm.PutOperationalIntentDetailsParameters `json:"operational_intent,omitempty"`
+	Constraint        *utm.PutConstraintDetailsParameters        `json:"constraint,omitempty"`
+}
+
+// publishedIntent is an operational intent published by the fake USS, along
+// with the telemetry of its flight.
+type publishedIntent struct {
+	intent    utm.OperationalIntent
+	telemetry []uspace.Telemetry
+}
+
+type store struct {
+	lock          sync.RWMutex
+	intents       map[uuid.UUID]*publishedIntent
+	notifications []Notification
+	reports       []utm.ErrorReport
+	exchanges     []utm.ExchangeRecord
+}
+
+// newStore publishes the operational intents of <oiCnfs>, departing at
+// <departure>. Operational intents without an owner_baseurl are published as
+// managed by the USS at <baseUrl>.
+func newStore(oiCnfs []config.OperationalIntentConfig, baseUrl string, departure time.Time) *store {
+	s := &store{intents: map[uuid.UUID]*publishedIntent{}}
+	for _, oiCnf := range oiCnfs {
+		oi := utm.OperationalIntentFromConfigAt(&oiCnf, departure)
+		oi.Reference.Manager = oiCnf.OwnerName
+		if oi.Reference.Manager == "" {
+			oi.Reference.Manager = "fake-uss"
+		}
+		oi.Reference.UssBaseUrl = utm.UssBaseURL(oiCnf.OwnerBaseURL)
+		if oi.Reference.UssBaseUrl == "" {
+			oi.Reference.UssBaseUrl = utm.UssBaseURL(baseUrl)
+		}
+		oi.Reference.UssAvailability = utm.UssAvailabilityNormal
+		oi.Reference.Version = 1
+		oi.Reference.Ovn = utm.SeededOvn(oiCnf.MissionId)
+		oi.Reference.SubscriptionId = uuid.New()
+
+		s.intents[oiCnf.MissionId] = &publishedIntent{
+			intent:    *oi,
+			telemetry: virtual_uspace.NewOperationalIntentManagerAt(&oiCnf, virtual_uspace.DefaultDetailFactor, departure).Telemetry(),
+		}
+	}
+	return s
+}
+
+type notFoundError struct {
+	entityId uuid.UUID
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("no operational intent is published with id %s", e.entityId)
+}
+
+// errNoTelemetry is returned for the telemetry of an operational intent that
+// has not departed yet.
+var errNoTelemetry = errors.New("the operational intent has not departed yet, so there is no telemetry for it")
+
+// operationalIntent is the published operational intent <entityId> as it is
+// at <now>: Accepted until it departs, then Activated.
+func (s *store) operationalIntent(entityId uuid.UUID, now time.Time) (*utm.OperationalIntent, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	p, ok := s.intents[entityId]
+	if !ok {
+		return nil, &notFoundError{entityId: entityId}
+	}
+	oi := p.intent
+	oi.Reference.State = utm.StateAccepted
+	if !now.Before(oi.Reference.TimeStart.Value) {
+		oi.Reference.State = utm.StateActivated
+	}
+	return &oi, nil
+}
+
+// telemetry is the last telemetry of the operational intent <entityId>
+// measured at or before <now>, with the time the next one is measured as its
+// next telemetry opportunity.
+func (s *store) telemetry(entityId uuid.UUID, now time.Time) (*utm.OperationalIntentTelemetry, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	p, ok := s.intents[entityId]
+	if !ok {
+		return nil, &notFoundError{entityId: entityId}
+	}
+	// the index of the first telemetry measured after now
+	next := sort.Search(len(p.telemetry), func(i int) bool {
+		return p.telemetry[i].TimeMeasured > now.UnixMilli()
+	})
+	if next == 0 {
+		return nil, errNoTelemetry
+	}
+
+	t := utm.TelemetryFromUspace(p.telemetry[next-1])
+	oit := &utm.OperationalIntentTelemetry{OperationalIntentId: entityId, Telemetry: &t}
+	if next < len(p.telemetry) {
+		nextTime := utm.NewTime(time.UnixMilli(p.telemetry[next].TimeMeasured).UTC())
+		oit.NextTelemetryOpportunity = &nextTime
+	}
+	return oit, nil
+}
+
+func (s *store) notify(n Notification) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.notifications = append(s.notifications, n)
+}
+
+func (s *store) getNotifications() []Notification {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return append([]Notification{}, s.notifications...)
+}
+
+// report stores <report> under a new report id.
+func (s *store) report(report utm.ErrorReport) utm.ErrorReport {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	report.ReportId = uuid.NewString()
+	s.reports = append(s.reports, report)
+	return report
+}
+
+func (s *store) getReports() []utm.ErrorReport {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return append([]utm.ErrorReport{}, s.reports...)
+}
+
+func (s *store) record(exchange utm.ExchangeRecord) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.exchanges) == maxExchanges {
+		s.exchanges = s.exchanges[1:]
+	}
+	s.exchanges = append(s.exchanges, exchange)
+}
+
+func (s *store) getExchanges() []utm.ExchangeRecord {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return append([]utm.ExchangeRecord{}, s.exchanges...)
+}
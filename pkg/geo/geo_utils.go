@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
 )
 
 // CreateStd4dVolContents computes and returns the start time, end time
@@ -50,6 +51,72 @@ func PolygonFromCoords(coords [][2]float64) orb.Polygon {
 	return orb.Polygon{ring}
 }
 
+// SwapPolygonAxes returns a copy of <p> with the axes of every point swapped,
+// converting between [lat, lng] and GeoJSON's [lng, lat] ordering.
+func SwapPolygonAxes(p orb.Polygon) orb.Polygon {
+	swapped := make(orb.Polygon, 0, len(p))
+	for _, r := range p {
+		ring := make(orb.Ring, 0, len(r))
+		for _, point := range r {
+			ring = append(ring, orb.Point{point[1], point[0]})
+		}
+		swapped = append(swapped, ring)
+	}
+	return swapped
+}
+
+// PolygonsIntersect reports whether the outer rings of <a> and <b> share any
+// area or boundary. Holes are ignored.
+func PolygonsIntersect(a orb.Polygon, b orb.Polygon) bool {
+	if len(a) == 0 || len(b) == 0 || len(a[0]) == 0 || len(b[0]) == 0 {
+		return false
+	}
+	ringA, ringB := a[0], b[0]
+	if !ringA.Bound().Intersects(ringB.Bound()) {
+		return false
+	}
+
+	// one ring lies entirely within the other
+	if planar.RingContains(ringB, ringA[0]) || planar.RingContains(ringA, ringB[0]) {
+		return true
+	}
+
+	for i := 0; i < len(ringA)-1; i++ {
+		for j := 0; j < len(ringB)-1; j++ {
+			if segmentsIntersect(ringA[i], ringA[i+1], ringB[j], ringB[j+1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func segmentsIntersect(p1, p2, q1, q2 orb.Point) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+
+	// collinear points lying on the other segment
+	return (d1 == 0 && onSegment(q1, q2, p1)) ||
+		(d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) ||
+		(d4 == 0 && onSegment(p1, p2, q2))
+}
+
+func orientation(a, b, c orb.Point) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+func onSegment(a, b, p orb.Point) bool {
+	return math.Min(a[0], b[0]) <= p[0] && p[0] <= math.Max(a[0], b[0]) &&
+		math.Min(a[1], b[1]) <= p[1] && p[1] <= math.Max(a[1], b[1])
+}
+
 type pointSeries struct {
 	points                      [][2]float64
 	seriesLock                  sync.Mutex
@@ -103,8 +170,28 @@ func (fp *pointSeries) interpolateWindow(index int) {
 	fp.seriesLock.Unlock()
 }
 
+// Midpoint returns the point halfway between <p1> and <p2>, in the same axis
+// order as its arguments.
 func Midpoint(p1 orb.Point, p2 orb.Point) orb.Point {
-	mLat := p1.Lat() + (p2.Lat()-p1.Lat())/2
-	mLon := p1.Lon() + (p2.Lon()-p1.Lon())/2
-	return orb.Point{mLat, mLon}
+	return orb.Point{
+		p1[0] + (p2[0]-p1[0])/2,
+		p1[1] + (p2[1]-p1[1])/2,
+	}
+}
+
+// CirclePlanar approximates the circle of <radius> metres around the [lng, lat]
+// point <center> with a polygon of <sides> sides.
+func CirclePlanar(center orb.Point, radius float64, sides int) orb.Polygon {
+	const metresPerDegree = 111_320.0
+	dLat := radius / metresPerDegree
+	dLng := dLat / math.Cos(center[1]*math.Pi/180)
+	ring := make(orb.Ring, 0, sides+1)
+
+	for i := 0; i < sides; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(sides)
+		ring = append(ring, orb.Point{center[0] + dLng*math.Cos(angle), center[1] + dLat*math.Sin(angle)})
+	}
+	ring = append(ring, ring[0])
+
+	return orb.Polygon{ring}
 }
@@ -0,0 +1,103 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultShutdownTimeout is how long a server waits for its requests to
+// finish when it shuts down, if no timeout is set.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Options configures how a server is run.
+type Options struct {
+	// CertFile and KeyFile are the PEM certificate chain and key the server
+	// is served over HTTPS with, it is served over plain HTTP if they are not
+	// set.
+	CertFile string
+	KeyFile  string
+	// ShutdownTimeout bounds the wait for the requests in flight to finish
+	// and for OnShutdown, DefaultShutdownTimeout if it is not set.
+	ShutdownTimeout time.Duration
+	// OnShutdown are run as the server shuts down, alongside the drain of its
+	// requests. Long-lived requests, such as event streams, are only
+	// finished by them.
+	OnShutdown []func(ctx context.Context) error
+}
+
+// Run serves <srv> until <ctx> is done, or the process is sent SIGINT or
+// SIGTERM. The server then stops accepting connections and waits for the
+// requests in flight and the OnShutdown hooks to finish, up to the
+// ShutdownTimeout, before closing any connections that are left.
+func Run(ctx context.Context, srv *http.Server, opts Options) error {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return fmt.Errorf("unable to start application server: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		if opts.CertFile != "" || opts.KeyFile != "" {
+			log.Infof("server listening over https on: %s", ln.Addr())
+			served <- srv.ServeTLS(ln, opts.CertFile, opts.KeyFile)
+			return
+		}
+		log.Infof("server listening on: %s", ln.Addr())
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		return fmt.Errorf("unable to serve application server: %w", err)
+	case <-ctx.Done():
+	}
+	// a second signal kills the process
+	stop()
+
+	timeout := opts.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	log.Infof("shutting down, waiting up to %s for requests to finish", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var lock sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, hook := range opts.OnShutdown {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := hook(shutdownCtx); err != nil {
+				lock.Lock()
+				errs = append(errs, err)
+				lock.Unlock()
+			}
+		}()
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Warnf("closing the connections left after %s: %v", timeout, err)
+		srv.Close()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("error occurred shutting down: %w", err)
+	}
+	log.Infof("server shut down")
+	return nil
+}
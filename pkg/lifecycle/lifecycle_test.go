@@ -0,0 +1,86 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	// a free port to serve on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	started := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hookErr := errors.New("flush failed")
+	var hooked bool
+	ran := make(chan error, 1)
+	go func() {
+		ran <- Run(ctx, &http.Server{Addr: addr, Handler: mux}, Options{
+			ShutdownTimeout: 5 * time.Second,
+			OnShutdown: []func(ctx context.Context) error{func(ctx context.Context) error {
+				hooked = true
+				return hookErr
+			}},
+		})
+	}()
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = http.Get("http://" + addr + "/health")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// the request in flight when the server shuts down is finished
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+	cancel()
+
+	assert.Equal(t, "done", <-body)
+	err = <-ran
+	assert.ErrorIs(t, err, hookErr)
+	assert.True(t, hooked)
+
+	_, err = http.Get("http://" + addr + "/health")
+	assert.Error(t, err)
+}
+
+func TestRunListenError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	err = Run(context.Background(), &http.Server{Addr: ln.Addr().String()}, Options{})
+	assert.ErrorContains(t, err, "unable to start application server")
+}
@@ -0,0 +1,157 @@
+// Package mock_utm_server is an in-memory stand-in for the manna-utm
+// UTMController, serving the routes used by uspace_client.MannaUtmClient.
+package mock_utm_server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/model/utm"
+)
+
+func GetServer() *gin.Engine {
+	router := gin.Default()
+	s := newStore()
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "manna-utm-cli mock manna-utm server is healthy.",
+		})
+	})
+
+	// see https://github.com/m4a3/manna-utm/blob/persistence/src/main/java/manna/aero/utm/controller/UTMController.java#L56-L91
+	router.POST("/operationalintent/:uavId/:entityId", func(c *gin.Context) {
+		uavId, err := strconv.Atoi(c.Param("uavId"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "uavId must be an integer"})
+			return
+		}
+		entityId := c.Param("entityId")
+
+		var intent uspace.OperationalIntent
+		if err := json.NewDecoder(c.Request.Body).Decode(&intent); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		record, err := s.create(uavId, entityId, &intent)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		log.Infof("created operational intent (entityId=%s, uavId=%d) with %d volumes", entityId, uavId, len(intent.Volumes))
+		c.JSON(http.StatusCreated, record)
+	})
+
+	// fetching and updating an operational intent are not routes of the
+	// UTMController, they are served so that updates can be exercised
+	router.GET("/operationalintent/:id", func(c *gin.Context) {
+		record, err := s.get(c.Param("id"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, record)
+	})
+
+	router.PUT("/operationalintent/:id", func(c *gin.Context) {
+		var update uspace.OperationalIntentUpdate
+		if err := json.NewDecoder(c.Request.Body).Decode(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if update.OperationalIntent == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "operational_intent is required"})
+			return
+		}
+
+		record, err := s.update(c.Param("id"), update.Ovn, update.OperationalIntent)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		log.Infof("updated operational intent (entityId=%s) to version %d with %d volumes", record.EntityId, record.Version, len(record.Intent.Volumes))
+		c.JSON(http.StatusOK, record)
+	})
+
+	// see https://github.com/m4a3/manna-utm/blob/persistence/src/main/java/manna/aero/utm/controller/UTMController.java#L117-L139
+	router.PUT("/operationalintent/:id/end", transitionHandler(s, utm.StateEnded, func(from utm.OperationalIntentState) error {
+		return utm.ValidateTransition(from, utm.StateEnded)
+	}))
+
+	// see https://github.com/m4a3/manna-utm/blob/persistence/src/main/java/manna/aero/utm/controller/UTMController.java#L141-L163
+	router.POST("/operationalintent/query", func(c *gin.Context) {
+		var vol uspace.Volume4d
+		if err := json.NewDecoder(c.Request.Body).Decode(&vol); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		details := s.query(vol)
+		log.Infof("%d operational intents intersect the queried volume", len(details))
+		c.JSON(http.StatusOK, details)
+	})
+
+	// see https://github.com/m4a3/manna-utm/blob/persistence/src/main/java/manna/aero/utm/controller/UTMController.java#L165-L197
+	router.POST("/ussClient/v1/operational_intents/:id", func(c *gin.Context) {
+		var t uspace.Telemetry
+		if err := json.NewDecoder(c.Request.Body).Decode(&t); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := s.addTelemetry(c.Param("id"), t); err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		log.Debugf("received telemetry for operational intent (entityId=%s)", c.Param("id"))
+		c.Status(http.StatusNoContent)
+	})
+
+	return router
+}
+
+func transitionHandler(s *store, to utm.OperationalIntentState, validate func(from utm.OperationalIntentState) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		record, err := s.transition(c.Param("id"), to, validate)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		log.Infof("operational intent (entityId=%s) is now %s", record.EntityId, record.State)
+		c.JSON(http.StatusOK, record)
+	}
+}
+
+func respondWithError(c *gin.Context, err error) {
+	var conflict *conflictError
+	var notFound *notFoundError
+	var invalidTransition *utm.InvalidTransitionError
+	var staleOvn *staleOvnError
+	var airspaceConflict *airspaceConflictError
+	switch {
+	case errors.As(err, &staleOvn):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "ovn": staleOvn.currentOvn})
+	case errors.As(err, &airspaceConflict):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":               err.Error(),
+			"conflicting_volumes": airspaceConflict.conflictingVolumes,
+			"conflicting_intents": airspaceConflict.conflictingIntents,
+		})
+	case errors.As(err, &conflict), errors.As(err, &invalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.As(err, &notFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,189 @@
+package mock_utm_server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/model/utm"
+)
+
+const (
+	entityA = "a3b5c7d9-0000-4000-8000-00000000000a"
+	entityB = "a3b5c7d9-0000-4000-8000-00000000000b"
+	entityC = "a3b5c7d9-0000-4000-8000-00000000000c"
+)
+
+// intentAt is an operational intent of <priority> with a volume over the
+// [lat, lng] square of side 0.01 degrees from <lat>, <lng>.
+func intentAt(priority uint16, lat float64, lng float64, start time.Time) *uspace.OperationalIntent {
+	return &uspace.OperationalIntent{
+		Priority:      priority,
+		DepartureTime: start,
+		Volumes: []uspace.Volume4d{{
+			TimeStart:     start,
+			TimeEnd:       start.Add(10 * time.Minute),
+			AltitudeLower: 0,
+			AltitudeUpper: 120,
+			Polygon:       orb.Polygon{{{lat, lng}, {lat + 0.01, lng}, {lat + 0.01, lng + 0.01}, {lat, lng + 0.01}, {lat, lng}}},
+		}},
+	}
+}
+
+// send sends a <method> request with the JSON of <body>, if not nil, to
+// <path> of <srv> and decodes the response into <out>, if not nil, returning
+// its status code.
+func send(t *testing.T, srv *httptest.Server, method string, path string, body any, out any) int {
+	var reqBody bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&reqBody).Encode(body))
+	}
+	req, err := http.NewRequest(method, srv.URL+path, &reqBody)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+func TestCreateAndConflicts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(GetServer())
+	defer srv.Close()
+	start := time.Now().Truncate(time.Millisecond)
+
+	var a OperationalIntentRecord
+	require.Equal(t, http.StatusCreated, send(t, srv, http.MethodPost, "/operationalintent/1/"+entityA, intentAt(10, 46.19, 6.12, start), &a))
+	assert.Equal(t, entityA, a.EntityId)
+	assert.Equal(t, 1, a.UavId)
+	assert.Equal(t, 1, a.Version)
+	assert.Equal(t, utm.StateAccepted, a.State)
+	assert.NotEmpty(t, a.Ovn)
+
+	// the entity is taken until its operational intent ends
+	assert.Equal(t, http.StatusConflict, send(t, srv, http.MethodPost, "/operationalintent/1/"+entityA, intentAt(10, 47, 7, start), nil))
+
+	// an intersecting operational intent of the same priority collides
+	var conflict struct {
+		ConflictingVolumes []int    `json:"conflicting_volumes"`
+		ConflictingIntents []string `json:"conflicting_intents"`
+	}
+	require.Equal(t, http.StatusConflict, send(t, srv, http.MethodPost, "/operationalintent/2/"+entityB, intentAt(10, 46.195, 6.125, start), &conflict))
+	assert.Equal(t, []int{0}, conflict.ConflictingVolumes)
+	assert.Equal(t, []string{entityA}, conflict.ConflictingIntents)
+
+	// a higher priority, a later time or another place does not
+	assert.Equal(t, http.StatusCreated, send(t, srv, http.MethodPost, "/operationalintent/2/"+entityB, intentAt(20, 46.195, 6.125, start), nil))
+	assert.Equal(t, http.StatusCreated, send(t, srv, http.MethodPost, "/operationalintent/3/"+entityC, intentAt(10, 46.19, 6.12, start.Add(time.Hour)), nil))
+
+	var found []utm.OperationalIntentDetails
+	require.Equal(t, http.StatusOK, send(t, srv, http.MethodPost, "/operationalintent/query", intentAt(0, 46.19, 6.12, start).Volumes[0], &found))
+	assert.Len(t, found, 2)
+
+	// the airspace of an ended operational intent is free
+	require.Equal(t, http.StatusOK, send(t, srv, http.MethodPut, "/operationalintent/"+entityB+"/end", nil, nil))
+	require.Equal(t, http.StatusOK, send(t, srv, http.MethodPost, "/operationalintent/query", intentAt(0, 46.19, 6.12, start).Volumes[0], &found))
+	assert.Len(t, found, 1)
+	assert.Equal(t, http.StatusCreated, send(t, srv, http.MethodPost, "/operationalintent/2/"+entityB, intentAt(20, 46.195, 6.125, start), nil))
+}
+
+func TestUpdate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(GetServer())
+	defer srv.Close()
+	start := time.Now().Truncate(time.Millisecond)
+
+	var a OperationalIntentRecord
+	require.Equal(t, http.StatusCreated, send(t, srv, http.MethodPost, "/operationalintent/1/"+entityA, intentAt(10, 46.19, 6.12, start), &a))
+	require.Equal(t, http.StatusCreated, send(t, srv, http.MethodPost, "/operationalintent/2/"+entityB, intentAt(10, 46.30, 6.30, start), nil))
+
+	// an update against a stale OVN is rejected with the current one
+	var stale struct {
+		Ovn string `json:"ovn"`
+	}
+	require.Equal(t, http.StatusConflict, send(t, srv, http.MethodPut, "/operationalintent/"+entityA,
+		uspace.OperationalIntentUpdate{Ovn: "stale", Version: 1, OperationalIntent: intentAt(10, 46.20, 6.13, start)}, &stale))
+	assert.Equal(t, a.Ovn, stale.Ovn)
+
+	// as is one moved into the airspace of another
+	var conflict struct {
+		ConflictingIntents []string `json:"conflicting_intents"`
+	}
+	require.Equal(t, http.StatusConflict, send(t, srv, http.MethodPut, "/operationalintent/"+entityA,
+		uspace.OperationalIntentUpdate{Ovn: a.Ovn, Version: 1, OperationalIntent: intentAt(10, 46.305, 6.305, start)}, &conflict))
+	assert.Equal(t, []string{entityB}, conflict.ConflictingIntents)
+
+	// its own volumes do not collide with the update
+	var updated OperationalIntentRecord
+	require.Equal(t, http.StatusOK, send(t, srv, http.MethodPut, "/operationalintent/"+entityA,
+		uspace.OperationalIntentUpdate{Ovn: a.Ovn, Version: 1, OperationalIntent: intentAt(10, 46.195, 6.125, start)}, &updated))
+	assert.Equal(t, 2, updated.Version)
+	assert.NotEqual(t, a.Ovn, updated.Ovn)
+
+	var got OperationalIntentRecord
+	require.Equal(t, http.StatusOK, send(t, srv, http.MethodGet, "/operationalintent/"+entityA, nil, &got))
+	assert.Equal(t, updated.Ovn, got.Ovn)
+	assert.InDelta(t, 46.195, got.Intent.Volumes[0].Polygon[0][0][0], 1e-5)
+
+	assert.Equal(t, http.StatusNotFound, send(t, srv, http.MethodGet, "/operationalintent/"+entityC, nil, nil))
+	assert.Equal(t, http.StatusNotFound, send(t, srv, http.MethodPut, "/operationalintent/"+entityC,
+		uspace.OperationalIntentUpdate{Ovn: a.Ovn, OperationalIntent: intentAt(10, 46.19, 6.12, start)}, nil))
+	assert.Equal(t, http.StatusBadRequest, send(t, srv, http.MethodPut, "/operationalintent/"+entityA, uspace.OperationalIntentUpdate{Ovn: updated.Ovn}, nil))
+}
+
+func TestTransitions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(GetServer())
+	defer srv.Close()
+	start := time.Now().Truncate(time.Millisecond)
+
+	require.Equal(t, http.StatusCreated, send(t, srv, http.MethodPost, "/operationalintent/1/"+entityA, intentAt(10, 46.19, 6.12, start), nil))
+
+	assert.Equal(t, http.StatusNoContent, send(t, srv, http.MethodPost, "/ussClient/v1/operational_intents/"+entityA, uspace.Telemetry{Latitude: 46.19, Longitude: 6.12}, nil))
+	assert.Equal(t, http.StatusNotFound, send(t, srv, http.MethodPost, "/ussClient/v1/operational_intents/"+entityB, uspace.Telemetry{}, nil))
+
+	var record OperationalIntentRecord
+	require.Equal(t, http.StatusOK, send(t, srv, http.MethodPut, "/operationalintent/"+entityA+"/end", nil, &record))
+	assert.Equal(t, utm.StateEnded, record.State)
+	assert.Len(t, record.Telemetry, 1)
+	// an ended operational intent stays ended, and cannot be updated
+	assert.Equal(t, http.StatusConflict, send(t, srv, http.MethodPut, "/operationalintent/"+entityA+"/end", nil, nil))
+	assert.Equal(t, http.StatusConflict, send(t, srv, http.MethodPut, "/operationalintent/"+entityA,
+		uspace.OperationalIntentUpdate{Ovn: record.Ovn, OperationalIntent: intentAt(10, 46.19, 6.12, start)}, nil))
+
+	// only the routes of the UTMController are served
+	assert.Equal(t, http.StatusNotFound, send(t, srv, http.MethodPut, "/operationalintent/"+entityB+"/activate", nil, nil))
+
+	assert.Equal(t, http.StatusNotFound, send(t, srv, http.MethodPut, "/operationalintent/"+entityC+"/end", nil, nil))
+	assert.Equal(t, http.StatusBadRequest, send(t, srv, http.MethodPost, "/operationalintent/uav/"+entityC, intentAt(10, 46.19, 6.12, start), nil))
+}
+
+func TestQueryOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(GetServer())
+	defer srv.Close()
+	start := time.Now().Truncate(time.Millisecond)
+
+	require.Equal(t, http.StatusCreated, send(t, srv, http.MethodPost, "/operationalintent/3/"+entityC, intentAt(10, 46.19, 6.12, start), nil))
+	require.Equal(t, http.StatusCreated, send(t, srv, http.MethodPost, "/operationalintent/1/"+entityA, intentAt(20, 46.19, 6.12, start.Add(2*time.Minute)), nil))
+	require.Equal(t, http.StatusCreated, send(t, srv, http.MethodPost, "/operationalintent/2/"+entityB, intentAt(30, 46.19, 6.12, start), nil))
+
+	// by the start of their time window, then by entity id
+	for range 10 {
+		var found []utm.OperationalIntentDetails
+		require.Equal(t, http.StatusOK, send(t, srv, http.MethodPost, "/operationalintent/query", intentAt(0, 46.19, 6.12, start).Volumes[0], &found))
+		require.Len(t, found, 3)
+		assert.Equal(t, []uint16{30, 10, 20}, []uint16{found[0].Priority, found[1].Priority, found[2].Priority})
+	}
+}
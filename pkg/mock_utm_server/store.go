@@ -0,0 +1,240 @@
+package mock_utm_server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/model/utm"
+)
+
+// OperationalIntentRecord is the state the mock server keeps for each
+// operational intent created through it.
+type OperationalIntentRecord struct {
+	EntityId  string                     `json:"entity_id"`
+	UavId     int                        `json:"uav_id"`
+	Ovn       string                     `json:"ovn"`
+	Version   int                        `json:"version"`
+	State     utm.OperationalIntentState `json:"state"`
+	CreatedAt time.Time                  `json:"created_at"`
+	UpdatedAt time.Time                  `json:"updated_at"`
+	Intent    *uspace.OperationalIntent  `json:"intent"`
+	Telemetry []uspace.Telemetry         `json:"telemetry"`
+}
+
+// store is an in-memory store of operational intents, keyed by entity id.
+type store struct {
+	lock    sync.RWMutex
+	intents map[string]*OperationalIntentRecord
+}
+
+func newStore() *store {
+	return &store{intents: map[string]*OperationalIntentRecord{}}
+}
+
+type conflictError struct {
+	entityId string
+}
+
+func (e *conflictError) Error() string {
+	return fmt.Sprintf("an active operational intent already exists for entity: %s", e.entityId)
+}
+
+// staleOvnError is returned for an update that was not made against the
+// current OVN of the operational intent.
+type staleOvnError struct {
+	entityId   string
+	ovn        string
+	currentOvn string
+}
+
+func (e *staleOvnError) Error() string {
+	return fmt.Sprintf("OVN %s of entity %s is stale, the current OVN is %s", e.ovn, e.entityId, e.currentOvn)
+}
+
+// airspaceConflictError is returned when the volumes of an operational intent
+// intersect those of other operational intents of the same or higher priority.
+type airspaceConflictError struct {
+	entityId           string
+	conflictingVolumes []int
+	conflictingIntents []string
+}
+
+func (e *airspaceConflictError) Error() string {
+	return fmt.Sprintf("volumes %v of entity %s conflict with the operational intents of entities %v", e.conflictingVolumes, e.entityId, e.conflictingIntents)
+}
+
+type notFoundError struct {
+	entityId string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("no operational intent exists for entity: %s", e.entityId)
+}
+
+func (s *store) create(uavId int, entityId string, intent *uspace.OperationalIntent) (OperationalIntentRecord, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if existing, ok := s.intents[entityId]; ok && existing.State != utm.StateEnded {
+		return OperationalIntentRecord{}, &conflictError{entityId: entityId}
+	}
+	if err := s.checkAirspace(entityId, intent); err != nil {
+		return OperationalIntentRecord{}, err
+	}
+
+	now := time.Now()
+	record := &OperationalIntentRecord{
+		EntityId:  entityId,
+		UavId:     uavId,
+		Ovn:       uuid.NewString(),
+		Version:   1,
+		State:     utm.StateAccepted,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Intent:    intent,
+	}
+	s.intents[entityId] = record
+	return *record, nil
+}
+
+// transition moves the operational intent of <entityId> to state <to>, if
+// <validate> allows it from its current state.
+func (s *store) transition(entityId string, to utm.OperationalIntentState, validate func(from utm.OperationalIntentState) error) (OperationalIntentRecord, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	record, ok := s.intents[entityId]
+	if !ok {
+		return OperationalIntentRecord{}, &notFoundError{entityId: entityId}
+	}
+	if err := validate(record.State); err != nil {
+		return OperationalIntentRecord{}, err
+	}
+	record.State = to
+	record.UpdatedAt = time.Now()
+	return *record, nil
+}
+
+// update replaces the intent of the operational intent of <entityId> with
+// <intent>, if <ovn> is its current OVN, and gives it a new OVN and version.
+func (s *store) update(entityId string, ovn string, intent *uspace.OperationalIntent) (OperationalIntentRecord, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	record, ok := s.intents[entityId]
+	if !ok {
+		return OperationalIntentRecord{}, &notFoundError{entityId: entityId}
+	}
+	if err := utm.ValidateUpdate(record.State); err != nil {
+		return OperationalIntentRecord{}, err
+	}
+	if ovn != record.Ovn {
+		return OperationalIntentRecord{}, &staleOvnError{entityId: entityId, ovn: ovn, currentOvn: record.Ovn}
+	}
+	if err := s.checkAirspace(entityId, intent); err != nil {
+		return OperationalIntentRecord{}, err
+	}
+
+	record.Intent = intent
+	record.Ovn = uuid.NewString()
+	record.Version++
+	record.UpdatedAt = time.Now()
+	return *record, nil
+}
+
+func (s *store) get(entityId string) (OperationalIntentRecord, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	record, ok := s.intents[entityId]
+	if !ok {
+		return OperationalIntentRecord{}, &notFoundError{entityId: entityId}
+	}
+	return *record, nil
+}
+
+// checkAirspace returns an airspaceConflictError if any volume of <intent>
+// intersects an operational intent, other than that of <entityId>, which has
+// not ended and has the same or a higher priority. The caller must hold the
+// lock.
+func (s *store) checkAirspace(entityId string, intent *uspace.OperationalIntent) error {
+	conflict := &airspaceConflictError{entityId: entityId}
+	for i, vol := range intent.Volumes {
+		collides := false
+		for _, record := range s.intents {
+			if record.EntityId == entityId || record.State == utm.StateEnded || record.Intent.Priority < intent.Priority {
+				continue
+			}
+			if record.Intent.Intersects(vol) {
+				collides = true
+				if !slices.Contains(conflict.conflictingIntents, record.EntityId) {
+					conflict.conflictingIntents = append(conflict.conflictingIntents, record.EntityId)
+				}
+			}
+		}
+		if collides {
+			conflict.conflictingVolumes = append(conflict.conflictingVolumes, i)
+		}
+	}
+	if len(conflict.conflictingVolumes) > 0 {
+		return conflict
+	}
+	return nil
+}
+
+func (s *store) addTelemetry(entityId string, t uspace.Telemetry) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	record, ok := s.intents[entityId]
+	if !ok {
+		return &notFoundError{entityId: entityId}
+	}
+	record.Telemetry = append(record.Telemetry, t)
+	return nil
+}
+
+// query returns the details of every operational intent that has not ended
+// and has a volume intersecting <vol>, ordered by the start of their time
+// window and then by entity id, so that the same query gives the same answer.
+func (s *store) query(vol uspace.Volume4d) []utm.OperationalIntentDetails {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var found []*OperationalIntentRecord
+	for _, record := range s.intents {
+		if record.State == utm.StateEnded || !record.Intent.Intersects(vol) {
+			continue
+		}
+		found = append(found, record)
+	}
+	slices.SortFunc(found, func(a, b *OperationalIntentRecord) int {
+		if c := timeStart(a.Intent).Compare(timeStart(b.Intent)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.EntityId, b.EntityId)
+	})
+
+	details := make([]utm.OperationalIntentDetails, 0, len(found))
+	for _, record := range found {
+		details = append(details, utm.OperationalIntentDetailsFromUspace(record.Intent))
+	}
+	return details
+}
+
+// timeStart is the start of the earliest volume of <oi>, or the zero time if
+// it has none.
+func timeStart(oi *uspace.OperationalIntent) time.Time {
+	var start time.Time
+	for i, v := range oi.Volumes {
+		if i == 0 || v.TimeStart.Before(start) {
+			start = v.TimeStart
+		}
+	}
+	return start
+}
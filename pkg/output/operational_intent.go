@@ -0,0 +1,99 @@
+package output
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/paulmach/orb/geojson"
+	"manna.aero/manna.utm.cli/model/utm"
+)
+
+// OperationalIntentSummary is one row of an operational intent table.
+type OperationalIntentSummary struct {
+	// Id and State are empty when they are not known, e.g. for the
+	// operational intents returned by a query.
+	Id        string
+	State     utm.OperationalIntentState
+	Priority  uint16
+	TimeStart time.Time
+	TimeEnd   time.Time
+	Volumes   int
+}
+
+// SummarizeOperationalIntent summarizes the operational intent <id> in
+// <state> with <details>, over the time window of all of its volumes.
+func SummarizeOperationalIntent(id string, state utm.OperationalIntentState, details utm.OperationalIntentDetails) OperationalIntentSummary {
+	s := OperationalIntentSummary{
+		Id:       id,
+		State:    state,
+		Priority: details.Priority,
+		Volumes:  len(details.Volumes) + len(details.OffNominalVolumes),
+	}
+	for _, vols := range [][]utm.Volume4d{details.Volumes, details.OffNominalVolumes} {
+		for _, vol := range vols {
+			if s.TimeStart.IsZero() || vol.TimeStart.Before(s.TimeStart) {
+				s.TimeStart = vol.TimeStart
+			}
+			if vol.TimeEnd.After(s.TimeEnd) {
+				s.TimeEnd = vol.TimeEnd
+			}
+		}
+	}
+	return s
+}
+
+// OperationalIntentTable is the table of <summaries>, with a row for each.
+func OperationalIntentTable(summaries ...OperationalIntentSummary) *Table {
+	t := &Table{Header: []string{"ID", "PRIORITY", "STATE", "TIME START", "TIME END", "VOLUMES"}}
+	for _, s := range summaries {
+		t.Rows = append(t.Rows, []string{
+			orDash(s.Id),
+			strconv.Itoa(int(s.Priority)),
+			orDash(string(s.State)),
+			FormatTime(s.TimeStart),
+			FormatTime(s.TimeEnd),
+			strconv.Itoa(s.Volumes),
+		})
+	}
+	return t
+}
+
+// OperationalIntentsGeoJson is a feature for each volume of <details>,
+// annotated with the id of its operational intent in <ids>, when it is known.
+func OperationalIntentsGeoJson(ids []string, details []utm.OperationalIntentDetails) *geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+	for i, d := range details {
+		for _, f := range d.GeoJsonFeatures() {
+			if i < len(ids) && ids[i] != "" {
+				f.Properties["id"] = ids[i]
+			}
+			f.Properties["operational_intent_index"] = i
+			fc.Append(f)
+		}
+	}
+	return fc
+}
+
+// Items converts <items> for Result.Items.
+func Items[T any](items []T) []any {
+	out := make([]any, 0, len(items))
+	for _, item := range items {
+		out = append(out, item)
+	}
+	return out
+}
+
+// FormatTime formats <t> for a table cell.
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
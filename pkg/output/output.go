@@ -0,0 +1,281 @@
+// Package output renders the data returned by commands in the format chosen
+// with their --output flag, so that it can be piped into jq, read as a table
+// or loaded straight into a map.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"manna.aero/manna.utm.cli/pkg/cli_error"
+)
+
+type Format string
+
+const (
+	FormatJSON    Format = "json"
+	FormatYAML    Format = "yaml"
+	FormatTable   Format = "table"
+	FormatGeoJSON Format = "geojson"
+	FormatNDJSON  Format = "ndjson"
+)
+
+// Formats is every format that a Result may be written in.
+var Formats = []Format{FormatJSON, FormatYAML, FormatTable, FormatGeoJSON, FormatNDJSON}
+
+// FlagUsage is the usage of the --output flag of a command.
+var FlagUsage = fmt.Sprintf("The format to print the result in, one of: %s.", strings.Join(formatNames(), ", "))
+
+// Result is the data returned by a command, along with its renderings in the
+// formats that are not generic.
+type Result struct {
+	// Data is written as JSON or YAML.
+	Data any
+	// Items are written one JSON document per line for ndjson, defaulting to
+	// Data as the only item.
+	Items []any
+	// Table is written for table, if set.
+	Table *Table
+	// GeoJSON is written for geojson, if set.
+	GeoJSON *geojson.FeatureCollection
+}
+
+// Table is a tabular rendering of a Result.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Print writes <result> to the output of <cmd>, in the format set by its
+// --output flag or <defaultFormat> if the flag is empty.
+func Print(cmd *cobra.Command, defaultFormat Format, result *Result) error {
+	return Write(cmd.OutOrStdout(), outputFormat(cmd, defaultFormat), result)
+}
+
+// ValidateFlag returns a usage error if the --output flag of <cmd> is not one
+// of Formats. It is the PreRunE of the commands with the flag, so that an
+// unknown format fails before any request is sent.
+func ValidateFlag(cmd *cobra.Command, args []string) error {
+	format := outputFormat(cmd, "")
+	if format == "" || slices.Contains(Formats, format) {
+		return nil
+	}
+	return unknown(format)
+}
+
+func outputFormat(cmd *cobra.Command, defaultFormat Format) Format {
+	if f := cmd.Flags().Lookup("output"); f != nil && f.Value.String() != "" {
+		return Format(f.Value.String())
+	}
+	return defaultFormat
+}
+
+// Write writes <result> to <w> in <format>. Asking for a format that <result>
+// has no rendering for is a usage error.
+func Write(w io.Writer, format Format, result *Result) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, result.Data, "    ")
+	case FormatYAML:
+		return writeYAML(w, result.Data)
+	case FormatNDJSON:
+		items := result.Items
+		if items == nil {
+			items = []any{result.Data}
+		}
+		for _, item := range items {
+			if err := writeJSON(w, item, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatTable:
+		if result.Table == nil {
+			return unsupported(format)
+		}
+		return result.Table.write(w)
+	case FormatGeoJSON:
+		if result.GeoJSON == nil {
+			return unsupported(format)
+		}
+		return writeJSON(w, result.GeoJSON, "  ")
+	default:
+		return unknown(format)
+	}
+}
+
+// Stream writes results one after another in a single format, for commands
+// that keep printing results as they arrive. Tables print their header once,
+// and everything else is written one result per line, or one YAML document per
+// result.
+type Stream struct {
+	w      io.Writer
+	format Format
+	n      int
+	widths []int
+}
+
+// NewStream streams results to the output of <cmd>, in the format set by its
+// --output flag or <defaultFormat> if the flag is empty.
+func NewStream(cmd *cobra.Command, defaultFormat Format) *Stream {
+	return &Stream{w: cmd.OutOrStdout(), format: outputFormat(cmd, defaultFormat)}
+}
+
+// Write writes the next <result> to the stream.
+func (s *Stream) Write(result *Result) error {
+	defer func() { s.n++ }()
+	switch s.format {
+	case FormatJSON, FormatNDJSON:
+		return Write(s.w, FormatNDJSON, result)
+	case FormatYAML:
+		if s.n > 0 {
+			if _, err := fmt.Fprintln(s.w, "---"); err != nil {
+				return err
+			}
+		}
+		return writeYAML(s.w, result.Data)
+	case FormatGeoJSON:
+		if result.GeoJSON == nil {
+			return unsupported(s.format)
+		}
+		return writeJSON(s.w, result.GeoJSON, "")
+	case FormatTable:
+		if result.Table == nil {
+			return unsupported(s.format)
+		}
+		// the rows are written as they arrive, so the columns are as wide as
+		// the widest cell so far rather than aligned by a tabwriter
+		if s.n == 0 {
+			s.fit(result.Table.Header)
+		}
+		for _, row := range result.Table.Rows {
+			s.fit(row)
+		}
+		if s.n == 0 {
+			if err := s.writeRow(result.Table.Header); err != nil {
+				return err
+			}
+		}
+		for _, row := range result.Table.Rows {
+			if err := s.writeRow(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return Write(s.w, s.format, result)
+	}
+}
+
+// fit widens the columns of the stream to fit <row>.
+func (s *Stream) fit(row []string) {
+	for i, cell := range row {
+		if i == len(s.widths) {
+			s.widths = append(s.widths, 0)
+		}
+		s.widths[i] = max(s.widths[i], len(cell))
+	}
+}
+
+func (s *Stream) writeRow(row []string) error {
+	var line strings.Builder
+	for i, cell := range row {
+		if i < len(row)-1 {
+			fmt.Fprintf(&line, "%-*s  ", s.widths[i], cell)
+		} else {
+			line.WriteString(cell)
+		}
+	}
+	_, err := fmt.Fprintln(s.w, line.String())
+	return err
+}
+
+func (t *Table) write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.Header, "\t"))
+	for _, row := range t.Rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, v any, indent string) error {
+	var data []byte
+	var err error
+	if indent == "" {
+		data, err = json.Marshal(v)
+	} else {
+		data, err = json.MarshalIndent(v, "", indent)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// writeYAML writes <v> as YAML with the same field names and values as its
+// JSON, by decoding its JSON as a YAML document, which keeps the order of the
+// fields.
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// blockStyle clears the flow style that JSON is decoded with, and the quotes
+// of strings that YAML can write plainly. Strings that YAML 1.1 parsers read
+// as booleans, such as "no", keep their quotes.
+func blockStyle(n *yaml.Node) {
+	n.Style &^= yaml.FlowStyle
+	if n.Kind != yaml.ScalarNode || n.Tag != "!!str" || !yaml11Bools[strings.ToLower(n.Value)] {
+		n.Style &^= yaml.DoubleQuotedStyle
+	}
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+var yaml11Bools = map[string]bool{"y": true, "yes": true, "n": true, "no": true, "on": true, "off": true}
+
+func unknown(format Format) error {
+	return &cli_error.UsageError{Err: fmt.Errorf("unknown output format %q, expected one of: %s", format, strings.Join(formatNames(), ", "))}
+}
+
+func unsupported(format Format) error {
+	return &cli_error.UsageError{Err: fmt.Errorf("this command cannot print its result as %s", format)}
+}
+
+func formatNames() []string {
+	names := make([]string, 0, len(Formats))
+	for _, f := range Formats {
+		names = append(names, string(f))
+	}
+	return names
+}
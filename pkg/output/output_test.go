@@ -0,0 +1,84 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"manna.aero/manna.utm.cli/pkg/cli_error"
+)
+
+func TestWrite(t *testing.T) {
+	type item struct {
+		Name    string `json:"name"`
+		Enabled string `json:"enabled"`
+	}
+	items := []item{{Name: "a", Enabled: "true"}, {Name: "b", Enabled: "no"}}
+	result := &Result{
+		Data:  items,
+		Items: Items(items),
+		Table: &Table{Header: []string{"NAME", "ENABLED"}, Rows: [][]string{{"a", "true"}, {"b", "no"}}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatYAML, result))
+	assert.Equal(t, "- name: a\n  enabled: \"true\"\n- name: b\n  enabled: \"no\"\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatNDJSON, result))
+	assert.Equal(t, "{\"name\":\"a\",\"enabled\":\"true\"}\n{\"name\":\"b\",\"enabled\":\"no\"}\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatTable, result))
+	assert.Equal(t, "NAME  ENABLED\na     true\nb     no\n", buf.String())
+
+	var usageErr *cli_error.UsageError
+	assert.True(t, errors.As(Write(&buf, FormatGeoJSON, result), &usageErr))
+	assert.True(t, errors.As(Write(&buf, "xml", result), &usageErr))
+}
+
+func TestStream(t *testing.T) {
+	var buf bytes.Buffer
+	s := &Stream{w: &buf, format: FormatTable}
+	require.NoError(t, s.Write(&Result{Table: &Table{Header: []string{"NAME", "STATE"}, Rows: [][]string{{"a", "Accepted"}}}}))
+	require.NoError(t, s.Write(&Result{Table: &Table{Header: []string{"NAME", "STATE"}, Rows: [][]string{{"b", "Activated"}}}}))
+	assert.Equal(t, "NAME  STATE\na     Accepted\nb     Activated\n", buf.String())
+
+	buf.Reset()
+	s = &Stream{w: &buf, format: FormatYAML}
+	require.NoError(t, s.Write(&Result{Data: map[string]int{"count": 1}}))
+	require.NoError(t, s.Write(&Result{Data: map[string]int{"count": 2}}))
+	assert.Equal(t, "count: 1\n---\ncount: 2\n", buf.String())
+}
+
+func TestValidateFlag(t *testing.T) {
+	for _, tt := range []struct {
+		output string
+		valid  bool
+	}{
+		{"", true},
+		{"json", true},
+		{"geojson", true},
+		{"ndjson", true},
+		{"xml", false},
+		{"JSON", false},
+	} {
+		cmd := &cobra.Command{Use: "read", PreRunE: ValidateFlag, RunE: func(*cobra.Command, []string) error {
+			return errors.New("the command ran")
+		}}
+		cmd.Flags().StringP("output", "o", "", FlagUsage)
+		cmd.SetArgs([]string{"-o", tt.output})
+		cmd.SilenceUsage = true
+
+		err := cmd.Execute()
+		var usageErr *cli_error.UsageError
+		if tt.valid {
+			assert.EqualError(t, err, "the command ran", tt.output)
+		} else {
+			assert.True(t, errors.As(err, &usageErr), "%s: %v", tt.output, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"manna.aero/manna.utm.cli/model/utm"
+)
+
+// TelemetryTable is the table of <telemetry>, with a row for each position.
+func TelemetryTable(telemetry ...utm.OperationalIntentTelemetry) *Table {
+	t := &Table{Header: []string{"TIME MEASURED", "LATITUDE", "LONGITUDE", "ALTITUDE", "SPEED", "TRACK", "NEXT OPPORTUNITY"}}
+	for _, oit := range telemetry {
+		t.Rows = append(t.Rows, TelemetryRow(oit))
+	}
+	return t
+}
+
+// TelemetryRow is the row of <oit> in a TelemetryTable.
+func TelemetryRow(oit utm.OperationalIntentTelemetry) []string {
+	next := "-"
+	if oit.NextTelemetryOpportunity != nil {
+		next = FormatTime(oit.NextTelemetryOpportunity.Value)
+	}
+	tel := oit.Telemetry
+	if tel == nil {
+		return []string{"-", "-", "-", "-", "-", "-", next}
+	}
+	return []string{
+		FormatTime(tel.TimeMeasured.Value),
+		formatFloat(tel.Position.Latitude),
+		formatFloat(tel.Position.Longitude),
+		formatFloat(tel.Position.Altitude.Value) + " " + tel.Position.Altitude.Units,
+		formatFloat(tel.Velocity.Speed) + " " + tel.Velocity.UnitsSpeed,
+		formatFloat(tel.Velocity.Track),
+		next,
+	}
+}
+
+// TelemetryGeoJson is a point feature for the position of each of
+// <telemetry> that has one.
+func TelemetryGeoJson(telemetry ...utm.OperationalIntentTelemetry) *geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+	for _, oit := range telemetry {
+		tel := oit.Telemetry
+		if tel == nil {
+			continue
+		}
+		f := geojson.NewFeature(orb.Point{tel.Position.Longitude, tel.Position.Latitude})
+		f.Properties["operational_intent_id"] = oit.OperationalIntentId.String()
+		f.Properties["time_measured"] = tel.TimeMeasured.Value
+		f.Properties["altitude"] = tel.Position.Altitude.Value
+		f.Properties["speed"] = tel.Velocity.Speed
+		f.Properties["track"] = tel.Velocity.Track
+		fc.Append(f)
+	}
+	return fc
+}
+
+// formatFloat formats <f> with at most 7 decimals, about a centimetre of
+// latitude.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1e7)/1e7, 'f', -1, 64)
+}
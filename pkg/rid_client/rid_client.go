@@ -0,0 +1,201 @@
+// Package rid_client is a client of the ASTM F3411-22a Network Remote ID APIs
+// of a DSS and of other Service and Display Providers.
+package rid_client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/model/rid"
+	"manna.aero/manna.utm.cli/pkg/auth"
+	"manna.aero/manna.utm.cli/pkg/transport"
+)
+
+type RidClient struct {
+	baseUrl   *url.URL
+	c         *http.Client
+	UserAgent string
+	// TokenSource provides the bearer tokens sent with each request, no
+	// Authorization header is sent when it is nil.
+	TokenSource auth.TokenSource
+}
+
+// NewRidClient creates a client for the F3411 API at <base>, e.g.
+// https://dss.example.com/rid/v2, sending requests with the retries and
+// timeouts of <policy>. <tlsConfig> may be nil to use the default TLS
+// settings.
+func NewRidClient(base string, tlsConfig *tls.Config, policy transport.Policy) (*RidClient, error) {
+	u, err := url.Parse(strings.TrimRight(base, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported RID url scheme %q, expected http or https", u.Scheme)
+	}
+
+	return &RidClient{
+		baseUrl:   u,
+		c:         transport.NewClient(tlsConfig, policy),
+		UserAgent: "manna-utm-cli",
+	}, nil
+}
+
+// RidClientError is a failed request to a DSS or USS. <Err> is the
+// transport.ClientError or transport.ServerError of an error response, or the
+// error of a request that received no response, e.g. a transport.NetworkError.
+type RidClientError struct {
+	StatusCode int
+	Body       string
+	Err        error
+}
+
+func (e *RidClientError) Error() string {
+	if e.StatusCode == 0 && e.Err != nil {
+		return fmt.Sprintf("rid client error: %v", e.Err)
+	}
+	return fmt.Sprintf("rid client error: status=%d body=%q", e.StatusCode, e.Body)
+}
+
+func (e *RidClientError) Unwrap() error {
+	return e.Err
+}
+
+// PutIdentificationServiceArea creates the Identification Service Area <id>
+// in the DSS, or updates it if <version> is set
+// (createIdentificationServiceArea, updateIdentificationServiceArea).
+func (ridClient *RidClient) PutIdentificationServiceArea(ctx context.Context, id string, version string, params rid.CreateIdentificationServiceAreaParameters) (*rid.PutIdentificationServiceAreaResponse, error) {
+	path := []string{"dss", "identification_service_areas", id}
+	if version != "" {
+		path = append(path, version)
+	}
+	var resp rid.PutIdentificationServiceAreaResponse
+	err := ridClient.do(ctx, http.MethodPut, auth.ScopeRidServiceProvider, params, &resp, nil, path...)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetIdentificationServiceArea gets the Identification Service Area <id> from
+// the DSS (getIdentificationServiceArea).
+func (ridClient *RidClient) GetIdentificationServiceArea(ctx context.Context, id string) (*rid.IdentificationServiceArea, error) {
+	var resp rid.GetIdentificationServiceAreaResponse
+	err := ridClient.do(ctx, http.MethodGet, auth.ScopeRidServiceProvider, nil, &resp, nil, "dss", "identification_service_areas", id)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.ServiceArea, nil
+}
+
+// DeleteIdentificationServiceArea removes the Identification Service Area
+// <id> at <version> from the DSS (deleteIdentificationServiceArea).
+func (ridClient *RidClient) DeleteIdentificationServiceArea(ctx context.Context, id string, version string) (*rid.DeleteIdentificationServiceAreaResponse, error) {
+	var resp rid.DeleteIdentificationServiceAreaResponse
+	err := ridClient.do(ctx, http.MethodDelete, auth.ScopeRidServiceProvider, nil, &resp, nil, "dss", "identification_service_areas", id, version)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// NotifyIdentificationServiceArea notifies the subscriber of a change to the
+// Identification Service Area <id> that its <params>.Subscriptions cover
+// (postIdentificationServiceArea).
+func (ridClient *RidClient) NotifyIdentificationServiceArea(ctx context.Context, id string, params rid.PutIdentificationServiceAreaNotificationParameters) error {
+	return ridClient.do(ctx, http.MethodPost, auth.ScopeRidServiceProvider, params, nil, nil, "uss", "identification_service_areas", id)
+}
+
+// GetFlights gets the flights of the Service Provider in <view>, given as
+// lat1,lng1,lat2,lng2, with their positions over the last
+// <recentPositionsDuration> seconds (searchFlights).
+func (ridClient *RidClient) GetFlights(ctx context.Context, view string, recentPositionsDuration int) (*rid.GetFlightsResponse, error) {
+	query := url.Values{"view": {view}}
+	if recentPositionsDuration > 0 {
+		query.Set("recent_positions_duration", strconv.Itoa(recentPositionsDuration))
+	}
+	var resp rid.GetFlightsResponse
+	err := ridClient.do(ctx, http.MethodGet, auth.ScopeRidDisplayProvider, nil, &resp, query, "uss", "flights")
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetFlightDetails gets the details of the flight <id> of the Service
+// Provider (getFlightDetails).
+func (ridClient *RidClient) GetFlightDetails(ctx context.Context, id string) (*rid.RIDFlightDetails, error) {
+	var resp rid.GetFlightDetailsResponse
+	err := ridClient.do(ctx, http.MethodGet, auth.ScopeRidDisplayProvider, nil, &resp, nil, "uss", "flights", id, "details")
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Details, nil
+}
+
+// do sends a <method> request with the JSON of <body>, if not nil, to
+// <path>?<query> of the API, authorized for <scope>, and decodes the response
+// into <out>, if not nil.
+func (ridClient *RidClient) do(ctx context.Context, method string, scope string, body any, out any, query url.Values, path ...string) error {
+	requestUrl, err := url.JoinPath(ridClient.baseUrl.String(), path...)
+	if err != nil {
+		return err
+	}
+	if len(query) > 0 {
+		requestUrl += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, requestUrl, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", ridClient.UserAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if err := auth.SetAuthorizationHeader(ctx, req, ridClient.TokenSource, scope); err != nil {
+		return err
+	}
+
+	log.Debugf("%s %s", method, requestUrl)
+	resp, err := ridClient.c.Do(req)
+	if err != nil {
+		return &RidClientError{Body: err.Error(), Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// read a limited amount so you don’t blow memory on huge error bodies
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return &RidClientError{StatusCode: resp.StatusCode, Body: string(b), Err: transport.ResponseError(resp.StatusCode, string(b))}
+	}
+	if out == nil {
+		return nil
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &RidClientError{Body: err.Error(), Err: err}
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to decode the response to %s %s: %w", method, requestUrl, err)
+	}
+	return nil
+}
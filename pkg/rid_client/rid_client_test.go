@@ -0,0 +1,129 @@
+package rid_client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"manna.aero/manna.utm.cli/model/rid"
+	"manna.aero/manna.utm.cli/pkg/transport"
+)
+
+const isaId = "a3b5c7d9-0000-4000-8000-000000000003"
+
+func TestDssApi(t *testing.T) {
+	var puts []string
+	mux := http.NewServeMux()
+	put := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var params rid.CreateIdentificationServiceAreaParameters
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+		puts = append(puts, r.URL.Path)
+		json.NewEncoder(w).Encode(rid.PutIdentificationServiceAreaResponse{
+			ServiceArea: rid.IdentificationServiceArea{Id: r.PathValue("id"), UssBaseUrl: params.UssBaseUrl, Version: "v2"},
+			Subscribers: []rid.SubscriberToNotify{{Url: "https://dp.example.com", Subscriptions: []rid.SubscriptionState{{SubscriptionId: "sub", NotificationIndex: 1}}}},
+		})
+	}
+	mux.HandleFunc("PUT /rid/v2/dss/identification_service_areas/{id}", put)
+	mux.HandleFunc("PUT /rid/v2/dss/identification_service_areas/{id}/{version}", put)
+	mux.HandleFunc("GET /rid/v2/dss/identification_service_areas/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != isaId {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(rid.ErrorResponse{Message: "not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(rid.GetIdentificationServiceAreaResponse{ServiceArea: rid.IdentificationServiceArea{Id: isaId, Version: "v1"}})
+	})
+	mux.HandleFunc("DELETE /rid/v2/dss/identification_service_areas/{id}/{version}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("version") != "v2" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		json.NewEncoder(w).Encode(rid.DeleteIdentificationServiceAreaResponse{ServiceArea: rid.IdentificationServiceArea{Id: r.PathValue("id"), Version: "v2"}})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client, err := NewRidClient(srv.URL+"/rid/v2/", nil, transport.DefaultPolicy())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// an area is created without a version and updated at its version
+	resp, err := client.PutIdentificationServiceArea(ctx, isaId, "", rid.CreateIdentificationServiceAreaParameters{UssBaseUrl: "https://sp.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://sp.example.com", resp.ServiceArea.UssBaseUrl)
+	require.Len(t, resp.Subscribers, 1)
+	assert.Equal(t, 1, resp.Subscribers[0].Subscriptions[0].NotificationIndex)
+	_, err = client.PutIdentificationServiceArea(ctx, isaId, "v1", rid.CreateIdentificationServiceAreaParameters{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/rid/v2/dss/identification_service_areas/" + isaId, "/rid/v2/dss/identification_service_areas/" + isaId + "/v1"}, puts)
+
+	isa, err := client.GetIdentificationServiceArea(ctx, isaId)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", isa.Version)
+
+	_, err = client.GetIdentificationServiceArea(ctx, "a3b5c7d9-0000-4000-8000-000000000004")
+	var clientErr *transport.ClientError
+	require.True(t, errors.As(err, &clientErr), "expected a client error, got %v", err)
+	assert.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+	var ridErr *RidClientError
+	require.True(t, errors.As(err, &ridErr))
+	assert.Contains(t, ridErr.Body, "not found")
+
+	deleted, err := client.DeleteIdentificationServiceArea(ctx, isaId, "v2")
+	require.NoError(t, err)
+	assert.Equal(t, isaId, deleted.ServiceArea.Id)
+	_, err = client.DeleteIdentificationServiceArea(ctx, isaId, "v1")
+	require.True(t, errors.As(err, &clientErr), "expected a client error, got %v", err)
+	assert.Equal(t, http.StatusConflict, clientErr.StatusCode)
+}
+
+func TestUssApi(t *testing.T) {
+	var notified rid.PutIdentificationServiceAreaNotificationParameters
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /uss/identification_service_areas/{id}", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, isaId, r.PathValue("id"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notified))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /uss/flights", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "46.185,6.115,46.195,6.130", r.URL.Query().Get("view"))
+		assert.Equal(t, "5", r.URL.Query().Get("recent_positions_duration"))
+		json.NewEncoder(w).Encode(rid.GetFlightsResponse{Flights: []rid.RIDFlight{{Id: isaId, AircraftType: "Helicopter"}}})
+	})
+	mux.HandleFunc("GET /uss/flights/{id}/details", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(rid.GetFlightDetailsResponse{Details: rid.RIDFlightDetails{Id: r.PathValue("id"), OperationDescription: "A"}})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client, err := NewRidClient(srv.URL, nil, transport.DefaultPolicy())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, client.NotifyIdentificationServiceArea(ctx, isaId, rid.PutIdentificationServiceAreaNotificationParameters{
+		Subscriptions: []rid.SubscriptionState{{SubscriptionId: "sub", NotificationIndex: 2}},
+	}))
+	require.Len(t, notified.Subscriptions, 1)
+	assert.Equal(t, 2, notified.Subscriptions[0].NotificationIndex)
+	assert.Nil(t, notified.ServiceArea, "a removed area is notified without it")
+
+	flights, err := client.GetFlights(ctx, "46.185,6.115,46.195,6.130", 5)
+	require.NoError(t, err)
+	require.Len(t, flights.Flights, 1)
+	assert.Equal(t, "Helicopter", flights.Flights[0].AircraftType)
+
+	details, err := client.GetFlightDetails(ctx, isaId)
+	require.NoError(t, err)
+	assert.Equal(t, "A", details.OperationDescription)
+}
+
+func TestNewRidClient(t *testing.T) {
+	_, err := NewRidClient("ftp://dss.example.com", nil, transport.DefaultPolicy())
+	assert.Error(t, err)
+}
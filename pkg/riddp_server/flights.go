@@ -0,0 +1,291 @@
+package riddp_server
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"manna.aero/manna.utm.cli/model/rid"
+	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/model/uspace/virtual_uspace"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+)
+
+// clusterGrid is the number of rows and columns that a wide view is divided
+// into for clustering. Each cell has a quarter of the diagonal of the view,
+// which is over NetMinClusterSize.
+const clusterGrid = 4
+
+// flight is the Remote ID of a configured operational intent, flown from the
+// departure of the flights.
+type flight struct {
+	oiCnf     config.OperationalIntentConfig
+	telemetry []uspace.Telemetry
+}
+
+// flights are the Remote ID of the configured operational intents, all
+// departing together.
+type flights struct {
+	byId  map[string]*flight
+	order []string
+}
+
+func newFlights(oiCnfs []config.OperationalIntentConfig, departure time.Time) *flights {
+	f := &flights{byId: map[string]*flight{}}
+	for _, oiCnf := range oiCnfs {
+		id := oiCnf.MissionId.String()
+		f.byId[id] = &flight{
+			oiCnf:     oiCnf,
+			telemetry: virtual_uspace.NewOperationalIntentManagerAt(&oiCnf, virtual_uspace.DefaultDetailFactor, departure).Telemetry(),
+		}
+		f.order = append(f.order, id)
+	}
+	sort.Strings(f.order)
+	return f
+}
+
+// viewTooLargeError is returned for a view wider than
+// NetMaxDisplayAreaDiagonal.
+type viewTooLargeError struct {
+	diagonal float64
+}
+
+func (e *viewTooLargeError) Error() string {
+	return fmt.Sprintf("the diagonal of the view is %.0f m, the most that flights are returned for is %.0f m", e.diagonal, rid.NetMaxDisplayAreaDiagonal)
+}
+
+// parseView parses a view of the form lat1,lng1,lat2,lng2 into the bound
+// between the two corners.
+func parseView(view string) (orb.Bound, error) {
+	parts := strings.Split(view, ",")
+	if len(parts) != 4 {
+		return orb.Bound{}, fmt.Errorf("view must be lat1,lng1,lat2,lng2, not %q", view)
+	}
+	var coords [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return orb.Bound{}, fmt.Errorf("view must be lat1,lng1,lat2,lng2: %w", err)
+		}
+		coords[i] = v
+	}
+	for _, lat := range []float64{coords[0], coords[2]} {
+		if lat < -90 || lat > 90 {
+			return orb.Bound{}, fmt.Errorf("latitude %v of the view is out of range", lat)
+		}
+	}
+	for _, lng := range []float64{coords[1], coords[3]} {
+		if lng < -180 || lng > 180 {
+			return orb.Bound{}, fmt.Errorf("longitude %v of the view is out of range", lng)
+		}
+	}
+	return orb.MultiPoint{{coords[1], coords[0]}, {coords[3], coords[2]}}.Bound(), nil
+}
+
+// inView is the flights in <view> at <now>, as a Display Provider shows them:
+// the flights in the air that have been in <view> within
+// NetMaxNearRealTimeDataPeriod, or clusters of them if the diagonal of <view>
+// is over NetDetailsMaxDisplayAreaDiagonal.
+func (f *flights) inView(view orb.Bound, now time.Time) (*rid.GetFlightsResponse, error) {
+	diagonal, err := checkView(view)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &rid.GetFlightsResponse{Timestamp: utm.NewTime(now.UTC()), Flights: []rid.RIDFlight{}}
+	var visible []rid.RIDFlight
+	for _, id := range f.order {
+		if ridFlight, ok := f.byId[id].state(now, view, rid.NetMaxNearRealTimeDataPeriod); ok {
+			visible = append(visible, ridFlight)
+		}
+	}
+	if diagonal <= rid.NetDetailsMaxDisplayAreaDiagonal {
+		resp.Flights = append(resp.Flights, visible...)
+		return resp, nil
+	}
+	resp.Clusters = cluster(view, visible)
+	return resp, nil
+}
+
+// provided is the flights in <view> at <now>, as a Service Provider serves
+// them: the flights in the air that have been in <view> within
+// NetMaxNearRealTimeDataPeriod, with their positions over the last <recent>.
+func (f *flights) provided(view orb.Bound, now time.Time, recent time.Duration) (*rid.GetFlightsResponse, error) {
+	if _, err := checkView(view); err != nil {
+		return nil, err
+	}
+
+	resp := &rid.GetFlightsResponse{Timestamp: utm.NewTime(now.UTC()), Flights: []rid.RIDFlight{}}
+	for _, id := range f.order {
+		if ridFlight, ok := f.byId[id].state(now, view, recent); ok {
+			resp.Flights = append(resp.Flights, ridFlight)
+		}
+	}
+	return resp, nil
+}
+
+// checkView returns the diagonal of <view> in metres, or a viewTooLargeError
+// if it is over NetMaxDisplayAreaDiagonal.
+func checkView(view orb.Bound) (float64, error) {
+	diagonal := geo.Distance(view.Min, view.Max)
+	if diagonal > rid.NetMaxDisplayAreaDiagonal {
+		return diagonal, &viewTooLargeError{diagonal: diagonal}
+	}
+	return diagonal, nil
+}
+
+// state is the Remote ID of the flight at <now>, with its positions over the
+// last <recent>, if it is in the air and has been in <view> within
+// NetMaxNearRealTimeDataPeriod.
+func (fl *flight) state(now time.Time, view orb.Bound, recent time.Duration) (rid.RIDFlight, bool) {
+	nowMilli := now.UnixMilli()
+	// the index of the first telemetry measured after now
+	next := sort.Search(len(fl.telemetry), func(i int) bool {
+		return fl.telemetry[i].TimeMeasured > nowMilli
+	})
+	if next == 0 || next == len(fl.telemetry) {
+		return rid.RIDFlight{}, false
+	}
+
+	current := fl.telemetry[next-1]
+	aircraftType := fl.oiCnf.AircraftType
+	if aircraftType == "" {
+		aircraftType = rid.AircraftTypeOther
+	}
+	ridFlight := rid.RIDFlight{
+		Id:           fl.oiCnf.MissionId.String(),
+		AircraftType: aircraftType,
+		Simulated:    true,
+		CurrentState: &rid.RIDAircraftState{
+			Timestamp:         utm.NewTime(time.UnixMilli(current.TimeMeasured).UTC()),
+			TimestampAccuracy: 0.1,
+			OperationalStatus: "Airborne",
+			Position:          aircraftPosition(current),
+			Track:             current.Heading,
+			Speed:             current.Speed,
+			SpeedAccuracy:     "SAUnknown",
+			VerticalSpeed:     current.VerticalSpeed,
+		},
+	}
+
+	inView := view.Contains(orb.Point{current.Longitude, current.Latitude})
+	since := now.Add(-rid.NetMaxNearRealTimeDataPeriod).UnixMilli()
+	recentSince := now.Add(-recent).UnixMilli()
+	for i := next - 2; i >= 0 && fl.telemetry[i].TimeMeasured >= since; i-- {
+		t := fl.telemetry[i]
+		inView = inView || view.Contains(orb.Point{t.Longitude, t.Latitude})
+		if t.TimeMeasured < recentSince {
+			continue
+		}
+		ridFlight.RecentPositions = append(ridFlight.RecentPositions, rid.RIDRecentAircraftPosition{
+			Time:     utm.NewTime(time.UnixMilli(t.TimeMeasured).UTC()),
+			Position: aircraftPosition(t),
+		})
+	}
+	return ridFlight, inView
+}
+
+func aircraftPosition(t uspace.Telemetry) rid.RIDAircraftPosition {
+	return rid.RIDAircraftPosition{
+		Lat:       t.Latitude,
+		Lng:       t.Longitude,
+		Alt:       t.Altitude,
+		AccuracyH: "HAUnknown",
+		AccuracyV: "VAUnknown",
+	}
+}
+
+// cluster groups <visible> by the cell of a clusterGrid by clusterGrid grid
+// over <view> that they are in, so that their positions are not given away.
+func cluster(view orb.Bound, visible []rid.RIDFlight) []rid.Cluster {
+	width := (view.Max.Lon() - view.Min.Lon()) / clusterGrid
+	height := (view.Max.Lat() - view.Min.Lat()) / clusterGrid
+
+	var counts [clusterGrid][clusterGrid]int
+	for _, ridFlight := range visible {
+		position := ridFlight.CurrentState.Position
+		counts[cellIndex(position.Lat, view.Min.Lat(), height)][cellIndex(position.Lng, view.Min.Lon(), width)]++
+	}
+
+	clusters := []rid.Cluster{}
+	for row := range clusterGrid {
+		for col := range clusterGrid {
+			if counts[row][col] == 0 {
+				continue
+			}
+			cell := orb.Bound{
+				Min: orb.Point{view.Min.Lon() + float64(col)*width, view.Min.Lat() + float64(row)*height},
+				Max: orb.Point{view.Min.Lon() + float64(col+1)*width, view.Min.Lat() + float64(row+1)*height},
+			}
+			clusters = append(clusters, rid.Cluster{
+				Corners: [2]utm.LatLngPoint{
+					{Lat: cell.Min.Lat(), Lng: cell.Min.Lon()},
+					{Lat: cell.Max.Lat(), Lng: cell.Max.Lon()},
+				},
+				AreaSqm:         geo.Area(cell.ToPolygon()),
+				NumberOfFlights: counts[row][col],
+			})
+		}
+	}
+	return clusters
+}
+
+// cellIndex is the index of the cell of <size> from <start> that <v> is in.
+func cellIndex(v float64, start float64, size float64) int {
+	if size == 0 {
+		return 0
+	}
+	return min(clusterGrid-1, max(0, int((v-start)/size)))
+}
+
+// details is the details of the flight <id>.
+func (f *flights) details(id string) (*rid.RIDFlightDetails, bool) {
+	fl, ok := f.byId[id]
+	if !ok {
+		return nil, false
+	}
+	details := &rid.RIDFlightDetails{
+		Id:                   id,
+		OperatorId:           fl.oiCnf.OwnerName,
+		OperationDescription: fl.oiCnf.Name,
+		UasId: &rid.UASID{
+			SerialNumber: serialNumber(fl.oiCnf.UavId),
+			UtmId:        id,
+		},
+	}
+	if len(fl.oiCnf.WaypointCoordinates) > 0 {
+		// the operator is at the take-off point, waypoints are [lat, lng]
+		takeOff := fl.oiCnf.WaypointCoordinates[0]
+		details.OperatorLocation = &rid.OperatorLocation{Position: utm.LatLngPoint{Lat: takeOff[0], Lng: takeOff[1]}}
+	}
+	return details, true
+}
+
+// serialNumber is the ANSI/CTA-2063-A serial number of the UAV <uavId>: a
+// manufacturer code, the length of the serial as a hex digit, then the serial.
+func serialNumber(uavId int) string {
+	serial := strconv.Itoa(uavId)
+	return fmt.Sprintf("MNNA%X%s", len(serial), serial)
+}
+
+// recentPositionsDuration parses the recent_positions_duration of a request,
+// in seconds. It is 0 when not given and at most NetMaxNearRealTimeDataPeriod.
+func recentPositionsDuration(param string) (time.Duration, error) {
+	if param == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, fmt.Errorf("recent_positions_duration must be a number of seconds: %w", err)
+	}
+	recent := time.Duration(seconds * float64(time.Second))
+	if recent < 0 || recent > rid.NetMaxNearRealTimeDataPeriod {
+		return 0, fmt.Errorf("recent_positions_duration must be between 0 and %.0f seconds", rid.NetMaxNearRealTimeDataPeriod.Seconds())
+	}
+	return recent, nil
+}
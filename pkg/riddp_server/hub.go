@@ -0,0 +1,192 @@
+package riddp_server
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// event is a server-sent event, identified by its position in the stream so
+// that reconnecting clients can resume after the last one they received.
+type event struct {
+	id uint64
+	// epoch is that of the hub that sent the event
+	epoch string
+	name  string
+	data  []byte
+	// payload is the value <data> is the JSON of, for the clients that
+	// filter the events. It is shared by every client, so it is not changed.
+	payload any
+}
+
+// eventId is the id of <e> sent to clients, <epoch>-<id>, so that the ids of
+// one run of the server are not mistaken for those of another.
+func (e event) eventId() string {
+	return e.epoch + "-" + strconv.FormatUint(e.id, 10)
+}
+
+// write writes <e> to <w> in the text/event-stream format.
+func (e event) write(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.eventId(), e.name, e.data)
+	return err
+}
+
+// subscriber is a client of a hub. <events> is closed when the client is
+// dropped for not keeping up.
+type subscriber struct {
+	events chan event
+}
+
+// hub broadcasts the events of a single shared simulation to every connected
+// client. Each client has a buffer of <bufferSize> events, a client whose
+// buffer is full is dropped rather than holding up the others, and resumes
+// from the last event it received when it reconnects.
+type hub struct {
+	lock        sync.Mutex
+	bufferSize  int
+	historySize int
+	// epoch identifies the run of the server that the hub is part of, as the
+	// ids of its events restart from 1 on every run
+	epoch string
+	// history is the most recent events, for the clients that resume
+	history     []event
+	nextId      uint64
+	subscribers map[*subscriber]struct{}
+	// dropped is the number of events that could not be queued for a
+	// subscriber, which was dropped instead
+	dropped uint64
+	// snapshot is the events that bring a new client up to date, it is
+	// called under the lock of the hub
+	snapshot func() []event
+}
+
+func newHub(bufferSize int, historySize int, snapshot func() []event) *hub {
+	return &hub{
+		bufferSize:  bufferSize,
+		historySize: historySize,
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		nextId:      1,
+		subscribers: map[*subscriber]struct{}{},
+		snapshot:    snapshot,
+	}
+}
+
+// publish runs <update> and sends the events it returns to every subscriber.
+// <update> runs under the lock of the hub, so that the change it makes to the
+// simulation is seen by a new subscriber either in its snapshot or as an
+// event, and never both.
+func (h *hub) publish(update func() []event) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, e := range update() {
+		e.id = h.nextId
+		e.epoch = h.epoch
+		h.nextId++
+		h.history = append(h.history, e)
+		if len(h.history) > h.historySize {
+			h.history = h.history[len(h.history)-h.historySize:]
+		}
+
+		for sub := range h.subscribers {
+			select {
+			case sub.events <- e:
+			default:
+				log.Warnf("dropping a client that is %d events behind", h.bufferSize)
+				h.dropped++
+				delete(h.subscribers, sub)
+				close(sub.events)
+			}
+		}
+	}
+}
+
+// subscribe adds a subscriber, returning it with the events it has to be sent
+// first: those after <lastEventId> if they are still in the history,
+// otherwise a snapshot of the simulation.
+func (h *hub) subscribe(lastEventId string) (*subscriber, []event) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	sub := &subscriber{events: make(chan event, h.bufferSize)}
+	h.subscribers[sub] = struct{}{}
+	if backlog, ok := h.since(lastEventId); ok {
+		return sub, backlog
+	}
+
+	backlog := h.snapshot()
+	for i := range backlog {
+		// snapshots take the id of the last event they include
+		backlog[i].id = h.nextId - 1
+		backlog[i].epoch = h.epoch
+	}
+	return sub, backlog
+}
+
+// since is the events after <lastEventId>, if it is an event of this hub and
+// none of the events after it have left the history.
+func (h *hub) since(lastEventId string) ([]event, bool) {
+	epoch, id, ok := strings.Cut(lastEventId, "-")
+	if !ok || epoch != h.epoch {
+		return nil, false
+	}
+	last, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || last >= h.nextId {
+		return nil, false
+	}
+	if last == h.nextId-1 {
+		return []event{}, true
+	}
+	if len(h.history) == 0 || h.history[0].id > last+1 {
+		return nil, false
+	}
+	return append([]event{}, h.history[last+1-h.history[0].id:]...), true
+}
+
+// unsubscribe removes <sub>, if it has not been dropped already.
+func (h *hub) unsubscribe(sub *subscriber) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// current is a snapshot of the simulation.
+func (h *hub) current() []event {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.snapshot()
+}
+
+// droppedCount is the number of events that could not be queued for a
+// subscriber.
+func (h *hub) droppedCount() uint64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.dropped
+}
+
+// queueDepth is the number of events queued for all of the subscribers, and
+// the most queued for any one of them.
+func (h *hub) queueDepth() (total int, most int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for sub := range h.subscribers {
+		total += len(sub.events)
+		most = max(most, len(sub.events))
+	}
+	return total, most
+}
+
+func (h *hub) subscriberCount() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.subscribers)
+}
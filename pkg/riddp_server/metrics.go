@@ -0,0 +1,90 @@
+package riddp_server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The transports the simulation is streamed over, the transport label of the
+// stream metrics.
+const (
+	transportSse       = "sse"
+	transportWebSocket = "websocket"
+)
+
+// simulationMetrics are the metrics of a simulation and its streams, served
+// at /metrics.
+type simulationMetrics struct {
+	registry          *prometheus.Registry
+	streamClients     *prometheus.GaugeVec
+	telemetryMessages *prometheus.CounterVec
+	streamWrites      *prometheus.HistogramVec
+}
+
+func newSimulationMetrics(h *hub) *simulationMetrics {
+	m := &simulationMetrics{
+		registry: prometheus.NewRegistry(),
+		streamClients: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "riddp_stream_clients",
+			Help: "The clients connected to the event streams, by transport.",
+		}, []string{"transport"}),
+		telemetryMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "riddp_telemetry_messages_total",
+			Help: "The positions of the aircraft published by the simulation, by mission id.",
+		}, []string{"mission_id"}),
+		streamWrites: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "riddp_stream_write_duration_seconds",
+			Help:    "The time taken to write an event to a stream client, by transport.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"transport"}),
+	}
+	m.registry.MustRegister(
+		m.streamClients,
+		m.telemetryMessages,
+		m.streamWrites,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "riddp_dropped_events_total",
+			Help: "The events that could not be queued for a stream client that was not keeping up, which was disconnected instead.",
+		}, func() float64 {
+			return float64(h.droppedCount())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "riddp_hub_queued_events",
+			Help: "The events queued for all of the stream clients.",
+		}, func() float64 {
+			total, _ := h.queueDepth()
+			return float64(total)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "riddp_hub_max_queued_events",
+			Help: "The most events queued for any one stream client.",
+		}, func() float64 {
+			_, most := h.queueDepth()
+			return float64(most)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "riddp_hub_buffer_size",
+			Help: "The events that can be queued for a stream client before it is disconnected.",
+		}, func() float64 {
+			return float64(h.bufferSize)
+		}),
+	)
+	return m
+}
+
+// timeWrite runs <write> of an event to a client over <transport>, observing
+// how long it took.
+func (m *simulationMetrics) timeWrite(transport string, write func() error) error {
+	start := time.Now()
+	err := write()
+	m.streamWrites.WithLabelValues(transport).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// handler serves the metrics in the Prometheus exposition format.
+func (m *simulationMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,82 @@
+package riddp_server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"manna.aero/manna.utm.cli/model/rid"
+)
+
+// originPolicy is the browser origins allowed to call the server, in addition
+// to the server's own.
+type originPolicy struct {
+	any     bool
+	allowed map[string]bool
+}
+
+func newOriginPolicy(origins []string) originPolicy {
+	p := originPolicy{allowed: map[string]bool{}}
+	for _, origin := range origins {
+		if origin == "*" {
+			p.any = true
+		}
+		p.allowed[origin] = true
+	}
+	return p
+}
+
+// allows is whether <origin> may call the server with <req>.
+func (p originPolicy) allows(origin string, req *http.Request) bool {
+	if p.any || p.allowed[origin] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == req.Host
+}
+
+// cors lets the allowed origins read the responses of the server, answering
+// their preflight requests.
+func cors(p originPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+		allowed := p.allows(origin, c.Request)
+		if allowed {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			if allowed {
+				c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+				c.Writer.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Last-Event-ID")
+				c.Writer.Header().Set("Access-Control-Max-Age", strconv.Itoa(int((10 * time.Minute).Seconds())))
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// limitRequestBytes rejects requests with bodies over <limit> bytes.
+func limitRequestBytes(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, rid.ErrorResponse{
+				Message: fmt.Sprintf("request bodies are limited to %d bytes", limit),
+			})
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
@@ -1,19 +1,97 @@
 package riddp_server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/model/rid"
 	"manna.aero/manna.utm.cli/pkg/config"
 )
 
-func GetServer(appConfig config.Config, writeRequests bool) *gin.Engine {
+const (
+	// sseKeepAliveInterval is how often a comment is sent to idle SSE clients.
+	sseKeepAliveInterval = 15 * time.Second
+	// defaultMaxRequestBytes bounds the size of request bodies when
+	// max_request_bytes is not set.
+	defaultMaxRequestBytes = 1 << 20
+)
+
+// defaultAllowedOrigins are the browser origins allowed to call the server
+// when allowed_origins is not set: the map frontend run alongside it.
+var defaultAllowedOrigins = []string{"http://localhost:3001"}
+
+// Options configures the Remote ID server.
+type Options struct {
+	// WriteRequests writes the GeoJSON first sent to each client of
+	// /features/events to .requests.
+	WriteRequests bool
+	// Departure is when the configured operational intents depart, and their
+	// Remote ID flights take off.
+	Departure time.Time
+	// TrailLength is the number of positions of each aircraft sent to new SSE
+	// clients, DefaultTrailLength if it is not set.
+	TrailLength int
+}
+
+// Server is the Remote ID server of the operational intents of a config, and
+// the simulation of their flights that it streams.
+type Server struct {
+	*gin.Engine
+	sim           *simulation
+	writeRequests bool
+	// stopped is closed when the simulation has stopped running
+	stopped chan struct{}
+}
+
+// GetServer is the Remote ID server of the operational intents of
+// <appConfig>, with the CORS and request limits of its rid_dp_server.
+func GetServer(appConfig config.Config, opts Options) *Server {
+	srvCnf := appConfig.RidDpServer
+	allowedOrigins := srvCnf.AllowedOrigins
+	if len(allowedOrigins) == 0 {
+		allowedOrigins = defaultAllowedOrigins
+	}
+	origins := newOriginPolicy(allowedOrigins)
+	maxRequestBytes := srvCnf.MaxRequestBytes
+	if maxRequestBytes == 0 {
+		maxRequestBytes = defaultMaxRequestBytes
+	}
+
 	router := gin.Default()
+	router.Use(cors(origins), limitRequestBytes(maxRequestBytes))
+	ridFlights := newFlights(appConfig.OperationalIntentConfigs, opts.Departure)
+	// a single simulation of the flights, shared by every SSE client
+	sim := newSimulation(opts.TrailLength)
+	srv := &Server{Engine: router, sim: sim, writeRequests: opts.WriteRequests, stopped: make(chan struct{})}
+	go func() {
+		defer close(srv.stopped)
+		sim.run(ridFlights)
+	}()
+
+	// ready is distinct from health, so that tests wait for the simulation
+	// rather than the process
+	router.GET("/ready", func(c *gin.Context) {
+		select {
+		case <-sim.done:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"ready": false, "message": "the server is shutting down."})
+			return
+		default:
+		}
+		if !sim.initialised.Load() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"ready": false, "message": "the simulation is being initialised."})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ready": true})
+	})
+
+	router.GET("/metrics", gin.WrapH(sim.metrics.handler()))
+
+	// a map of the flights, so that a scenario can be seen without a frontend
+	router.GET("/", serveViewer)
 
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -31,91 +109,88 @@ func GetServer(appConfig config.Config, writeRequests bool) *gin.Engine {
 		c.Data(http.StatusOK, "application/json", data)
 	})
 
-	router.GET("/features/events", func(c *gin.Context) {
-		// CORS for SSE (adjust origin as needed)
-		c.Writer.Header().Set("Access-Control-Allow-Origin", fmt.Sprintf("http://localhost:3001"))
-		// If you use cookies/auth:
-		// c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		c.Writer.Header().Set("Content-Type", "text/event-stream")
-		c.Writer.Header().Set("Cache-Control", "no-cache")
-		c.Writer.Header().Set("Connection", "keep-alive")
-		c.Writer.Header().Set("X-Accel-Buffering", "no")
+	// the ASTM F3411-22a display provider API, see
+	// https://github.com/uastech/standards/blob/astm_rid_api_2.1/remoteid/canonical.yaml
+	router.GET("/flights", func(c *gin.Context) {
+		view, err := parseView(c.Query("view"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, rid.ErrorResponse{Message: err.Error()})
+			return
+		}
+		resp, err := ridFlights.inView(view, time.Now())
+		if err != nil {
+			c.JSON(http.StatusRequestEntityTooLarge, rid.ErrorResponse{Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	})
 
-		flusher, ok := c.Writer.(http.Flusher)
+	router.GET("/flights/:id/details", func(c *gin.Context) {
+		details, ok := ridFlights.details(c.Param("id"))
 		if !ok {
-			c.AbortWithStatusJSON(500, gin.H{"error": "streaming unsupported"})
+			c.JSON(http.StatusNotFound, rid.ErrorResponse{Message: fmt.Sprintf("no flight is known with id %s", c.Param("id"))})
 			return
 		}
+		c.JSON(http.StatusOK, rid.GetFlightDetailsResponse{Details: *details})
+	})
 
-		// initial write helps some setups
-		_, _ = c.Writer.WriteString(": connected\n\n")
-		flusher.Flush()
-
-		// Optional: tell the client how long to wait before auto-reconnect (ms)
-		_, _ = c.Writer.WriteString("retry: 3000\n\n")
-		flusher.Flush()
-
-		ticker := time.NewTicker(500 * time.Millisecond)
-		defer ticker.Stop()
-
-		log.Infof("operning sse connection")
-
-		operationalIntentGeoJsonFc := appConfig.ToGeoJson()
-		operationalIntentGeoJsonFcBytes, err := json.Marshal(operationalIntentGeoJsonFc)
+	// the ASTM F3411-22a Service Provider API, which Display Providers fetch
+	// the flights in the identification service areas of the server from
+	router.GET("/uss/flights", func(c *gin.Context) {
+		view, err := parseView(c.Query("view"))
 		if err != nil {
-			log.Fatalf("error occurred marshalling config to GeoJson %v", err)
+			c.JSON(http.StatusBadRequest, rid.ErrorResponse{Message: err.Error()})
+			return
 		}
-
-		if writeRequests == true {
-			outFileName := ".requests/riddp_init.geojson"
-			err := os.WriteFile(outFileName, operationalIntentGeoJsonFcBytes, os.ModePerm)
-			if err != nil {
-				log.Errorf("unable to GeoJSON data to " + outFileName)
-			}
+		recent, err := recentPositionsDuration(c.Query("recent_positions_duration"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, rid.ErrorResponse{Message: err.Error()})
+			return
+		}
+		resp, err := ridFlights.provided(view, time.Now(), recent)
+		if err != nil {
+			c.JSON(http.StatusRequestEntityTooLarge, rid.ErrorResponse{Message: err.Error()})
+			return
 		}
+		c.JSON(http.StatusOK, resp)
+	})
 
-		// create a telemetry bus, that we can listen to for the configured flights
-		telemetryBus := StartTelemetryProducersForAllOperationalIntents(appConfig, 1000)
+	router.GET("/uss/flights/:id/details", func(c *gin.Context) {
+		details, ok := ridFlights.details(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, rid.ErrorResponse{Message: fmt.Sprintf("no flight is known with id %s", c.Param("id"))})
+			return
+		}
+		c.JSON(http.StatusOK, rid.GetFlightDetailsResponse{Details: *details})
+	})
 
-		for {
-			select {
-			case <-ticker.C: // if we receive a ticker message in the channel.
-				log.Debugf("responding to client with operationalIntentGeoJsonFcBytes")
-				// Send event. Each SSE event is delimited by a blank line.
-				// Note: "data:" supports multi-line; JSON is usually single-line.
-				_, err := fmt.Fprintf(c.Writer, "event: features\ndata: %s\n\n", operationalIntentGeoJsonFcBytes)
-				if err != nil {
-					log.Errorf("error writing event features to client: %v", err)
-					return
-				}
-				flusher.Flush()
-				break
-			case telemetryMessage, ok := <-telemetryBus.TelemetryEvents:
-				if !ok {
-					log.Warnf("telemetry channel closed")
-					return
-				}
-				log.Tracef("telemetry message received from mission: %s", telemetryMessage.GetMissionId())
+	router.GET("/features/events", sim.serveEvents(opts.WriteRequests))
+	router.GET("/ws", sim.serveWebSocket(origins))
 
-				// convert it to geojson.
-				operationalIntentGeoJsonFc.Append(telemetryMessage.ToGeoJsonFeature())
-				operationalIntentGeoJsonFcBytes, err = json.Marshal(operationalIntentGeoJsonFc)
-				if err != nil {
-					log.Fatalf("error occurred marshalling config to GeoJson %v", err)
-				}
+	return srv
+}
 
-				_, err := fmt.Fprintf(c.Writer, "event: features\ndata: %s\n\n", operationalIntentGeoJsonFcBytes)
+// Shutdown stops the simulation and ends the event streams of its clients.
+// With WriteRequests, the last snapshot of the simulation is written to
+// .requests.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.sim.shutdown()
+	select {
+	case <-s.stopped:
+	case <-ctx.Done():
+		return fmt.Errorf("error occurred stopping the simulation: %w", ctx.Err())
+	}
+
+	if s.writeRequests {
+		for _, e := range s.sim.hub.current() {
+			if snapshot, ok := e.payload.(snapshotData); ok {
+				data, err := json.Marshal(snapshot.Features)
 				if err != nil {
-					log.Errorf("error writing event features to client: %v", err)
-					return
+					return fmt.Errorf("error occurred marshalling the last snapshot: %w", err)
 				}
-				flusher.Flush()
-				// write it to the client channel.
-				break
+				writeDump("riddp_final.geojson", data)
 			}
 		}
-	})
-
-	return router
+	}
+	return nil
 }
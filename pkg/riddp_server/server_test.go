@@ -0,0 +1,479 @@
+package riddp_server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+	"manna.aero/manna.utm.cli/model/rid"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/dss_emulator_server"
+	"manna.aero/manna.utm.cli/pkg/rid_client"
+	"manna.aero/manna.utm.cli/pkg/transport"
+)
+
+var testOiCnf = config.OperationalIntentConfig{
+	Name:                "A",
+	OwnerName:           "uss.example.com",
+	MissionId:           uuid.New(),
+	UavId:               42,
+	Duration:            60 * time.Second,
+	WaypointCoordinates: [][2]float64{{46.19128, 6.12335}, {46.19165, 6.12464}, {46.19205, 6.12571}},
+}
+
+func TestFlightsInView(t *testing.T) {
+	now := time.Now()
+	f := newFlights([]config.OperationalIntentConfig{testOiCnf}, now.Add(-10*time.Second))
+
+	view, err := parseView("46.185,6.115,46.195,6.130")
+	require.NoError(t, err)
+	resp, err := f.inView(view, now)
+	require.NoError(t, err)
+	require.Len(t, resp.Flights, 1)
+	assert.Empty(t, resp.Clusters)
+	flight := resp.Flights[0]
+	assert.Equal(t, testOiCnf.MissionId.String(), flight.Id)
+	assert.Equal(t, rid.AircraftTypeOther, flight.AircraftType, "the aircraft type is not configured")
+	require.NotNil(t, flight.CurrentState)
+	assert.InDelta(t, 46.19, flight.CurrentState.Position.Lat, 0.01)
+	assert.InDelta(t, 6.12, flight.CurrentState.Position.Lng, 0.01)
+	assert.NotEmpty(t, flight.RecentPositions)
+	for _, p := range flight.RecentPositions {
+		assert.True(t, p.Time.Value.Before(flight.CurrentState.Timestamp.Value))
+	}
+
+	// elsewhere
+	view, err = parseView("47.0,7.0,47.01,7.01")
+	require.NoError(t, err)
+	resp, err = f.inView(view, now)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Flights)
+
+	// a flight with a configured aircraft type
+	helicopter := testOiCnf
+	helicopter.AircraftType = "Helicopter"
+	f = newFlights([]config.OperationalIntentConfig{helicopter}, now.Add(-10*time.Second))
+	view, err = parseView("46.185,6.115,46.195,6.130")
+	require.NoError(t, err)
+	resp, err = f.inView(view, now)
+	require.NoError(t, err)
+	require.Len(t, resp.Flights, 1)
+	assert.Equal(t, "Helicopter", resp.Flights[0].AircraftType)
+
+	// not departed yet, and landed
+	view, err = parseView("46.185,6.115,46.195,6.130")
+	require.NoError(t, err)
+	for _, at := range []time.Time{now.Add(-time.Minute), now.Add(2 * time.Minute)} {
+		resp, err = f.inView(view, at)
+		require.NoError(t, err)
+		assert.Empty(t, resp.Flights)
+	}
+
+	// wide views get clusters
+	view, err = parseView("46.17,6.10,46.21,6.15")
+	require.NoError(t, err)
+	resp, err = f.inView(view, now)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Flights)
+	require.Len(t, resp.Clusters, 1)
+	assert.Equal(t, 1, resp.Clusters[0].NumberOfFlights)
+	assert.Greater(t, resp.Clusters[0].AreaSqm, 0.0)
+
+	// too wide
+	view, err = parseView("46.0,6.0,46.2,6.2")
+	require.NoError(t, err)
+	_, err = f.inView(view, now)
+	assert.ErrorAs(t, err, new(*viewTooLargeError))
+}
+
+func TestFlightsEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(GetServer(config.Config{OperationalIntentConfigs: []config.OperationalIntentConfig{testOiCnf}}, Options{Departure: time.Now()}))
+	defer srv.Close()
+
+	for view, status := range map[string]int{
+		"46.185,6.115,46.195,6.130": http.StatusOK,
+		"46.185,6.115,46.195":       http.StatusBadRequest,
+		"91,6.115,46.195,6.130":     http.StatusBadRequest,
+		"46.0,6.0,46.2,6.2":         http.StatusRequestEntityTooLarge,
+	} {
+		resp, err := http.Get(srv.URL + "/flights?view=" + view)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, status, resp.StatusCode, view)
+	}
+
+	resp, err := http.Get(srv.URL + "/")
+	require.NoError(t, err)
+	page, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(page), `new EventSource("features/events")`)
+
+	resp, err = http.Get(srv.URL + "/flights/" + testOiCnf.MissionId.String() + "/details")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var details rid.GetFlightDetailsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&details))
+	assert.Equal(t, "uss.example.com", details.Details.OperatorId)
+	assert.Equal(t, "MNNA242", details.Details.UasId.SerialNumber)
+	assert.InDelta(t, 46.19128, details.Details.OperatorLocation.Position.Lat, 1e-9)
+
+	resp, err = http.Get(srv.URL + "/flights/" + uuid.NewString() + "/details")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServiceProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	departure := time.Now().Add(-10 * time.Second)
+	sp := httptest.NewServer(GetServer(config.Config{OperationalIntentConfigs: []config.OperationalIntentConfig{testOiCnf}}, Options{Departure: departure}))
+	defer sp.Close()
+	dss := httptest.NewServer(dss_emulator_server.GetServer(nil, dss_emulator_server.Options{}))
+	defer dss.Close()
+
+	// a Display Provider subscribed to the area of the flight
+	notifications := make(chan rid.PutIdentificationServiceAreaNotificationParameters, 1)
+	dp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/uss/identification_service_areas/"+testOiCnf.MissionId.String(), r.URL.Path)
+		var params rid.PutIdentificationServiceAreaNotificationParameters
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+		notifications <- params
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer dp.Close()
+	extents := identificationServiceAreaExtents(utm.OperationalIntentFromConfigAt(&testOiCnf, departure).Details.Volumes)
+	sub, err := json.Marshal(rid.CreateSubscriptionParameters{Extents: extents, UssBaseUrl: dp.URL})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPut, dss.URL+"/rid/v2/dss/subscriptions/"+uuid.NewString(), bytes.NewReader(sub))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	dssClient, err := rid_client.NewRidClient(dss.URL+"/rid/v2", nil, transport.DefaultPolicy())
+	require.NoError(t, err)
+	isas, err := PublishIdentificationServiceAreas(context.Background(), dssClient, []config.OperationalIntentConfig{testOiCnf}, departure, sp.URL)
+	require.NoError(t, err)
+	require.Len(t, isas, 1)
+	assert.Equal(t, testOiCnf.MissionId.String(), isas[0].Id)
+	assert.Equal(t, sp.URL, isas[0].UssBaseUrl)
+
+	notification := <-notifications
+	require.NotNil(t, notification.ServiceArea)
+	assert.Equal(t, isas[0].Id, notification.ServiceArea.Id)
+	require.Len(t, notification.Subscriptions, 1)
+	assert.Equal(t, 1, notification.Subscriptions[0].NotificationIndex)
+
+	// the Display Provider fetches the flight from the uss_base_url of the ISA
+	spClient, err := rid_client.NewRidClient(isas[0].UssBaseUrl, nil, transport.DefaultPolicy())
+	require.NoError(t, err)
+	flights, err := spClient.GetFlights(context.Background(), "46.185,6.115,46.195,6.130", 5)
+	require.NoError(t, err)
+	require.Len(t, flights.Flights, 1)
+	for _, p := range flights.Flights[0].RecentPositions {
+		assert.WithinDuration(t, time.Now(), p.Time.Value, 6*time.Second)
+	}
+	details, err := spClient.GetFlightDetails(context.Background(), testOiCnf.MissionId.String())
+	require.NoError(t, err)
+	assert.Equal(t, "A", details.OperationDescription)
+
+	// publishing it again, as after a restart, updates the area left behind
+	again, err := PublishIdentificationServiceAreas(context.Background(), dssClient, []config.OperationalIntentConfig{testOiCnf}, departure.Add(time.Minute), sp.URL)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, isas[0].Id, again[0].Id)
+	assert.NotEqual(t, isas[0].Version, again[0].Version)
+	notification = <-notifications
+	assert.Equal(t, 2, notification.Subscriptions[0].NotificationIndex)
+
+	require.NoError(t, WithdrawIdentificationServiceAreas(context.Background(), dssClient, again))
+	<-notifications
+	_, err = dssClient.GetIdentificationServiceArea(context.Background(), isas[0].Id)
+	var clientErr *transport.ClientError
+	require.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+}
+
+func TestHub(t *testing.T) {
+	snapshots := 0
+	h := newHub(2, 3, func() []event {
+		snapshots++
+		return []event{{name: "features", data: []byte("snapshot")}}
+	})
+	publish := func(data string) {
+		h.publish(func() []event { return []event{{name: "features", data: []byte(data)}} })
+	}
+
+	// new clients get a snapshot, then the events after it
+	sub, backlog := h.subscribe("")
+	require.Len(t, backlog, 1)
+	assert.Equal(t, uint64(0), backlog[0].id)
+	publish("1")
+	e := <-sub.events
+	assert.Equal(t, uint64(1), e.id)
+	assert.Equal(t, "1", string(e.data))
+
+	// a client that does not keep up is dropped
+	publish("2")
+	publish("3")
+	publish("4")
+	for range sub.events {
+	}
+	assert.Equal(t, 0, h.subscriberCount())
+
+	// and resumes from the last event it got
+	sub, backlog = h.subscribe(h.epoch + "-2")
+	require.Len(t, backlog, 2)
+	assert.Equal(t, h.epoch+"-3", backlog[0].eventId())
+	assert.Equal(t, h.epoch+"-4", backlog[1].eventId())
+	h.unsubscribe(sub)
+	_, backlog = h.subscribe(h.epoch + "-4")
+	assert.Empty(t, backlog)
+
+	// unless it is too far behind, or the id is not of this hub
+	snapshots = 0
+	_, backlog = h.subscribe(h.epoch + "-0")
+	assert.Equal(t, 1, snapshots)
+	assert.Equal(t, h.epoch+"-4", backlog[0].eventId())
+	_, _ = h.subscribe(h.epoch + "-99")
+	assert.Equal(t, 2, snapshots)
+	_, _ = h.subscribe("2")
+	assert.Equal(t, 3, snapshots)
+
+	// such as an id of an earlier run of the server, whose ids restarted
+	// from 1
+	earlier := h.epoch
+	h = newHub(2, 3, h.snapshot)
+	h.epoch = earlier + "0"
+	publish("1")
+	publish("2")
+	_, backlog = h.subscribe(earlier + "-1")
+	assert.Equal(t, 4, snapshots)
+	assert.Equal(t, h.epoch+"-2", backlog[0].eventId())
+}
+
+func TestFeatureEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	oiCnf := testOiCnf
+	oiCnf.Duration = 2 * time.Second
+	sim := newSimulation(2)
+	// departing once the client is connected
+	go sim.run(newFlights([]config.OperationalIntentConfig{oiCnf}, time.Now().Add(500*time.Millisecond)))
+	router := gin.New()
+	router.GET("/features/events", sim.serveEvents(false))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/features/events", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// the snapshot, then the departure and the positions as they are measured
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	var names []string
+	var position positionData
+	for len(names) < 4 && scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			names = append(names, name)
+		}
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok && len(names) == 3 {
+			require.NoError(t, json.Unmarshal([]byte(data), &position))
+		}
+	}
+	assert.Equal(t, []string{eventSnapshot, eventOiCreated, eventPosition, eventPosition}, names)
+	assert.Equal(t, oiCnf.MissionId.String(), position.MissionId)
+	assert.Equal(t, "Point", position.Feature.Geometry.GeoJSONType())
+
+	exposition := httptest.NewRecorder()
+	sim.metrics.handler().ServeHTTP(exposition, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, exposition.Code)
+	assert.Contains(t, exposition.Body.String(), `riddp_stream_clients{transport="sse"} 1`+"\n")
+	assert.Contains(t, exposition.Body.String(), `riddp_telemetry_messages_total{mission_id="`+oiCnf.MissionId.String()+`"}`)
+	assert.Contains(t, exposition.Body.String(), `riddp_stream_write_duration_seconds_count{transport="sse"}`)
+	assert.Contains(t, exposition.Body.String(), "riddp_dropped_events_total 0\n")
+
+	// the client goroutine exits when the client disconnects
+	cancel()
+	assert.Eventually(t, func() bool {
+		return sim.hub.subscriberCount() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// new clients get the last positions of the trail of each aircraft
+	_, backlog := sim.hub.subscribe("")
+	require.Len(t, backlog, 1)
+	var snapshot snapshotData
+	require.NoError(t, json.Unmarshal(backlog[0].data, &snapshot))
+	assert.Equal(t, 2, snapshot.TrailLength)
+	positions := 0
+	for _, f := range snapshot.Features.Features {
+		if f.Properties["layer"] == layerTelemetry {
+			positions++
+		}
+	}
+	assert.Equal(t, 2, positions)
+}
+
+func TestWebSocket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a := testOiCnf
+	a.Duration = 2 * time.Second
+	b := a
+	b.Name = "B"
+	b.MissionId = uuid.New()
+	b.WaypointCoordinates = [][2]float64{{47.37, 8.54}, {47.38, 8.55}}
+	sim := newSimulation(0)
+	go sim.run(newFlights([]config.OperationalIntentConfig{a, b}, time.Now().Add(500*time.Millisecond)))
+	router := gin.New()
+	router.GET("/ws", sim.serveWebSocket(newOriginPolicy(defaultAllowedOrigins)))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	wsUrl := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	_, err := websocket.Dial(wsUrl, "", "http://evil.example.com")
+	assert.Error(t, err)
+
+	ws, err := websocket.Dial(wsUrl, "", defaultAllowedOrigins[0])
+	require.NoError(t, err)
+	defer ws.Close()
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	type message struct {
+		Type    string          `json:"type"`
+		Data    json.RawMessage `json:"data"`
+		Message string          `json:"message"`
+	}
+	receive := func() message {
+		var msg message
+		require.NoError(t, websocket.JSON.Receive(ws, &msg))
+		return msg
+	}
+
+	require.NoError(t, websocket.JSON.Send(ws, subscribeMessage{Type: "subscribe", Layers: []string{"trajectories"}}))
+	assert.Equal(t, "error", receive().Type)
+
+	// only the waypoints and positions of A
+	require.NoError(t, websocket.JSON.Send(ws, subscribeMessage{
+		Type:       "subscribe",
+		MissionIds: []string{a.MissionId.String()},
+		Layers:     []string{layerWaypoints, layerTelemetry},
+	}))
+	assert.Equal(t, eventSnapshot, receive().Type)
+	msg := receive()
+	require.Equal(t, eventOiCreated, msg.Type)
+	var created oiCreatedData
+	require.NoError(t, json.Unmarshal(msg.Data, &created))
+	assert.Equal(t, a.MissionId.String(), created.MissionId)
+	require.Len(t, created.Features.Features, len(a.WaypointCoordinates))
+	for _, f := range created.Features.Features {
+		assert.Equal(t, layerWaypoints, f.Properties["layer"])
+	}
+	msg = receive()
+	require.Equal(t, eventPosition, msg.Type)
+	var position positionData
+	require.NoError(t, json.Unmarshal(msg.Data, &position))
+	assert.Equal(t, a.MissionId.String(), position.MissionId)
+
+	// panning to B replaces the filter, with a snapshot of what is in view
+	require.NoError(t, websocket.JSON.Send(ws, subscribeMessage{Type: "subscribe", Bbox: []float64{8.5, 47.3, 8.6, 47.4}}))
+	for msg = receive(); msg.Type != eventSnapshot; msg = receive() {
+	}
+	var snapshot snapshotData
+	require.NoError(t, json.Unmarshal(msg.Data, &snapshot))
+	require.NotEmpty(t, snapshot.Features.Features)
+	for _, f := range snapshot.Features.Features {
+		assert.Equal(t, b.MissionId.String(), f.Properties["mission_id"])
+	}
+}
+
+func TestCorsAndRequestLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(GetServer(config.Config{RidDpServer: config.ServerConfig{
+		AllowedOrigins:  []string{"https://map.example.com"},
+		MaxRequestBytes: 16,
+	}}, Options{Departure: time.Now()}))
+	defer srv.Close()
+
+	get := func(method string, origin string, body string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+"/health", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get(http.MethodGet, "https://map.example.com", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "https://map.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// the default origin is replaced, but the server's own origin is allowed
+	assert.Empty(t, get(http.MethodGet, "http://localhost:3001", "").Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, srv.URL, get(http.MethodGet, srv.URL, "").Header.Get("Access-Control-Allow-Origin"))
+
+	resp = get(http.MethodOptions, "https://map.example.com", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "Last-Event-ID")
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, get(http.MethodPost, "", strings.Repeat("x", 17)).StatusCode)
+}
+
+func TestReadyAndShutdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ridServer := GetServer(config.Config{OperationalIntentConfigs: []config.OperationalIntentConfig{testOiCnf}}, Options{Departure: time.Now()})
+	srv := httptest.NewServer(ridServer)
+	defer srv.Close()
+
+	ready := func() int {
+		resp, err := http.Get(srv.URL + "/ready")
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Eventually(t, func() bool { return ready() == http.StatusOK }, time.Second, 10*time.Millisecond)
+
+	metricsResp, err := http.Get(srv.URL + "/metrics")
+	require.NoError(t, err)
+	exposition, err := io.ReadAll(metricsResp.Body)
+	metricsResp.Body.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(exposition), "# TYPE riddp_hub_queued_events gauge\n")
+
+	resp, err := http.Get(srv.URL + "/features/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Eventually(t, func() bool { return ridServer.sim.hub.subscriberCount() == 1 }, time.Second, 10*time.Millisecond)
+
+	// the streams end, so that the server can drain its requests
+	require.NoError(t, ridServer.Shutdown(context.Background()))
+	_, err = io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ridServer.sim.hub.subscriberCount())
+	assert.Equal(t, http.StatusServiceUnavailable, ready())
+}
@@ -0,0 +1,143 @@
+package riddp_server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/paulmach/orb"
+	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/model/rid"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/rid_client"
+	"manna.aero/manna.utm.cli/pkg/transport"
+)
+
+// PublishIdentificationServiceAreas creates an Identification Service Area in
+// the DSS of <dss> for each of <oiCnfs>, departing at <departure>, covering
+// the bounds of the operational intent, so that Display Providers fetch its
+// flight from <ussBaseUrl>. The area of an operational intent is identified by
+// its mission id, so an area left in the DSS by an earlier run, e.g. one that
+// crashed, is updated rather than created. The subscribers to each area are
+// notified of it.
+func PublishIdentificationServiceAreas(ctx context.Context, dss *rid_client.RidClient, oiCnfs []config.OperationalIntentConfig, departure time.Time, ussBaseUrl string) ([]rid.IdentificationServiceArea, error) {
+	var isas []rid.IdentificationServiceArea
+	for _, oiCnf := range oiCnfs {
+		id := oiCnf.MissionId.String()
+		version, err := existingVersion(ctx, dss, id)
+		if err != nil {
+			return isas, fmt.Errorf("error occurred fetching the identification service area of operational intent %s: %w", oiCnf.Name, err)
+		}
+
+		extents := identificationServiceAreaExtents(utm.OperationalIntentFromConfigAt(&oiCnf, departure).Details.Volumes)
+		resp, err := dss.PutIdentificationServiceArea(ctx, id, version, rid.CreateIdentificationServiceAreaParameters{
+			Extents:    extents,
+			UssBaseUrl: ussBaseUrl,
+		})
+		if err != nil {
+			return isas, fmt.Errorf("error occurred putting the identification service area of operational intent %s: %w", oiCnf.Name, err)
+		}
+		if version == "" {
+			log.Infof("created identification service area %s (version=%s) for operational intent %s", resp.ServiceArea.Id, resp.ServiceArea.Version, oiCnf.Name)
+		} else {
+			log.Infof("updated existing identification service area %s (version=%s) for operational intent %s", resp.ServiceArea.Id, resp.ServiceArea.Version, oiCnf.Name)
+		}
+		notifySubscribers(ctx, resp.ServiceArea, &extents, resp.Subscribers)
+		isas = append(isas, resp.ServiceArea)
+	}
+	return isas, nil
+}
+
+// existingVersion is the version of the Identification Service Area <id> in
+// the DSS of <dss>, or empty if there is none.
+func existingVersion(ctx context.Context, dss *rid_client.RidClient, id string) (string, error) {
+	isa, err := dss.GetIdentificationServiceArea(ctx, id)
+	var clientErr *transport.ClientError
+	if errors.As(err, &clientErr) && clientErr.StatusCode == http.StatusNotFound {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return isa.Version, nil
+}
+
+// WithdrawIdentificationServiceAreas deletes <isas> from the DSS of <dss>, so
+// that Display Providers stop fetching their flights, and notifies the
+// subscribers to each area that it was removed.
+func WithdrawIdentificationServiceAreas(ctx context.Context, dss *rid_client.RidClient, isas []rid.IdentificationServiceArea) error {
+	var errs []error
+	for _, isa := range isas {
+		resp, err := dss.DeleteIdentificationServiceArea(ctx, isa.Id, isa.Version)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error occurred deleting identification service area %s: %w", isa.Id, err))
+			continue
+		}
+		log.Infof("deleted identification service area %s", isa.Id)
+		notifySubscribers(ctx, resp.ServiceArea, nil, resp.Subscribers)
+	}
+	return errors.Join(errs...)
+}
+
+// identificationServiceAreaExtents is the volume that bounds <vols>, over
+// their altitudes and the whole of their time.
+func identificationServiceAreaExtents(vols []utm.Volume4d) utm.Volume4d {
+	var extents utm.Volume4d
+	var bound orb.Bound
+	for i, vol := range vols {
+		if i == 0 {
+			bound = vol.Volume.Outline().Bound()
+			extents = utm.Volume4d{
+				TimeStart: vol.TimeStart,
+				TimeEnd:   vol.TimeEnd,
+				Volume:    utm.Volume3d{AltitudeLower: vol.Volume.AltitudeLower, AltitudeUpper: vol.Volume.AltitudeUpper},
+			}
+			continue
+		}
+		bound = bound.Union(vol.Volume.Outline().Bound())
+		extents.TimeStart = minTime(extents.TimeStart, vol.TimeStart)
+		extents.TimeEnd = maxTime(extents.TimeEnd, vol.TimeEnd)
+		extents.Volume.AltitudeLower = min(extents.Volume.AltitudeLower, vol.Volume.AltitudeLower)
+		extents.Volume.AltitudeUpper = max(extents.Volume.AltitudeUpper, vol.Volume.AltitudeUpper)
+	}
+	extents.Volume.OutlinePolygon = bound.ToPolygon()
+	return extents
+}
+
+func minTime(a time.Time, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// notifySubscribers notifies <subscribers> that <isa> changed to <extents>, or
+// was removed if <extents> is nil. Failures are logged, as the change has
+// already been made in the DSS.
+func notifySubscribers(ctx context.Context, isa rid.IdentificationServiceArea, extents *utm.Volume4d, subscribers []rid.SubscriberToNotify) {
+	params := rid.PutIdentificationServiceAreaNotificationParameters{Extents: extents}
+	if extents != nil {
+		params.ServiceArea = &isa
+	}
+	for _, subscriber := range subscribers {
+		params.Subscriptions = subscriber.Subscriptions
+		client, err := rid_client.NewRidClient(subscriber.Url, nil, transport.DefaultPolicy())
+		if err == nil {
+			err = client.NotifyIdentificationServiceArea(ctx, isa.Id, params)
+		}
+		if err != nil {
+			log.Warnf("could not notify %s of identification service area %s: %v", subscriber.Url, isa.Id, err)
+			continue
+		}
+		log.Infof("notified %s of identification service area %s", subscriber.Url, isa.Id)
+	}
+}
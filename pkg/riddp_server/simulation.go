@@ -0,0 +1,323 @@
+package riddp_server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/pkg/config"
+)
+
+const (
+	// sseBufferSize is the number of events buffered for each SSE client
+	// before it is dropped for not keeping up.
+	sseBufferSize = 256
+	// sseHistorySize is the number of events kept for SSE clients that
+	// resume.
+	sseHistorySize = 1024
+	// DefaultTrailLength is the number of positions of each aircraft kept in
+	// the snapshots of the simulation when no trail length is set.
+	DefaultTrailLength = 100
+)
+
+// The events of the simulation. A client starts from a snapshot and applies
+// the other events to it, dropping the oldest positions of an aircraft beyond
+// the trail length of the snapshot.
+const (
+	eventSnapshot  = "snapshot"
+	eventOiCreated = "oi-created"
+	eventPosition  = "position"
+	eventOiEnded   = "oi-ended"
+)
+
+// The layers of the features of the simulation, set as the "layer" property
+// of each feature.
+const (
+	layerVolumes   = "volumes"
+	layerWaypoints = "waypoints"
+	layerTelemetry = "telemetry"
+)
+
+// snapshotData is the state of the simulation: the volumes of the operational
+// intents in flight, and the trail of positions of each aircraft.
+type snapshotData struct {
+	TrailLength int                        `json:"trail_length"`
+	Features    *geojson.FeatureCollection `json:"features"`
+}
+
+// oiCreatedData is an operational intent that has departed, with its volumes
+// and waypoints.
+type oiCreatedData struct {
+	MissionId string                     `json:"mission_id"`
+	Name      string                     `json:"name"`
+	Features  *geojson.FeatureCollection `json:"features"`
+}
+
+// positionData is the position of an aircraft as a GeoJSON point.
+type positionData struct {
+	MissionId string           `json:"mission_id"`
+	Feature   *geojson.Feature `json:"feature"`
+}
+
+type oiEndedData struct {
+	MissionId string `json:"mission_id"`
+}
+
+// activeFlight is an operational intent in flight, with its volumes and
+// waypoints, and the last positions of its aircraft.
+type activeFlight struct {
+	features *geojson.FeatureCollection
+	trail    []*geojson.Feature
+}
+
+// simulation is the single simulation of the configured flights shared by
+// the SSE clients of a server. It publishes the departure of each operational
+// intent, the positions of its aircraft as they are measured, and its end.
+type simulation struct {
+	hub         *hub
+	trailLength int
+	// active is only accessed under the lock of the hub
+	active  map[string]*activeFlight
+	metrics *simulationMetrics
+	// initialised is set once the steps of the simulation are ready to run
+	initialised atomic.Bool
+	// done is closed when the simulation is shut down, ending its streams
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newSimulation(trailLength int) *simulation {
+	if trailLength <= 0 {
+		trailLength = DefaultTrailLength
+	}
+	sim := &simulation{trailLength: trailLength, active: map[string]*activeFlight{}, done: make(chan struct{})}
+	sim.hub = newHub(sseBufferSize, sseHistorySize, sim.snapshot)
+	sim.metrics = newSimulationMetrics(sim.hub)
+	return sim
+}
+
+// snapshot is the snapshot event of the simulation, called under the lock of
+// the hub.
+func (sim *simulation) snapshot() []event {
+	ids := make([]string, 0, len(sim.active))
+	for id := range sim.active {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	features := geojson.NewFeatureCollection()
+	for _, id := range ids {
+		features.Features = append(features.Features, sim.active[id].features.Features...)
+		features.Features = append(features.Features, sim.active[id].trail...)
+	}
+	return newEvents(eventSnapshot, snapshotData{TrailLength: sim.trailLength, Features: features})
+}
+
+// newEvents is the event <name> with the JSON of <data>, or no events if it
+// cannot be marshalled.
+func newEvents(name string, data any) []event {
+	b, err := json.Marshal(data)
+	if err != nil {
+		log.Errorf("error occurred marshalling the %s event: %v", name, err)
+		return nil
+	}
+	return []event{{name: name, data: b, payload: data}}
+}
+
+// step is a step of the simulation: the departure of an operational intent,
+// a telemetry of its aircraft, or its end.
+type step struct {
+	at        int64
+	name      string
+	oiCnf     *config.OperationalIntentConfig
+	telemetry uspace.Telemetry
+}
+
+// shutdown stops the simulation and ends the streams of its clients.
+func (sim *simulation) shutdown() {
+	sim.stopOnce.Do(func() { close(sim.done) })
+}
+
+// run publishes the flights of <f> as they happen, until they have all ended
+// or the simulation is shut down.
+func (sim *simulation) run(f *flights) {
+	var steps []step
+	for _, id := range f.order {
+		fl := f.byId[id]
+		if len(fl.telemetry) == 0 {
+			continue
+		}
+		steps = append(steps, step{at: fl.telemetry[0].TimeMeasured, name: eventOiCreated, oiCnf: &fl.oiCnf})
+		for _, t := range fl.telemetry {
+			steps = append(steps, step{at: t.TimeMeasured, name: eventPosition, oiCnf: &fl.oiCnf, telemetry: t})
+		}
+		steps = append(steps, step{at: fl.telemetry[len(fl.telemetry)-1].TimeMeasured, name: eventOiEnded, oiCnf: &fl.oiCnf})
+	}
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].at < steps[j].at })
+	sim.initialised.Store(true)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for _, st := range steps {
+		timer.Reset(time.Until(time.UnixMilli(st.at)))
+		select {
+		case <-sim.done:
+			return
+		case <-timer.C:
+		}
+		sim.hub.publish(func() []event { return sim.apply(st) })
+	}
+	log.Debugf("all of the flights of the simulation have ended")
+}
+
+// apply applies <st> to the simulation, returning the event of it. It is
+// called under the lock of the hub.
+func (sim *simulation) apply(st step) []event {
+	missionId := st.oiCnf.MissionId.String()
+	switch st.name {
+	case eventOiCreated:
+		features := st.oiCnf.ToGeoJson()
+		for _, f := range features.Features {
+			f.Properties["layer"] = layerVolumes
+		}
+		for i, coordinate := range st.oiCnf.WaypointCoordinates {
+			f := geojson.NewFeature(orb.Point{coordinate[1], coordinate[0]})
+			f.Properties["layer"] = layerWaypoints
+			f.Properties["index"] = i
+			features.Append(f)
+		}
+		for _, f := range features.Features {
+			f.Properties["mission_id"] = missionId
+			f.Properties["name"] = st.oiCnf.Name
+		}
+		sim.active[missionId] = &activeFlight{features: features}
+		log.Debugf("operational intent %s departed", st.oiCnf.Name)
+		return newEvents(eventOiCreated, oiCreatedData{MissionId: missionId, Name: st.oiCnf.Name, Features: features})
+	case eventPosition:
+		fl, ok := sim.active[missionId]
+		if !ok {
+			return nil
+		}
+		f := st.telemetry.GeoJsonFeature()
+		f.Properties["layer"] = layerTelemetry
+		f.Properties["mission_id"] = missionId
+		fl.trail = append(fl.trail, f)
+		if len(fl.trail) > sim.trailLength {
+			fl.trail = fl.trail[len(fl.trail)-sim.trailLength:]
+		}
+		sim.metrics.telemetryMessages.WithLabelValues(missionId).Inc()
+		log.Tracef("telemetry measured for mission: %s", missionId)
+		return newEvents(eventPosition, positionData{MissionId: missionId, Feature: f})
+	case eventOiEnded:
+		delete(sim.active, missionId)
+		log.Debugf("operational intent %s ended", st.oiCnf.Name)
+		return newEvents(eventOiEnded, oiEndedData{MissionId: missionId})
+	}
+	return nil
+}
+
+// serveEvents streams the events of the simulation to each client as
+// server-sent events, until the client disconnects.
+func (sim *simulation) serveEvents(writeRequests bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// the stream outlives the write_timeout of the server
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+		c.Writer.Header().Set("Content-Type", "text/event-stream")
+		c.Writer.Header().Set("Cache-Control", "no-cache")
+		c.Writer.Header().Set("Connection", "keep-alive")
+		c.Writer.Header().Set("X-Accel-Buffering", "no")
+
+		flusher, ok := c.Writer.(http.Flusher)
+		if !ok {
+			c.AbortWithStatusJSON(500, gin.H{"error": "streaming unsupported"})
+			return
+		}
+
+		// reconnecting EventSources send the id of the last event they got
+		sub, backlog := sim.hub.subscribe(c.GetHeader("Last-Event-ID"))
+		defer sim.hub.unsubscribe(sub)
+		sim.metrics.streamClients.WithLabelValues(transportSse).Inc()
+		defer sim.metrics.streamClients.WithLabelValues(transportSse).Dec()
+		log.Infof("opening sse connection, %d events to catch up on", len(backlog))
+
+		// initial write helps some setups
+		_, _ = c.Writer.WriteString(": connected\n\n")
+		flusher.Flush()
+
+		// Optional: tell the client how long to wait before auto-reconnect (ms)
+		_, _ = c.Writer.WriteString("retry: 3000\n\n")
+		flusher.Flush()
+
+		if writeRequests == true && len(backlog) > 0 {
+			writeDump("riddp_init.geojson", backlog[len(backlog)-1].data)
+		}
+
+		for _, e := range backlog {
+			if err := e.write(c.Writer); err != nil {
+				log.Errorf("error writing event %s to client: %v", e.name, err)
+				return
+			}
+		}
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(sseKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				log.Infof("sse client disconnected")
+				return
+			case <-sim.done:
+				// the client reconnects, to the server that replaces this one
+				return
+			case <-keepAlive.C:
+				// comments keep proxies from closing an idle connection
+				if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case e, ok := <-sub.events:
+				if !ok {
+					// dropped for not keeping up, the client resumes from the
+					// last event it got when it reconnects
+					return
+				}
+				err := sim.metrics.timeWrite(transportSse, func() error {
+					if err := e.write(c.Writer); err != nil {
+						return err
+					}
+					flusher.Flush()
+					return nil
+				})
+				if err != nil {
+					log.Errorf("error writing event %s to client: %v", e.name, err)
+					return
+				}
+			}
+		}
+	}
+}
+
+// writeDump writes <data> to <name> in .requests, logging any error.
+func writeDump(name string, data []byte) {
+	outFileName := filepath.Join(".requests", name)
+	err := os.MkdirAll(filepath.Dir(outFileName), 0755)
+	if err == nil {
+		err = os.WriteFile(outFileName, data, 0644)
+	}
+	if err != nil {
+		log.Errorf("unable to write GeoJSON data to %s: %v", outFileName, err)
+	}
+}
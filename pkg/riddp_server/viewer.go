@@ -0,0 +1,19 @@
+package riddp_server
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// viewerPage is a map of the flights streamed from /features/events, drawn
+// over a coordinate grid, or the raster tiles of the URL template in its tiles
+// query parameter.
+//
+//go:embed web/index.html
+var viewerPage []byte
+
+func serveViewer(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", viewerPage)
+}
@@ -0,0 +1,259 @@
+package riddp_server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+// subscribeMessage is sent by a WebSocket client to choose the features it is
+// sent. Each one replaces the filter of the client, which is then sent a
+// snapshot of the simulation through it.
+type subscribeMessage struct {
+	Type string `json:"type"`
+	// Bbox is the area of the features, as a GeoJSON bbox: [west, south,
+	// east, north]. Features anywhere are sent if it is not set.
+	Bbox []float64 `json:"bbox"`
+	// MissionIds are the operational intents of the features, those of every
+	// operational intent are sent if it is not set.
+	MissionIds []string `json:"mission_ids"`
+	// Layers are any of volumes, waypoints and telemetry, every layer is sent
+	// if it is not set.
+	Layers []string `json:"layers"`
+}
+
+// wsMessage is a message sent to a WebSocket client: an event of the
+// simulation through its filter, or an error with the last message it sent.
+type wsMessage struct {
+	Type    string `json:"type"`
+	Id      string `json:"id,omitempty"`
+	Data    any    `json:"data,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// featureFilter is the features a WebSocket client has subscribed to.
+type featureFilter struct {
+	bbox       *orb.Bound
+	missionIds map[string]bool
+	layers     map[string]bool
+}
+
+// parseSubscribeMessage is the filter of the subscribe message <b>.
+func parseSubscribeMessage(b []byte) (*featureFilter, error) {
+	var msg subscribeMessage
+	if err := json.Unmarshal(b, &msg); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	if msg.Type != "subscribe" {
+		return nil, fmt.Errorf("unknown message type: %q", msg.Type)
+	}
+
+	f := &featureFilter{missionIds: map[string]bool{}, layers: map[string]bool{}}
+	switch len(msg.Bbox) {
+	case 0:
+	case 4:
+		west, south, east, north := msg.Bbox[0], msg.Bbox[1], msg.Bbox[2], msg.Bbox[3]
+		if west > east || south > north || south < -90 || north > 90 {
+			return nil, fmt.Errorf("bbox must be [west, south, east, north], got %v", msg.Bbox)
+		}
+		f.bbox = &orb.Bound{Min: orb.Point{west, south}, Max: orb.Point{east, north}}
+	default:
+		return nil, fmt.Errorf("bbox must be [west, south, east, north], got %v", msg.Bbox)
+	}
+	for _, id := range msg.MissionIds {
+		missionId, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mission id %q: %w", id, err)
+		}
+		f.missionIds[missionId.String()] = true
+	}
+	for _, layer := range msg.Layers {
+		switch layer {
+		case layerVolumes, layerWaypoints, layerTelemetry:
+			f.layers[layer] = true
+		default:
+			return nil, fmt.Errorf("unknown layer %q, must be one of %s, %s and %s", layer, layerVolumes, layerWaypoints, layerTelemetry)
+		}
+	}
+	return f, nil
+}
+
+func (f *featureFilter) matchesMission(missionId string) bool {
+	return len(f.missionIds) == 0 || f.missionIds[missionId]
+}
+
+func (f *featureFilter) matches(feature *geojson.Feature) bool {
+	if len(f.layers) > 0 && !f.layers[feature.Properties.MustString("layer", "")] {
+		return false
+	}
+	if !f.matchesMission(feature.Properties.MustString("mission_id", "")) {
+		return false
+	}
+	return f.bbox == nil || f.bbox.Intersects(feature.Geometry.Bound())
+}
+
+func (f *featureFilter) features(fc *geojson.FeatureCollection) *geojson.FeatureCollection {
+	filtered := geojson.NewFeatureCollection()
+	for _, feature := range fc.Features {
+		if f.matches(feature) {
+			filtered.Append(feature)
+		}
+	}
+	return filtered
+}
+
+// apply is the data of <e> through the filter, or false if none of it passes.
+// The payload of <e> is shared by every client, so it is copied rather than
+// changed.
+func (f *featureFilter) apply(e event) (any, bool) {
+	switch data := e.payload.(type) {
+	case snapshotData:
+		return snapshotData{TrailLength: data.TrailLength, Features: f.features(data.Features)}, true
+	case oiCreatedData:
+		if !f.matchesMission(data.MissionId) {
+			return nil, false
+		}
+		features := f.features(data.Features)
+		if len(features.Features) == 0 {
+			return nil, false
+		}
+		return oiCreatedData{MissionId: data.MissionId, Name: data.Name, Features: features}, true
+	case positionData:
+		return data, f.matches(data.Feature)
+	case oiEndedData:
+		return data, f.matchesMission(data.MissionId)
+	}
+	return nil, false
+}
+
+// checkOrigin accepts the WebSocket handshakes of the origins allowed by <p>,
+// and of clients that are not browsers and send no origin.
+func checkOrigin(p originPolicy) func(*websocket.Config, *http.Request) error {
+	return func(_ *websocket.Config, req *http.Request) error {
+		if origin := req.Header.Get("Origin"); origin != "" && !p.allows(origin, req) {
+			return fmt.Errorf("origin %s is not allowed", origin)
+		}
+		return nil
+	}
+}
+
+// wsRequest is a subscribe message received from a WebSocket client.
+type wsRequest struct {
+	filter *featureFilter
+	err    error
+}
+
+// serveWebSocket streams the events of the simulation to each WebSocket
+// client that has subscribed, through the filter of its last subscribe
+// message, until the client disconnects. Browsers may only connect from the
+// origins allowed by <origins>.
+func (sim *simulation) serveWebSocket(origins originPolicy) gin.HandlerFunc {
+	return gin.WrapH(websocket.Server{Handshake: checkOrigin(origins), Handler: sim.streamFiltered})
+}
+
+func (sim *simulation) streamFiltered(ws *websocket.Conn) {
+	defer ws.Close()
+	// the connection outlives the read and write timeouts of the server
+	_ = ws.SetDeadline(time.Time{})
+	log.Infof("opening websocket connection")
+	sim.metrics.streamClients.WithLabelValues(transportWebSocket).Inc()
+	defer sim.metrics.streamClients.WithLabelValues(transportWebSocket).Dec()
+
+	requests := make(chan wsRequest)
+	closed := make(chan struct{})
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		defer close(closed)
+		for {
+			var b []byte
+			if err := websocket.Message.Receive(ws, &b); err != nil {
+				return
+			}
+			f, err := parseSubscribeMessage(b)
+			select {
+			case requests <- wsRequest{filter: f, err: err}:
+			case <-stopped:
+				return
+			}
+		}
+	}()
+
+	var filter *featureFilter
+	var sub *subscriber
+	var lastId string
+	defer func() {
+		if sub != nil {
+			sim.hub.unsubscribe(sub)
+		}
+	}()
+
+	send := func(e event) error {
+		lastId = e.eventId()
+		data, ok := filter.apply(e)
+		if !ok {
+			return nil
+		}
+		return sim.metrics.timeWrite(transportWebSocket, func() error {
+			return websocket.JSON.Send(ws, wsMessage{Type: e.name, Id: e.eventId(), Data: data})
+		})
+	}
+	// subscribe (re)subscribes the client to the hub, sending it the events
+	// after <lastEventId>, or a snapshot
+	subscribe := func(lastEventId string) error {
+		if sub != nil {
+			sim.hub.unsubscribe(sub)
+		}
+		var backlog []event
+		sub, backlog = sim.hub.subscribe(lastEventId)
+		for _, e := range backlog {
+			if err := send(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for {
+		// a nil channel, until the client has subscribed
+		var events chan event
+		if sub != nil {
+			events = sub.events
+		}
+
+		var err error
+		select {
+		case <-closed:
+			log.Infof("websocket client disconnected")
+			return
+		case <-sim.done:
+			return
+		case r := <-requests:
+			if r.err != nil {
+				err = websocket.JSON.Send(ws, wsMessage{Type: "error", Message: r.err.Error()})
+				break
+			}
+			filter = r.filter
+			err = subscribe("")
+		case e, ok := <-events:
+			if !ok {
+				// dropped for not keeping up, resume from the last event sent
+				err = subscribe(lastId)
+				break
+			}
+			err = send(e)
+		}
+		if err != nil {
+			log.Errorf("error writing to websocket client: %v", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Package state is a local, file backed record of the operational intents
+// created by the CLI, and the lifecycle transitions made to them.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/config"
+)
+
+// OperationalIntentRecord is everything the CLI knows about an operational
+// intent that it created, keyed by the name of its config.
+type OperationalIntentRecord struct {
+	Name        string                     `json:"name"`
+	EntityId    string                     `json:"entity_id"`
+	UavId       int                        `json:"uav_id"`
+	Environment string                     `json:"environment"`
+	Ovn         string                     `json:"ovn,omitempty"`
+	Version     int                        `json:"version,omitempty"`
+	State       utm.OperationalIntentState `json:"state"`
+	CreatedAt   time.Time                  `json:"created_at"`
+	UpdatedAt   time.Time                  `json:"updated_at"`
+	// Intent is the operational intent last sent to the server, so that
+	// updates to it can be compared against it.
+	Intent      *uspace.OperationalIntent `json:"intent,omitempty"`
+	Transitions []Transition              `json:"transitions"`
+}
+
+// Transition is a change in state of an operational intent, along with the
+// response of the server that made it.
+type Transition struct {
+	From       utm.OperationalIntentState `json:"from,omitempty"`
+	To         utm.OperationalIntentState `json:"to"`
+	At         time.Time                  `json:"at"`
+	Ovn        string                     `json:"ovn,omitempty"`
+	Version    int                        `json:"version,omitempty"`
+	StatusCode int                        `json:"status_code,omitempty"`
+	Response   json.RawMessage            `json:"response,omitempty"`
+}
+
+// Store is a JSON file of OperationalIntentRecords.
+type Store struct {
+	path string
+	lock sync.Mutex
+}
+
+type stateFile struct {
+	OperationalIntents map[string]*OperationalIntentRecord `json:"operational_intents"`
+}
+
+// NotFoundError is returned when the store has no record for an operational
+// intent.
+type NotFoundError struct {
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("no operational intent has been created by the name: %s", e.Name)
+}
+
+// EnvironmentError is returned when an operational intent was created in an
+// environment other than the selected one, so that its record does not
+// describe the operational intent in the selected environment.
+type EnvironmentError struct {
+	Name        string
+	Environment string
+	Selected    string
+}
+
+func (e *EnvironmentError) Error() string {
+	return fmt.Sprintf("operational intent %s was created in environment %s, not %s, select it with --env", e.Name, e.Environment, e.Selected)
+}
+
+// NewStore returns the store backed by the file at <path>, which is created
+// on first write.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// DefaultPath is the path of the store for the simulation in <cfg>, so that
+// each simulation keeps its own state.
+func DefaultPath(cfg *config.Config) string {
+	return filepath.Join(config.LibConfigDir, "state", cfg.Name+".json")
+}
+
+// List returns every record in the store, ordered by creation time.
+func (s *Store) List() ([]OperationalIntentRecord, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	f, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]OperationalIntentRecord, 0, len(f.OperationalIntents))
+	for _, r := range f.OperationalIntents {
+		records = append(records, *r)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	return records, nil
+}
+
+// Get returns the record of the operational intent called <name>.
+func (s *Store) Get(name string) (*OperationalIntentRecord, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	f, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	r, ok := f.OperationalIntents[name]
+	if !ok {
+		return nil, &NotFoundError{Name: name}
+	}
+	return r, nil
+}
+
+// GetIn returns the record of the operational intent called <name>, or an
+// EnvironmentError if it was created in an environment other than
+// <environment>.
+func (s *Store) GetIn(name string, environment string) (*OperationalIntentRecord, error) {
+	r, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkEnvironment(environment); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// CheckCreate returns an EnvironmentError if the operational intent called
+// <name> cannot be created in <environment>, because it has a record in
+// another environment that has not ended.
+func (s *Store) CheckCreate(name string, environment string) error {
+	r, err := s.Get(name)
+	var notFound *NotFoundError
+	if errors.As(err, &notFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return r.checkCreate(environment)
+}
+
+// RecordCreated records the creation of the operational intent called
+// <name> as <intent> in <environment>. Re-creating an operational intent keeps
+// the history of its earlier transitions, and is refused as by CheckCreate.
+func (s *Store) RecordCreated(name string, entityId string, uavId int, environment string, intent *uspace.OperationalIntent, t Transition) error {
+	return s.update(func(f *stateFile) error {
+		r, ok := f.OperationalIntents[name]
+		if !ok {
+			r = &OperationalIntentRecord{Name: name}
+			f.OperationalIntents[name] = r
+		} else if err := r.checkCreate(environment); err != nil {
+			return err
+		}
+
+		t.From = r.State
+		r.EntityId = entityId
+		r.UavId = uavId
+		r.Environment = environment
+		r.CreatedAt = t.At
+		r.Intent = intent
+		r.Version = 0
+		r.apply(t)
+		return nil
+	})
+}
+
+// RecordTransition records the transition of the operational intent called
+// <name> to <t>.To.
+func (s *Store) RecordTransition(name string, t Transition) error {
+	return s.update(func(f *stateFile) error {
+		r, ok := f.OperationalIntents[name]
+		if !ok {
+			return &NotFoundError{Name: name}
+		}
+		t.From = r.State
+		r.apply(t)
+		return nil
+	})
+}
+
+// RecordUpdated records that the operational intent called <name> was
+// replaced by <intent>, without changing its state.
+func (s *Store) RecordUpdated(name string, intent *uspace.OperationalIntent, t Transition) error {
+	return s.update(func(f *stateFile) error {
+		r, ok := f.OperationalIntents[name]
+		if !ok {
+			return &NotFoundError{Name: name}
+		}
+		t.From = r.State
+		t.To = r.State
+		r.Intent = intent
+		r.apply(t)
+		return nil
+	})
+}
+
+// checkEnvironment returns an EnvironmentError if <r> was created in an
+// environment other than <environment>. Records that predate the environment
+// being recorded are in every environment.
+func (r *OperationalIntentRecord) checkEnvironment(environment string) error {
+	if r.Environment == "" || r.Environment == environment {
+		return nil
+	}
+	return &EnvironmentError{Name: r.Name, Environment: r.Environment, Selected: environment}
+}
+
+func (r *OperationalIntentRecord) checkCreate(environment string) error {
+	if r.State == utm.StateEnded {
+		return nil
+	}
+	return r.checkEnvironment(environment)
+}
+
+func (r *OperationalIntentRecord) apply(t Transition) {
+	r.State = t.To
+	r.UpdatedAt = t.At
+	if t.Ovn != "" {
+		r.Ovn = t.Ovn
+	}
+	if t.Version != 0 {
+		r.Version = t.Version
+	}
+	r.Transitions = append(r.Transitions, t)
+}
+
+func (s *Store) update(fn func(f *stateFile) error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	f, err := s.read()
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		return err
+	}
+	return s.write(f)
+}
+
+func (s *Store) read() (*stateFile, error) {
+	f := &stateFile{OperationalIntents: map[string]*OperationalIntentRecord{}}
+
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+
+	if err := json.Unmarshal(b, f); err != nil {
+		return nil, fmt.Errorf("parse state %s: %w", s.path, err)
+	}
+	if f.OperationalIntents == nil {
+		f.OperationalIntents = map[string]*OperationalIntentRecord{}
+	}
+	return f, nil
+}
+
+// write replaces the state file atomically, so that an interrupted write
+// never leaves a truncated file behind.
+func (s *Store) write(f *stateFile) error {
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), os.ModePerm); err != nil {
+		return fmt.Errorf("create state directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// ForConfig returns the store of the simulation in <cfg>.
+func ForConfig(cfg *config.Config) *Store {
+	return NewStore(DefaultPath(cfg))
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"manna.aero/manna.utm.cli/model/utm"
+)
+
+func TestStore_Lifecycle(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "state", "sim.json"))
+
+	_, err := store.Get("A")
+	var notFound *NotFoundError
+	assert.ErrorAs(t, err, &notFound)
+
+	assert.NoError(t, store.RecordCreated("A", "entity", 1, "local", nil, Transition{To: utm.StateAccepted, At: time.Now(), Ovn: "ovn-1"}))
+	assert.NoError(t, store.RecordTransition("A", Transition{To: utm.StateEnded, At: time.Now()}))
+	assert.NoError(t, store.RecordCreated("A", "entity", 1, "local", nil, Transition{To: utm.StateAccepted, At: time.Now(), Ovn: "ovn-2"}))
+
+	record, err := store.Get("A")
+	assert.NoError(t, err)
+	assert.Equal(t, utm.StateAccepted, record.State)
+	assert.Equal(t, "ovn-2", record.Ovn)
+	assert.Len(t, record.Transitions, 3)
+	assert.Equal(t, utm.StateAccepted, record.Transitions[1].From)
+	assert.Equal(t, utm.StateEnded, record.Transitions[2].From)
+
+	assert.NoError(t, store.RecordUpdated("A", nil, Transition{At: time.Now(), Ovn: "ovn-3", Version: 2}))
+	record, err = store.Get("A")
+	assert.NoError(t, err)
+	assert.Equal(t, utm.StateAccepted, record.State)
+	assert.Equal(t, "ovn-3", record.Ovn)
+	assert.Equal(t, 2, record.Version)
+
+	assert.ErrorAs(t, store.RecordTransition("B", Transition{To: utm.StateEnded, At: time.Now()}), &notFound)
+
+	records, err := store.List()
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestStore_Environment(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "state", "sim.json"))
+	assert.NoError(t, store.CheckCreate("A", "local"))
+	assert.NoError(t, store.RecordCreated("A", "entity", 1, "local", nil, Transition{To: utm.StateAccepted, At: time.Now()}))
+
+	record, err := store.GetIn("A", "local")
+	assert.NoError(t, err)
+	assert.Equal(t, "local", record.Environment)
+
+	// the record does not describe the operational intent in another environment
+	var envErr *EnvironmentError
+	_, err = store.GetIn("A", "staging")
+	assert.ErrorAs(t, err, &envErr)
+	assert.Equal(t, "local", envErr.Environment)
+	assert.ErrorAs(t, store.CheckCreate("A", "staging"), &envErr)
+	assert.ErrorAs(t, store.RecordCreated("A", "entity", 1, "staging", nil, Transition{To: utm.StateAccepted, At: time.Now()}), &envErr)
+
+	// until it has ended
+	assert.NoError(t, store.RecordTransition("A", Transition{To: utm.StateEnded, At: time.Now()}))
+	assert.NoError(t, store.CheckCreate("A", "staging"))
+	assert.NoError(t, store.RecordCreated("A", "entity", 1, "staging", nil, Transition{To: utm.StateAccepted, At: time.Now()}))
+	_, err = store.GetIn("A", "staging")
+	assert.NoError(t, err)
+	_, err = store.GetIn("A", "local")
+	assert.ErrorAs(t, err, &envErr)
+}
@@ -0,0 +1,290 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	// EndpointEnvVar is the standard OpenTelemetry variable for the OTLP/HTTP
+	// collector that spans are exported to, when no endpoint is set.
+	EndpointEnvVar = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// TraceparentEnvVar is the traceparent that the spans of the process are
+	// children of, when none is set.
+	TraceparentEnvVar = "TRACEPARENT"
+
+	DefaultServiceName = "manna-utm-cli"
+
+	// exportTimeout bounds each export to a collector
+	exportTimeout = 10 * time.Second
+	scopeName     = "manna.aero/manna.utm.cli"
+)
+
+// Options configures where the spans of the process are exported.
+type Options struct {
+	// File is the path the spans are appended to, as a line of OTLP JSON for
+	// each batch, which the otlpjsonfile receiver of the OpenTelemetry
+	// Collector reads.
+	File string
+	// Endpoint is the base URL of the OTLP/HTTP receiver of a collector, e.g.
+	// http://localhost:4318, the spans are posted to <Endpoint>/v1/traces.
+	Endpoint string
+	// ServiceName is the service.name of the spans, DefaultServiceName if it
+	// is not set.
+	ServiceName string
+}
+
+// active is the tracer provider of the process, nil until Setup.
+var active atomic.Pointer[sdktrace.TracerProvider]
+
+// Setup starts exporting the spans of the process to the file and the
+// collector of <opts>, in batches. Spans are not recorded if neither is set.
+func Setup(opts Options) error {
+	var exporters []sdktrace.SpanExporter
+	if opts.File != "" {
+		e, err := newFileExporter(opts.File)
+		if err != nil {
+			return err
+		}
+		exporters = append(exporters, e)
+	}
+	if opts.Endpoint != "" {
+		e, err := newOtlpExporter(opts.Endpoint)
+		if err != nil {
+			return err
+		}
+		exporters = append(exporters, e)
+	}
+	if len(exporters) == 0 {
+		return nil
+	}
+	serviceName := opts.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return err
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	for _, e := range exporters {
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(e, sdktrace.WithExportTimeout(exportTimeout)))
+	}
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Warnf("unable to export spans: %v", err)
+	}))
+	if previous := active.Swap(sdktrace.NewTracerProvider(providerOpts...)); previous != nil {
+		previous.Shutdown(context.Background())
+	}
+	return nil
+}
+
+// Shutdown exports the spans that have ended and stops exporting, waiting
+// until <ctx> is done at most.
+func Shutdown(ctx context.Context) error {
+	p := active.Swap(nil)
+	if p == nil {
+		return nil
+	}
+	// the errors of the last export are only returned by a flush
+	flushErr := p.ForceFlush(ctx)
+	if flushErr != nil {
+		flushErr = fmt.Errorf("unable to export spans: %w", flushErr)
+	}
+	return errors.Join(flushErr, p.Shutdown(ctx))
+}
+
+// newOtlpExporter is the exporter of the spans to the OTLP/HTTP receiver of
+// the collector at <endpoint>.
+func newOtlpExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported OTLP endpoint scheme %q, expected http or https", u.Scheme)
+	}
+	if !strings.HasSuffix(u.Path, "/v1/traces") {
+		u = u.JoinPath("/v1/traces")
+	}
+	// a command does not wait on a collector that is down, the spans of a
+	// failed export are dropped
+	return otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(u.String()),
+		otlptracehttp.WithTimeout(exportTimeout),
+		otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}))
+}
+
+// fileExporter appends each batch to a file, so that the spans of every
+// command of a scenario can be written to the same file. The SDK has no
+// exporter of OTLP JSON, the format of the otlpjsonfile receiver.
+type fileExporter struct {
+	lock sync.Mutex
+	f    *os.File
+}
+
+func newFileExporter(path string) (*fileExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open trace file: %w", err)
+	}
+	return &fileExporter{f: f}, nil
+}
+
+func (e *fileExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	request, err := json.Marshal(encodeSpans(spans))
+	if err != nil {
+		return err
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	_, err = e.f.Write(append(request, '\n'))
+	return err
+}
+
+func (e *fileExporter) Shutdown(context.Context) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.f.Close()
+}
+
+// The OTLP JSON encoding of an ExportTraceServiceRequest, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding. Ids are
+// hex and 64 bit integers are strings.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// the codes of the status of a span, left out when it is unset
+const (
+	statusOk    = 1
+	statusError = 2
+)
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// encodeSpans encodes <spans>, which come from the one tracer provider of the
+// process, under their resource and instrumentation scope.
+func encodeSpans(spans []sdktrace.ReadOnlySpan) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		encoded = append(encoded, encodeSpan(s))
+	}
+	scope := spans[0].InstrumentationScope()
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttributes(spans[0].Resource().Attributes())},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: scope.Name, Version: scope.Version}, Spans: encoded}},
+	}}}
+}
+
+func encodeSpan(s sdktrace.ReadOnlySpan) otlpSpan {
+	span := otlpSpan{
+		TraceId:           s.SpanContext().TraceID().String(),
+		SpanId:            s.SpanContext().SpanID().String(),
+		Name:              s.Name(),
+		Kind:              s.SpanKind(),
+		StartTimeUnixNano: strconv.FormatInt(s.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.EndTime().UnixNano(), 10),
+		Attributes:        encodeAttributes(s.Attributes()),
+	}
+	switch s.Status().Code {
+	case codes.Ok:
+		span.Status = otlpStatus{Code: statusOk}
+	case codes.Error:
+		span.Status = otlpStatus{Code: statusError, Message: s.Status().Description}
+	}
+	if s.Parent().SpanID().IsValid() {
+		span.ParentSpanId = s.Parent().SpanID().String()
+	}
+	return span
+}
+
+func encodeAttributes(attrs []attribute.KeyValue) []otlpAttribute {
+	encoded := make([]otlpAttribute, 0, len(attrs))
+	for _, attr := range attrs {
+		var v otlpValue
+		switch attr.Value.Type() {
+		case attribute.INT64:
+			i := strconv.FormatInt(attr.Value.AsInt64(), 10)
+			v.IntValue = &i
+		case attribute.BOOL:
+			b := attr.Value.AsBool()
+			v.BoolValue = &b
+		case attribute.FLOAT64:
+			f := attr.Value.AsFloat64()
+			v.DoubleValue = &f
+		default:
+			s := attr.Value.Emit()
+			v.StringValue = &s
+		}
+		encoded = append(encoded, otlpAttribute{Key: string(attr.Key), Value: v})
+	}
+	return encoded
+}
@@ -0,0 +1,143 @@
+// Package tracing records the requests of the clients as OpenTelemetry spans,
+// propagated to the servers they call with the W3C traceparent header and
+// exported over OTLP, so that the scenarios driven by the CLI join the traces
+// of manna-utm. It is a thin layer over the OpenTelemetry SDK that keeps the
+// spans of the clients in one shape.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TraceparentHeader is the W3C Trace Context header that carries the span of
+// a request to the server.
+const TraceparentHeader = "traceparent"
+
+// The keys of the attributes of the spans of requests.
+const (
+	AttrEntityId      = "utm.entity_id"
+	AttrUavId         = "utm.uav_id"
+	AttrRoute         = "http.route"
+	AttrMethod        = "http.request.method"
+	AttrStatusCode    = "http.response.status_code"
+	AttrServerAddress = "server.address"
+	AttrUrl           = "url.full"
+)
+
+// propagator reads and writes the traceparent header.
+var propagator = propagation.TraceContext{}
+
+// ParseTraceparent parses the traceparent header <v>.
+func ParseTraceparent(v string) (trace.SpanContext, error) {
+	header := http.Header{}
+	header.Set(TraceparentHeader, v)
+	sc := trace.SpanContextFromContext(propagator.Extract(context.Background(), propagation.HeaderCarrier(header)))
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("invalid traceparent %q, expected <version>-<trace id>-<span id>-<flags>", v)
+	}
+	return sc, nil
+}
+
+// Traceparent is the traceparent header of <sc>, e.g.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01, or "" if <sc> is
+// not valid.
+func Traceparent(sc trace.SpanContext) string {
+	header := http.Header{}
+	propagator.Inject(trace.ContextWithSpanContext(context.Background(), sc), propagation.HeaderCarrier(header))
+	return header.Get(TraceparentHeader)
+}
+
+// ContextWithTraceparent is <ctx> with the span of the traceparent header
+// <traceparent> as the parent of the spans started with it, e.g. the span of
+// the test harness that runs the CLI.
+func ContextWithTraceparent(ctx context.Context, traceparent string) (context.Context, error) {
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx, err
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc), nil
+}
+
+// Inject sets the traceparent header of <header> to the span of <ctx>, if it
+// has one.
+func Inject(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// SpanKind is the role of a span in a trace.
+type SpanKind = trace.SpanKind
+
+const (
+	SpanKindInternal = trace.SpanKindInternal
+	SpanKindClient   = trace.SpanKindClient
+)
+
+// Attribute is a key and a value of a span.
+type Attribute = attribute.KeyValue
+
+func String(key string, value string) Attribute {
+	return attribute.String(key, value)
+}
+
+func Int(key string, value int) Attribute {
+	return attribute.Int(key, value)
+}
+
+// Span is an operation in a trace, from when it is started until End is
+// called. The methods of a nil Span do nothing.
+type Span struct {
+	span trace.Span
+}
+
+// Start starts the span <name> as a child of the span of <ctx>, or of the
+// traceparent it carries, returning the span and a context carrying it. A
+// new trace is started when <ctx> has neither. Until Setup, spans are not
+// recorded, and the span of <ctx> is propagated as it is, so that the servers
+// called do not get the ids of spans that are never exported.
+func Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	var provider trace.TracerProvider = noop.NewTracerProvider()
+	if p := active.Load(); p != nil {
+		provider = p
+	}
+	ctx, span := provider.Tracer(scopeName).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+	return ctx, &Span{span: span}
+}
+
+// Context is the span context of <s>, to propagate it.
+func (s *Span) Context() trace.SpanContext {
+	if s == nil {
+		return trace.SpanContext{}
+	}
+	return s.span.SpanContext()
+}
+
+// SetAttributes adds <attrs> to <s>, replacing those with the same keys.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(attrs...)
+}
+
+// End ends <s>, as failed with <err> if it is not nil, and queues it to be
+// exported. The status of a span that did not fail is left unset, as the
+// semantic conventions ask of client spans. Only the first call has any
+// effect.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	if err != nil && s.span.IsRecording() {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
@@ -0,0 +1,197 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent(testTraceparent)
+	require.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID().String())
+	assert.True(t, sc.IsSampled())
+	assert.Equal(t, testTraceparent, Traceparent(sc))
+
+	sc, err = ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.NoError(t, err)
+	assert.False(t, sc.IsSampled())
+
+	// later versions may add fields
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.NoError(t, err)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+	} {
+		_, err := ParseTraceparent(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestStartWithoutSetup(t *testing.T) {
+	ctx, span := Start(context.Background(), "untraced", SpanKindInternal)
+	assert.False(t, span.Context().IsValid())
+	// a span that is not recorded does nothing
+	span.SetAttributes(String("key", "value"))
+	span.End(errors.New("failed"))
+
+	header := http.Header{}
+	Inject(ctx, header)
+	assert.Empty(t, header.Get(TraceparentHeader))
+
+	// the span of a traceparent is propagated as it is, rather than the ids of
+	// child spans that are not exported
+	ctx, err := ContextWithTraceparent(context.Background(), testTraceparent)
+	require.NoError(t, err)
+	ctx, span = Start(ctx, "child", SpanKindClient)
+	Inject(ctx, header)
+	assert.Equal(t, testTraceparent, header.Get(TraceparentHeader))
+	assert.Equal(t, testTraceparent, Traceparent(span.Context()))
+	span.End(nil)
+}
+
+// otlpCollector is the OTLP/HTTP receiver of a collector, decoding the
+// protobuf requests the exporter of the SDK posts to /v1/traces.
+func otlpCollector(t *testing.T) (*httptest.Server, func() []*tracepb.ResourceSpans) {
+	var lock sync.Mutex
+	var received []*tracepb.ResourceSpans
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var request coltracepb.ExportTraceServiceRequest
+		require.NoError(t, proto.Unmarshal(b, &request))
+		lock.Lock()
+		received = append(received, request.ResourceSpans...)
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		response, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+		w.Write(response)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []*tracepb.ResourceSpans {
+		lock.Lock()
+		defer lock.Unlock()
+		return received
+	}
+}
+
+func TestExport(t *testing.T) {
+	collector, received := otlpCollector(t)
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	require.NoError(t, Setup(Options{File: file, Endpoint: collector.URL}))
+
+	ctx, err := ContextWithTraceparent(context.Background(), testTraceparent)
+	require.NoError(t, err)
+	ctx, parent := Start(ctx, "manna-utm-cli create-oi", SpanKindInternal)
+	_, child := Start(ctx, "POST /operationalintent/{n}/{id}", SpanKindClient, String(AttrEntityId, "a3b5c7d9-0000-4000-8000-000000000001"), Int(AttrUavId, 7))
+	child.SetAttributes(Int(AttrStatusCode, 409))
+	child.End(errors.New("conflict"))
+	_, ok := Start(ctx, "GET /operationalintent/{id}", SpanKindClient)
+	ok.End(nil)
+	parent.End(nil)
+	// spans of a trace that is not sampled are not exported
+	notSampled, err := ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.NoError(t, err)
+	_, ignored := Start(notSampled, "ignored", SpanKindClient)
+	ignored.End(nil)
+
+	require.NoError(t, Shutdown(context.Background()))
+
+	// the collector
+	resourceSpans := received()
+	require.Len(t, resourceSpans, 1)
+	var serviceName string
+	for _, attr := range resourceSpans[0].Resource.Attributes {
+		if attr.Key == "service.name" {
+			serviceName = attr.Value.GetStringValue()
+		}
+	}
+	assert.Equal(t, DefaultServiceName, serviceName)
+	spans := resourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 3)
+	c, o, p := spans[0], spans[1], spans[2]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceID(p.TraceId).String())
+	assert.Equal(t, "00f067aa0ba902b7", trace.SpanID(p.ParentSpanId).String())
+	assert.Equal(t, p.TraceId, c.TraceId)
+	assert.Equal(t, p.SpanId, c.ParentSpanId)
+	assert.Equal(t, tracepb.Span_SPAN_KIND_CLIENT, c.Kind)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, c.Status.GetCode())
+	assert.Equal(t, "conflict", c.Status.GetMessage())
+	// a span that did not fail is left unset, it is not marked ok
+	assert.Equal(t, tracepb.Status_STATUS_CODE_UNSET, o.Status.GetCode())
+	assert.Equal(t, tracepb.Status_STATUS_CODE_UNSET, p.Status.GetCode())
+
+	// the file
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+	var written []otlpRequest
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var request otlpRequest
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &request))
+		written = append(written, request)
+	}
+	require.Len(t, written, 1)
+	fileSpans := written[0].ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, fileSpans, 3)
+	fc, fo, fp := fileSpans[0], fileSpans[1], fileSpans[2]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", fp.TraceId)
+	assert.Equal(t, "00f067aa0ba902b7", fp.ParentSpanId)
+	assert.Equal(t, fp.SpanId, fc.ParentSpanId)
+	assert.Equal(t, SpanKindClient, fc.Kind)
+	assert.Equal(t, otlpStatus{Code: statusError, Message: "conflict"}, fc.Status)
+	assert.Equal(t, otlpStatus{}, fo.Status)
+	assert.Equal(t, otlpStatus{}, fp.Status)
+	require.Len(t, fc.Attributes, 3)
+	assert.Equal(t, "a3b5c7d9-0000-4000-8000-000000000001", *fc.Attributes[0].Value.StringValue)
+	assert.Equal(t, "7", *fc.Attributes[1].Value.IntValue)
+	assert.Equal(t, AttrStatusCode, fc.Attributes[2].Key)
+	assert.Equal(t, "409", *fc.Attributes[2].Value.IntValue)
+}
+
+func TestExportError(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	require.NoError(t, Setup(Options{Endpoint: collector.URL + "/v1/traces"}))
+	_, span := Start(context.Background(), "lost", SpanKindInternal)
+	span.End(nil)
+	err := Shutdown(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unavailable")
+
+	assert.Error(t, Setup(Options{Endpoint: "localhost:4318"}))
+}
@@ -0,0 +1,96 @@
+package transport
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker stops requests to a host after <policy>.FailureThreshold
+// consecutive failed attempts. Once <policy>.Cooldown has passed, a single
+// request is let through: the circuit closes if it succeeds and opens again
+// if it fails.
+type circuitBreaker struct {
+	host   string
+	policy CircuitBreakerPolicy
+
+	lock      sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow returns a CircuitOpenError if a request may not be sent to the host
+// at <now>.
+func (b *circuitBreaker) allow(now time.Time) error {
+	if b.policy.FailureThreshold < 0 {
+		return nil
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.failures < b.policy.FailureThreshold {
+		return nil
+	}
+	if now.Before(b.openUntil) || b.probing {
+		return &CircuitOpenError{Host: b.host, Until: b.openUntil}
+	}
+	b.probing = true
+	return nil
+}
+
+// record the outcome of an attempt that was allowed at <now>.
+func (b *circuitBreaker) record(now time.Time, failed bool) {
+	if b.policy.FailureThreshold < 0 {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.policy.FailureThreshold {
+		b.openUntil = now.Add(b.policy.Cooldown)
+	}
+}
+
+// breakerSet holds a circuit breaker for each host and policy, so that the
+// clients of environments with different policies for the same host each
+// break the circuit as they are configured to.
+type breakerSet struct {
+	lock     sync.Mutex
+	breakers map[breakerKey]*circuitBreaker
+}
+
+type breakerKey struct {
+	host   string
+	policy CircuitBreakerPolicy
+}
+
+func newBreakerSet() *breakerSet {
+	return &breakerSet{breakers: map[breakerKey]*circuitBreaker{}}
+}
+
+// forHost returns the circuit breaker of <host> with <policy>, creating it if
+// there is none yet.
+func (s *breakerSet) forHost(host string, policy CircuitBreakerPolicy) *circuitBreaker {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := breakerKey{host: host, policy: policy}
+	b, ok := s.breakers[key]
+	if !ok {
+		b = &circuitBreaker{host: host, policy: policy}
+		s.breakers[key] = b
+	}
+	return b
+}
+
+// hostBreakers are shared by every client in the process, so that all
+// requests to a host with the same policy count towards the same circuit.
+var hostBreakers = newBreakerSet()
@@ -0,0 +1,87 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// NetworkError is returned when a request could not be sent, or no response
+// was received for it.
+type NetworkError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("network error: %s %s: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is returned when no response was received for a request
+// within the timeout of the attempt.
+type TimeoutError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timeout: %s %s: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// CircuitOpenError is returned without sending a request while the circuit
+// breaker of its host is open.
+type CircuitOpenError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for %s is open until %s after repeated failures", e.Host, e.Until.Format(time.RFC3339))
+}
+
+// ClientError is a 4xx response.
+type ClientError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *ClientError) Error() string {
+	return fmt.Sprintf("client error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// ServerError is a 5xx response.
+type ServerError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("server error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// ResponseError returns a ClientError or ServerError for a response with
+// <statusCode> and <body>, or nil if <statusCode> is not an error.
+func ResponseError(statusCode int, body string) error {
+	switch {
+	case statusCode >= 500:
+		return &ServerError{StatusCode: statusCode, Body: body}
+	case statusCode >= 400:
+		return &ClientError{StatusCode: statusCode, Body: body}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,84 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Metrics are the metrics of the requests sent by every client in the
+// process, by method, host and route, e.g. for a load test against manna-utm.
+var Metrics = prometheus.NewRegistry()
+
+var (
+	clientRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "manna_utm_cli_client_requests_total",
+		Help: "The attempts at requests sent by the clients, by method, host, route and status code, or the error of attempts that received no response.",
+	}, []string{"method", "host", "route", "status"})
+	clientRequestDurations = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "manna_utm_cli_client_request_duration_seconds",
+		Help:    "The time taken by the attempts at requests sent by the clients to receive the headers of their response, by method, host and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "host", "route"})
+)
+
+func init() {
+	Metrics.MustRegister(clientRequests, clientRequestDurations)
+}
+
+// WriteMetrics writes the Metrics to <w> in the Prometheus text format.
+func WriteMetrics(w io.Writer) error {
+	families, err := Metrics.Gather()
+	if err != nil {
+		return err
+	}
+	enc := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range families {
+		if err := enc.Encode(family); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observe records an attempt at <req> that took <took> and ended with <resp>
+// or <err>.
+func observe(req *http.Request, resp *http.Response, err error, took time.Duration) {
+	r := Route(req.URL.Path)
+	var status string
+	var timeoutErr *TimeoutError
+	switch {
+	case err == nil:
+		status = strconv.Itoa(resp.StatusCode)
+	case errors.Is(err, context.Canceled):
+		status = "canceled"
+	case errors.As(err, &timeoutErr):
+		status = "timeout"
+	default:
+		status = "network_error"
+	}
+	clientRequests.WithLabelValues(req.Method, req.URL.Host, r, status).Inc()
+	clientRequestDurations.WithLabelValues(req.Method, req.URL.Host, r).Observe(took.Seconds())
+}
+
+// Route is <path> with the ids in it replaced, so that the requests to each
+// route of a server are counted together, e.g. /operationalintent/{n}/{id}.
+func Route(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if _, err := uuid.Parse(segment); err == nil && len(segment) == 36 {
+			segments[i] = "{id}"
+		} else if _, err := strconv.Atoi(segment); err == nil {
+			segments[i] = "{n}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,43 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"manna.aero/manna.utm.cli/pkg/tracing"
+)
+
+// StartSpan starts the client span of a <method> request to <requestUrl>,
+// named by its method and route, e.g. PUT /operationalintent/{id}/{n}, with
+// <attrs> such as the entity id of the request. The Transport propagates the
+// span to the server with the traceparent header.
+func StartSpan(ctx context.Context, method string, requestUrl string, attrs ...tracing.Attribute) (context.Context, *tracing.Span) {
+	r := requestUrl
+	host := ""
+	if u, err := url.Parse(requestUrl); err == nil {
+		r = Route(u.Path)
+		host = u.Host
+		requestUrl = u.Redacted()
+	}
+	attrs = append([]tracing.Attribute{
+		tracing.String(tracing.AttrMethod, method),
+		tracing.String(tracing.AttrRoute, r),
+		tracing.String(tracing.AttrServerAddress, host),
+		tracing.String(tracing.AttrUrl, requestUrl),
+	}, attrs...)
+	return tracing.Start(ctx, method+" "+r, tracing.SpanKindClient, attrs...)
+}
+
+// propagate is <req> with the traceparent header of the span of its context,
+// if it has one. <req> itself is not changed, as a RoundTripper must not.
+func propagate(req *http.Request) *http.Request {
+	header := http.Header{}
+	tracing.Inject(req.Context(), header)
+	if len(header) == 0 {
+		return req
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(tracing.TraceparentHeader, header.Get(tracing.TraceparentHeader))
+	return req
+}
@@ -0,0 +1,306 @@
+// Package transport is the HTTP transport shared by the manna-utm and USS
+// clients. It bounds each attempt at a request with a timeout, retries
+// transient failures with exponential backoff, and keeps a circuit breaker
+// for each host, so that an unavailable host fails fast.
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/pkg/config"
+)
+
+const (
+	DefaultTimeout          = 15 * time.Second
+	DefaultMaxAttempts      = 3
+	DefaultInitialBackoff   = 200 * time.Millisecond
+	DefaultMaxBackoff       = 5 * time.Second
+	DefaultFailureThreshold = 5
+	DefaultCooldown         = 30 * time.Second
+)
+
+// Policy configures a Transport.
+type Policy struct {
+	// Timeout bounds each attempt at a request, rather than the request as a
+	// whole, so that retries are not cut short.
+	Timeout        time.Duration
+	Retry          RetryPolicy
+	CircuitBreaker CircuitBreakerPolicy
+}
+
+// RetryPolicy is how a Transport retries requests, see config.RetryConfig.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// CircuitBreakerPolicy is how a Transport breaks the circuit of a host, see
+// config.CircuitBreakerConfig.
+type CircuitBreakerPolicy struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+// DefaultPolicy is the policy of environments that do not configure one.
+func DefaultPolicy() Policy {
+	return Policy{
+		Timeout: DefaultTimeout,
+		Retry: RetryPolicy{
+			MaxAttempts:    DefaultMaxAttempts,
+			InitialBackoff: DefaultInitialBackoff,
+			MaxBackoff:     DefaultMaxBackoff,
+		},
+		CircuitBreaker: CircuitBreakerPolicy{
+			FailureThreshold: DefaultFailureThreshold,
+			Cooldown:         DefaultCooldown,
+		},
+	}
+}
+
+// PolicyFromEnvironment is the policy configured for <env>, with the defaults
+// in place of any settings that it leaves empty.
+func PolicyFromEnvironment(env *config.EnvironmentConfig) Policy {
+	p := DefaultPolicy()
+	if env.Timeout > 0 {
+		p.Timeout = env.Timeout
+	}
+	if env.Retry.MaxAttempts > 0 {
+		p.Retry.MaxAttempts = env.Retry.MaxAttempts
+	}
+	if env.Retry.InitialBackoff > 0 {
+		p.Retry.InitialBackoff = env.Retry.InitialBackoff
+	}
+	if env.Retry.MaxBackoff > 0 {
+		p.Retry.MaxBackoff = env.Retry.MaxBackoff
+	}
+	if env.CircuitBreaker.FailureThreshold != 0 {
+		p.CircuitBreaker.FailureThreshold = env.CircuitBreaker.FailureThreshold
+	}
+	if env.CircuitBreaker.Cooldown > 0 {
+		p.CircuitBreaker.Cooldown = env.CircuitBreaker.Cooldown
+	}
+	return p
+}
+
+// Transport is an http.RoundTripper that applies a Policy to the requests
+// sent through <base>.
+//
+// Requests are retried after a network error, a timeout or a 502, 503 or 504
+// response only if they are idempotent, i.e. their method is idempotent, they
+// carry an Idempotency-Key header or their context is marked WithIdempotent. A 429 response means the request was
+// not processed, so any request is retried after one. Errors are returned as
+// a NetworkError, TimeoutError or CircuitOpenError. The span of the context
+// of a request, see StartSpan, is propagated to the server with the
+// traceparent header.
+type Transport struct {
+	base     http.RoundTripper
+	policy   Policy
+	breakers *breakerSet
+}
+
+// New creates a Transport that sends requests with <base>. The circuit
+// breakers are shared with every other Transport in the process with the same
+// CircuitBreakerPolicy.
+func New(base http.RoundTripper, policy Policy) *Transport {
+	return &Transport{base: base, policy: policy, breakers: hostBreakers}
+}
+
+// NewClient creates an http.Client that sends requests with a Transport, over
+// TLS configured by <tlsConfig>, which may be nil to use the default TLS
+// settings.
+func NewClient(tlsConfig *tls.Config, policy Policy) *http.Client {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: New(base, policy)}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = propagate(req)
+	breaker := t.breakers.forHost(req.URL.Host, t.policy.CircuitBreaker)
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 1; ; attempt++ {
+		if err := breaker.allow(time.Now()); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		start := time.Now()
+		resp, err := t.send(attemptReq)
+		observe(attemptReq, resp, err, time.Since(start))
+		breaker.record(time.Now(), failed(resp, err))
+
+		if attempt >= t.policy.Retry.MaxAttempts || !replayable || !retryable(req, resp, err) {
+			return resp, err
+		}
+		wait := t.backoff(attempt, resp)
+		if wait > t.policy.Retry.MaxBackoff {
+			log.Warnf("not retrying %s %s, the server asks to wait %s, longer than the maximum backoff of %s", req.Method, req.URL.Redacted(), wait.Round(time.Second), t.policy.Retry.MaxBackoff)
+			return resp, err
+		}
+		if deadline, ok := req.Context().Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return resp, err
+		}
+
+		reason := errorOrStatus(resp, err)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		log.Warnf("retrying %s %s in %s after attempt %d of %d failed with %s", req.Method, req.URL.Redacted(), wait.Round(time.Millisecond), attempt, t.policy.Retry.MaxAttempts, reason)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes a single attempt at <req>, bounded by the timeout of the policy.
+func (t *Transport) send(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.policy.Timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, classify(req, err)
+	}
+	// the attempt lasts until its response body has been read
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff is the wait before retrying after attempt number <attempt>: the
+// Retry-After of <resp> if it has one, otherwise a random duration up to the
+// exponentially growing backoff ceiling. Only a Retry-After can be longer than
+// the MaxBackoff of the policy, in which case the request is not retried.
+func (t *Transport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := retryAfter(resp); ok {
+			return wait
+		}
+	}
+
+	ceiling := t.policy.Retry.InitialBackoff
+	for i := 1; i < attempt && ceiling < t.policy.Retry.MaxBackoff; i++ {
+		ceiling *= 2
+	}
+	ceiling = min(ceiling, t.policy.Retry.MaxBackoff)
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling)
+}
+
+// retryAfter parses the Retry-After header of <resp>, which is either a
+// number of seconds or an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		if req.Context().Err() != nil {
+			return false
+		}
+		var networkErr *NetworkError
+		var timeoutErr *TimeoutError
+		return (errors.As(err, &networkErr) || errors.As(err, &timeoutErr)) && idempotent(req)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent(req)
+	default:
+		return false
+	}
+}
+
+type idempotentKey struct{}
+
+// WithIdempotent marks the requests made with <ctx> as idempotent, so that
+// they are retried whatever their method, e.g. a POST that only queries.
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		marked, _ := req.Context().Value(idempotentKey{}).(bool)
+		return marked || req.Header.Get("Idempotency-Key") != ""
+	}
+}
+
+// failed reports whether an attempt counts as a failure of the host, for its
+// circuit breaker. Client errors and rate limiting are not failures of the
+// host.
+func failed(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= 500
+}
+
+func classify(req *http.Request, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &TimeoutError{Method: req.Method, URL: req.URL.Redacted(), Err: err}
+	}
+	return &NetworkError{Method: req.Method, URL: req.URL.Redacted(), Err: err}
+}
+
+func errorOrStatus(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.Status
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
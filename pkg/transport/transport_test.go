@@ -0,0 +1,194 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testClient(policy Policy) *http.Client {
+	return &http.Client{Transport: &Transport{base: http.DefaultTransport, policy: policy, breakers: newBreakerSet()}}
+}
+
+func testPolicy() Policy {
+	p := DefaultPolicy()
+	p.Timeout = time.Second
+	p.Retry.InitialBackoff = time.Millisecond
+	p.Retry.MaxBackoff = 5 * time.Millisecond
+	return p
+}
+
+// failingServer responds with <status> to the first <failures> requests, and
+// 200 after that.
+func failingServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if attempts.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &attempts
+}
+
+func TestTransport_RetriesIdempotentRequests(t *testing.T) {
+	srv, attempts := failingServer(t, 2, http.StatusServiceUnavailable, nil)
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, strings.NewReader("body"))
+	resp, err := testClient(testPolicy()).Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	b, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "body", string(b), "the body is replayed on each attempt")
+	assert.EqualValues(t, 3, attempts.Load())
+}
+
+func TestTransport_DoesNotRetryNonIdempotentRequests(t *testing.T) {
+	srv, attempts := failingServer(t, 1, http.StatusServiceUnavailable, nil)
+
+	resp, err := testClient(testPolicy()).Post(srv.URL, "application/json", strings.NewReader("{}"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.EqualValues(t, 1, attempts.Load())
+}
+
+func TestTransport_RetriesRequestsMarkedIdempotent(t *testing.T) {
+	srv, attempts := failingServer(t, 1, http.StatusBadGateway, nil)
+
+	req, _ := http.NewRequestWithContext(WithIdempotent(context.Background()), http.MethodPost, srv.URL, strings.NewReader("{}"))
+	resp, err := testClient(testPolicy()).Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.EqualValues(t, 2, attempts.Load())
+}
+
+func TestTransport_HonoursRetryAfter(t *testing.T) {
+	srv, attempts := failingServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+
+	p := testPolicy()
+	p.Retry.MaxBackoff = 2 * time.Second
+	start := time.Now()
+	resp, err := testClient(p).Post(srv.URL, "application/json", strings.NewReader("{}"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.EqualValues(t, 2, attempts.Load())
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestTransport_RetryAfterLongerThanMaxBackoff(t *testing.T) {
+	for _, retryAfter := range []string{"86400", time.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat)} {
+		srv, attempts := failingServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {retryAfter}})
+
+		start := time.Now()
+		resp, err := testClient(testPolicy()).Post(srv.URL, "application/json", strings.NewReader("{}"))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "the response is returned rather than waiting a day")
+		assert.EqualValues(t, 1, attempts.Load())
+		assert.Less(t, time.Since(start), time.Second)
+	}
+}
+
+func TestTransport_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	p := testPolicy()
+	p.Timeout = 20 * time.Millisecond
+	_, err := testClient(p).Get(srv.URL)
+
+	var timeoutErr *TimeoutError
+	assert.ErrorAs(t, err, &timeoutErr)
+}
+
+func TestTransport_CircuitBreaker(t *testing.T) {
+	srv, attempts := failingServer(t, 100, http.StatusBadGateway, nil)
+
+	p := testPolicy()
+	p.Retry.MaxAttempts = 1
+	p.CircuitBreaker = CircuitBreakerPolicy{FailureThreshold: 2, Cooldown: time.Hour}
+	c := testClient(p)
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(srv.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	_, err := c.Get(srv.URL)
+	var openErr *CircuitOpenError
+	assert.ErrorAs(t, err, &openErr)
+	assert.EqualValues(t, 2, attempts.Load(), "no request is sent while the circuit is open")
+}
+
+func TestTransport_CircuitBreakerPerPolicy(t *testing.T) {
+	srv, attempts := failingServer(t, 100, http.StatusBadGateway, nil)
+	breakers := newBreakerSet()
+
+	strict := testPolicy()
+	strict.Retry.MaxAttempts = 1
+	strict.CircuitBreaker = CircuitBreakerPolicy{FailureThreshold: 1, Cooldown: time.Hour}
+	lenient := strict
+	lenient.CircuitBreaker = CircuitBreakerPolicy{FailureThreshold: 10, Cooldown: time.Hour}
+
+	resp, err := (&http.Client{Transport: &Transport{base: http.DefaultTransport, policy: strict, breakers: breakers}}).Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	_, err = (&http.Client{Transport: &Transport{base: http.DefaultTransport, policy: strict, breakers: breakers}}).Get(srv.URL)
+	var openErr *CircuitOpenError
+	assert.ErrorAs(t, err, &openErr)
+
+	// the circuit of a client with another policy for the same host is its own
+	for i := 0; i < 3; i++ {
+		resp, err := (&http.Client{Transport: &Transport{base: http.DefaultTransport, policy: lenient, breakers: breakers}}).Get(srv.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	assert.EqualValues(t, 4, attempts.Load())
+}
+
+func TestResponseError(t *testing.T) {
+	var clientErr *ClientError
+	var serverErr *ServerError
+	assert.ErrorAs(t, ResponseError(http.StatusConflict, ""), &clientErr)
+	assert.ErrorAs(t, ResponseError(http.StatusBadGateway, ""), &serverErr)
+	assert.NoError(t, ResponseError(http.StatusOK, ""))
+}
+
+func TestTransport_Metrics(t *testing.T) {
+	srv, _ := failingServer(t, 1, http.StatusServiceUnavailable, nil)
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/metrics-test/42/8302353f-a149-40ac-87c4-dd071b124b1d", nil)
+	resp, err := testClient(testPolicy()).Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	var b strings.Builder
+	assert.NoError(t, WriteMetrics(&b))
+	host := strings.TrimPrefix(srv.URL, "http://")
+	labels := `host="` + host + `",method="PUT",route="/metrics-test/{n}/{id}"`
+	assert.Contains(t, b.String(), "manna_utm_cli_client_requests_total{"+labels+`,status="503"} 1`+"\n")
+	assert.Contains(t, b.String(), "manna_utm_cli_client_requests_total{"+labels+`,status="200"} 1`+"\n")
+	assert.Contains(t, b.String(), "manna_utm_cli_client_request_duration_seconds_count{"+labels+"} 2\n")
+}
@@ -3,6 +3,7 @@ package uspace_client
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,13 +13,16 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"manna.aero/manna.utm.cli/model/uspace"
 	"manna.aero/manna.utm.cli/model/utm"
-	"manna.aero/manna.utm.cli/pkg/config"
+	"manna.aero/manna.utm.cli/pkg/auth"
+	"manna.aero/manna.utm.cli/pkg/tracing"
+	"manna.aero/manna.utm.cli/pkg/transport"
 )
 
 type MannaUtmClient struct {
@@ -26,40 +30,35 @@ type MannaUtmClient struct {
 	c             *http.Client
 	UserAgent     string
 	writeRequests bool
+	// TokenSource provides the bearer tokens sent with each request, no
+	// Authorization header is sent when it is nil.
+	TokenSource auth.TokenSource
 }
 
-func NewMannaUtmClient(host string, port int, writeRequests bool) (*MannaUtmClient, error) {
-	baseUrlStr := fmt.Sprintf("http://%s:%d", host, port)
-	baseUrl, err := url.Parse(baseUrlStr)
+// NewMannaUtmClient creates a client for the manna-utm deployment at <baseUrl>,
+// which may include a path prefix, sending requests with the retries and
+// timeouts of <policy>. <tlsConfig> may be nil to use the default TLS settings.
+func NewMannaUtmClient(baseUrl string, tlsConfig *tls.Config, policy transport.Policy, writeRequests bool) (*MannaUtmClient, error) {
+	u, err := url.Parse(strings.TrimRight(baseUrl, "/"))
 	if err != nil {
 		return nil, err
 	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported manna-utm url scheme %q, expected http or https", u.Scheme)
+	}
 
 	return &MannaUtmClient{
-		baseUrl: baseUrl,
-		c: &http.Client{
-			Timeout: 15 * time.Second,
-		},
+		baseUrl:       u,
+		c:             transport.NewClient(tlsConfig, policy),
 		UserAgent:     "manna-utm-cli",
 		writeRequests: writeRequests,
 	}, nil
 }
 
-// Query4dVolume uses the manna-utm U-Space interface to query a given 4d volume.
+// Query4dVolume uses the manna-utm U-Space interface to query the 4d volume <vol>,
+// recording the request under <volName>.
 // see https://github.com/m4a3/manna-utm/blob/persistence/src/main/java/manna/aero/utm/controller/UTMController.java#L141-L163
-func (mutm *MannaUtmClient) Query4dVolume(ctx context.Context, volName string) ([]utm.OperationalIntentDetails, error) {
-	c, err := config.LoadConfig("./config.yaml")
-	if err != nil {
-		return nil, err
-	}
-
-	volCnf, err := c.Get4dVolumeConfigByName(volName)
-	if err != nil {
-		return nil, err
-	}
-
-	vol := uspace.GetVolume4dFromConfig(*volCnf)
-
+func (mutm *MannaUtmClient) Query4dVolume(ctx context.Context, volName string, vol uspace.Volume4d) (_ []utm.OperationalIntentDetails, err error) {
 	reader, err := vol.ToReader()
 	if err != nil {
 		return nil, err
@@ -69,40 +68,54 @@ func (mutm *MannaUtmClient) Query4dVolume(ctx context.Context, volName string) (
 		return nil, fmt.Errorf("failed to read intent body: %w", err)
 	}
 
-	requestUrl := fmt.Sprintf("http://localhost:%d/operationalintent/query", c.MannaUtmPort)
-	req, err := http.NewRequestWithContext(ctx, "POST", requestUrl, bytes.NewReader(bodyBytes))
+	requestUrl, err := url.JoinPath(mutm.baseUrl.String(), "/operationalintent/query")
 	if err != nil {
-		log.Errorf("An error occurred attempting to create operational intent in manna-utm: %v", err)
+		return nil, err
+	}
+	ctx, span := transport.StartSpan(ctx, "POST", requestUrl)
+	defer func() { span.End(err) }()
+	// querying does not change anything, so it is safe to retry
+	req, err := http.NewRequestWithContext(transport.WithIdempotent(ctx), "POST", requestUrl, bytes.NewReader(bodyBytes))
+	if err != nil {
+		log.Errorf("An error occurred attempting to query 4d volume in manna-utm: %v", err)
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", mutm.UserAgent)
 	req.Header.Set("Content-Type", "application/json")
+	if err := auth.SetAuthorizationHeader(ctx, req, mutm.TokenSource, auth.ScopeStrategicCoordination); err != nil {
+		return nil, err
+	}
 
-	mutm.logRequestContents(*req, IdAndTimeRecord{startTime: time.Now(), missionId: volName})
+	mutm.logRequestContents(req, IdAndTimeRecord{startTime: time.Now(), missionId: volName})
 
 	resp, err := mutm.c.Do(req)
 	if err != nil {
-		if resp == nil {
-			return nil, err
-		}
-
-		errJson, err := json.Marshal(err.Error())
-		if err != nil {
-			return nil, fmt.Errorf("error trying to parse error response from manna-utm: %v", err)
-		}
-		return nil, &MannaUtmError{StatusCode: resp.StatusCode, Body: string(errJson)}
+		return nil, requestError(err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(tracing.Int(tracing.AttrStatusCode, resp.StatusCode))
 
 	// Handle non-2xx responses with useful errors
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// read a limited amount so you don’t blow memory on huge error bodies
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
-		return nil, &MannaUtmError{StatusCode: resp.StatusCode, Body: string(b)}
+		return nil, responseError(resp)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, requestError(err)
+	}
+
+	operationalIntents := []utm.OperationalIntentDetails{}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return operationalIntents, nil
+	}
+	if err := json.Unmarshal(b, &operationalIntents); err != nil {
+		return nil, fmt.Errorf("failed to decode operational intents returned by manna-utm: %w", err)
 	}
 
-	return nil, nil
+	log.Debugf("manna-utm returned %d operational intents in 4d volume %s", len(operationalIntents), volName)
+	return operationalIntents, nil
 }
 
 type CreateOperationalIntentRequest struct {
@@ -122,30 +135,35 @@ func (coir CreateOperationalIntentRequest) getMissionId() string {
 
 // CreateOperationalIntent interfaces with the manna-utm U-Space interface to create an operational intent.
 // see https://github.com/m4a3/manna-utm/blob/persistence/src/main/java/manna/aero/utm/controller/UTMController.java#L56-L91
-func (mutm *MannaUtmClient) CreateOperationalIntent(ctx context.Context, uavId int, entityId string, intent *uspace.OperationalIntent) error {
+func (mutm *MannaUtmClient) CreateOperationalIntent(ctx context.Context, uavId int, entityId string, intent *uspace.OperationalIntent) (_ *OperationalIntentResponse, err error) {
 
 	requestUrl, err := url.JoinPath(mutm.baseUrl.String(), path.Join("/operationalintent", strconv.Itoa(uavId), entityId))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	ctx, span := transport.StartSpan(ctx, "POST", requestUrl, tracing.String(tracing.AttrEntityId, entityId), tracing.Int(tracing.AttrUavId, uavId))
+	defer func() { span.End(err) }()
 
 	reader, err := intent.ToReader()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	bodyBytes, err := io.ReadAll(reader)
 	if err != nil {
-		return fmt.Errorf("failed to read intent body: %w", err)
+		return nil, fmt.Errorf("failed to read intent body: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", requestUrl, bytes.NewReader(bodyBytes))
 	if err != nil {
 		log.Errorf("An error occurred attempting to create operational intent in manna-utm: %v", err)
-		return err
+		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", mutm.UserAgent)
 	req.Header.Set("Content-Type", "application/json")
+	if err := auth.SetAuthorizationHeader(ctx, req, mutm.TokenSource, auth.ScopeStrategicCoordination); err != nil {
+		return nil, err
+	}
 
 	var wg sync.WaitGroup
 
@@ -154,7 +172,7 @@ func (mutm *MannaUtmClient) CreateOperationalIntent(ctx context.Context, uavId i
 		defer wg.Done()
 		logReq, _ := http.NewRequestWithContext(ctx, "POST", requestUrl, bytes.NewReader(bodyBytes))
 		logReq.Header = req.Header.Clone()
-		mutm.logRequestContents(*logReq, CreateOperationalIntentRequest{
+		mutm.logRequestContents(logReq, CreateOperationalIntentRequest{
 			time:      time.Now(),
 			oi:        intent,
 			uav:       uavId,
@@ -163,46 +181,39 @@ func (mutm *MannaUtmClient) CreateOperationalIntent(ctx context.Context, uavId i
 	}()
 
 	errChannel := make(chan error, 1)
+	var response *OperationalIntentResponse
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		resp, err := mutm.c.Do(req)
 		if err != nil {
-			if resp == nil {
-				errChannel <- &MannaUtmError{StatusCode: 0, Body: err.Error()}
-				return
-			}
-
-			errJson, err := json.Marshal(err.Error())
-			if err != nil {
-				errChannel <- fmt.Errorf("error trying to parse error response from manna-utm: %v", err)
-				return
-			}
-			errChannel <- &MannaUtmError{StatusCode: resp.StatusCode, Body: string(errJson)}
+			errChannel <- requestError(err)
 			return
 		}
 		defer resp.Body.Close()
+		span.SetAttributes(tracing.Int(tracing.AttrStatusCode, resp.StatusCode))
 
 		// Handle non-2xx responses with useful errors
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			// read a limited amount so you don’t blow memory on huge error bodies
-			b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
-			errChannel <- &MannaUtmError{StatusCode: resp.StatusCode, Body: string(b)}
+			errChannel <- responseError(resp)
 			return
 		} else {
 			log.Infof("successfully created operational intent in manna-utm")
 		}
 
-		return
+		response, err = readOperationalIntentResponse(resp)
+		if err != nil {
+			errChannel <- err
+		}
 	}()
 
 	wg.Wait()
 	select {
 	case err := <-errChannel:
-		return err
+		return nil, err
 	default:
-		return nil
+		return response, nil
 	}
 }
 
@@ -225,64 +236,154 @@ func (eoir IdAndTimeRecord) getMissionId() string {
 // NOTE that the UTMController class specifies the argument to this method as the 'operationId'.
 // In InterUSS, there are only entityIds, and there can be 1 entity for 1 operation.
 // When this is routed to InterUSS, manna-utm will remove all versions of the entity (specified by OVN) from the DSS.
-func (mutm *MannaUtmClient) EndOperationalIntent(ctx context.Context, missionId string) error {
-	requestUrl, err := url.JoinPath(mutm.baseUrl.String(), path.Join("/operationalintent", missionId, "end"))
+func (mutm *MannaUtmClient) EndOperationalIntent(ctx context.Context, missionId string) (*OperationalIntentResponse, error) {
+	return mutm.transitionOperationalIntent(ctx, missionId, "end")
+}
+
+// CancelOperationalIntent cancels the operational intent associated with
+// <missionId>, which is in state <from>, before it has been activated.
+//
+// NOTE that the UTMController has no route to cancel an operational intent.
+// A cancellation is validated with utm.ValidateCancellation and then sent as
+// an end, which removes the entity from the DSS all the same.
+func (mutm *MannaUtmClient) CancelOperationalIntent(ctx context.Context, missionId string, from utm.OperationalIntentState) (*OperationalIntentResponse, error) {
+	if err := utm.ValidateCancellation(from); err != nil {
+		return nil, err
+	}
+	return mutm.EndOperationalIntent(ctx, missionId)
+}
+
+// transitionOperationalIntent requests manna-utm to perform <action> on the
+// operational intent associated with <missionId>, with
+// PUT /operationalintent/<missionId>/<action>.
+func (mutm *MannaUtmClient) transitionOperationalIntent(ctx context.Context, missionId string, action string) (_ *OperationalIntentResponse, err error) {
+	requestUrl, err := url.JoinPath(mutm.baseUrl.String(), path.Join("/operationalintent", missionId, action))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	ctx, span := transport.StartSpan(ctx, "PUT", requestUrl, tracing.String(tracing.AttrEntityId, missionId))
+	defer func() { span.End(err) }()
 
 	req, err := http.NewRequestWithContext(ctx, "PUT", requestUrl, nil)
 	if err != nil {
-		log.Errorf("An error occurred attempting to create operational intent in manna-utm: %v", err)
-		return err
+		log.Errorf("An error occurred attempting to %s operational intent in manna-utm: %v", action, err)
+		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", mutm.UserAgent)
 	req.Header.Set("Content-Type", "application/json")
+	if err := auth.SetAuthorizationHeader(ctx, req, mutm.TokenSource, auth.ScopeStrategicCoordination); err != nil {
+		return nil, err
+	}
 
-	mutm.logRequestContents(*req, IdAndTimeRecord{startTime: time.Now(), missionId: missionId})
+	mutm.logRequestContents(req, IdAndTimeRecord{startTime: time.Now(), missionId: missionId})
 
 	resp, err := mutm.c.Do(req)
 	if err != nil {
-		if resp == nil {
-			return &MannaUtmError{StatusCode: 0, Body: err.Error()}
-		}
-
-		errJson, err := json.Marshal(err.Error())
-		if err != nil {
-			return fmt.Errorf("error trying to parse error response from manna-utm: %v", err)
-		}
-		return &MannaUtmError{StatusCode: resp.StatusCode, Body: string(errJson)}
+		return nil, requestError(err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(tracing.Int(tracing.AttrStatusCode, resp.StatusCode))
 
 	// Handle non-2xx responses with useful errors
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// read a limited amount so you don’t blow memory on huge error bodies
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
-		return &MannaUtmError{StatusCode: resp.StatusCode, Body: string(b)}
+		return nil, responseError(resp)
 	} else {
-		log.Infof("successfully ended operational intent in manna-utm")
+		log.Infof("successfully performed %s on operational intent in manna-utm", action)
 	}
 
-	return nil
+	return readOperationalIntentResponse(resp)
+}
+
+// OperationalIntentResponse is the response of manna-utm to a request that
+// changes an operational intent.
+type OperationalIntentResponse struct {
+	StatusCode int
+	Body       json.RawMessage
 }
 
+// Ovn returns the OVN of the operational intent in the response, if manna-utm
+// returned one either at the top level or in the operational intent reference.
+func (r *OperationalIntentResponse) Ovn() string {
+	var body struct {
+		Ovn       string `json:"ovn"`
+		Reference struct {
+			Ovn string `json:"ovn"`
+		} `json:"reference"`
+	}
+	if err := json.Unmarshal(r.Body, &body); err != nil {
+		return ""
+	}
+	if body.Ovn != "" {
+		return body.Ovn
+	}
+	return body.Reference.Ovn
+}
+
+// Version returns the version of the operational intent in the response, or 0
+// if manna-utm did not return one.
+func (r *OperationalIntentResponse) Version() int {
+	var body struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(r.Body, &body); err != nil {
+		return 0
+	}
+	return body.Version
+}
+
+func readOperationalIntentResponse(resp *http.Response) (*OperationalIntentResponse, error) {
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return nil, requestError(err)
+	}
+	if !json.Valid(b) {
+		// keep non-JSON responses, so that they can still be recorded
+		b, _ = json.Marshal(string(b))
+	}
+	return &OperationalIntentResponse{StatusCode: resp.StatusCode, Body: b}, nil
+}
+
+// MannaUtmError is a failed request to manna-utm. <Err> is the
+// transport.ClientError or transport.ServerError of an error response, or the
+// error of a request that received no response, e.g. a transport.NetworkError.
 type MannaUtmError struct {
 	StatusCode int
 	Body       string
+	Err        error
 }
 
 func (m MannaUtmError) Error() string {
+	if m.StatusCode == 0 && m.Err != nil {
+		return fmt.Sprintf("manna-utm error: %v", m.Err)
+	}
 	return fmt.Sprintf("manna-utm error: status=%d body=%q", m.StatusCode, m.Body)
 }
 
+func (m MannaUtmError) Unwrap() error {
+	return m.Err
+}
+
+// responseError reads the body of the error response <resp> into a
+// MannaUtmError.
+func responseError(resp *http.Response) *MannaUtmError {
+	// read a limited amount so you don’t blow memory on huge error bodies
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return &MannaUtmError{StatusCode: resp.StatusCode, Body: string(b), Err: transport.ResponseError(resp.StatusCode, string(b))}
+}
+
+// requestError is the MannaUtmError of a request that failed with <err>
+// before a full response was received.
+func requestError(err error) *MannaUtmError {
+	return &MannaUtmError{Body: err.Error(), Err: err}
+}
+
 type HasTimeAndMissionId interface {
 	getTime() time.Time
 	getMissionId() string
 }
 
-func (mutm *MannaUtmClient) logRequestContents(r http.Request, request HasTimeAndMissionId) {
+func (mutm *MannaUtmClient) logRequestContents(r *http.Request, request HasTimeAndMissionId) {
 	reqDirName := "./.requests"
 	// ensure outDir exists
 	err := os.MkdirAll(reqDirName, os.ModePerm)
@@ -290,7 +391,13 @@ func (mutm *MannaUtmClient) logRequestContents(r http.Request, request HasTimeAn
 		log.Errorf("an error occurred creating requests output directory: %v", err)
 	}
 
-	dump, err := httputil.DumpRequest(&r, true)
+	// never write bearer tokens to the logs or request files
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		r.Header.Set("Authorization", "Bearer <redacted>")
+		defer r.Header.Set("Authorization", authorization)
+	}
+
+	dump, err := httputil.DumpRequest(r, true)
 	if err != nil {
 		log.Debugf("dump error: %v", err)
 		return
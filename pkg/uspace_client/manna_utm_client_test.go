@@ -0,0 +1,184 @@
+package uspace_client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/mock_utm_server"
+	"manna.aero/manna.utm.cli/pkg/transport"
+)
+
+func TestQuery4dVolume(t *testing.T) {
+	t.Chdir(t.TempDir())
+	start := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	vol := uspace.Volume4d{
+		TimeStart:     start,
+		TimeEnd:       start.Add(10 * time.Minute),
+		AltitudeLower: 0,
+		AltitudeUpper: 120,
+		Polygon:       orb.Polygon{{{46.19, 6.12}, {46.20, 6.12}, {46.20, 6.13}, {46.19, 6.12}}},
+	}
+
+	var response string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST /operationalintent/query", r.Method+" "+r.URL.Path)
+		// the query is a U-Space volume
+		var query map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+		assert.EqualValues(t, start.UnixMilli(), query["time_start"])
+		assert.Len(t, query["polygon"], 3)
+		w.Write([]byte(response))
+	}))
+	defer srv.Close()
+
+	client, err := NewMannaUtmClient(srv.URL, nil, transport.DefaultPolicy(), false)
+	require.NoError(t, err)
+
+	// the operational intents are returned as ASTM F3548-21 details
+	response = `[
+		{
+			"priority": 10,
+			"volumes": [{
+				"volume": {
+					"outline_polygon": {"vertices": [{"lat": 46.19, "lng": 6.12}, {"lat": 46.20, "lng": 6.12}, {"lat": 46.20, "lng": 6.13}]},
+					"altitude_lower": {"value": 0, "reference": "W84", "units": "M"},
+					"altitude_upper": {"value": 120, "reference": "W84", "units": "M"}
+				},
+				"time_start": {"value": "2026-10-18T10:00:00Z", "format": "RFC3339"},
+				"time_end": {"value": "2026-10-18T10:10:00Z", "format": "RFC3339"}
+			}],
+			"off_nominal_volumes": []
+		},
+		{
+			"priority": 20,
+			"volumes": [{
+				"volume": {
+					"outline_circle": {"center": {"lat": 46.195, "lng": 6.125}, "radius": {"value": 300, "units": "M"}},
+					"altitude_upper": {"value": 60, "reference": "W84", "units": "M"}
+				},
+				"time_start": {"value": "2026-10-18T10:05:00Z", "format": "RFC3339"}
+			}]
+		}
+	]`
+	ois, err := client.Query4dVolume(context.Background(), "query", vol)
+	require.NoError(t, err)
+	require.Len(t, ois, 2)
+
+	assert.Equal(t, uint16(10), ois[0].Priority)
+	require.Len(t, ois[0].Volumes, 1)
+	a := ois[0].Volumes[0]
+	assert.Equal(t, orb.Ring{{6.12, 46.19}, {6.12, 46.20}, {6.13, 46.20}, {6.12, 46.19}}, a.Volume.OutlinePolygon[0])
+	assert.Equal(t, 120.0, a.Volume.AltitudeUpper)
+	assert.True(t, start.Equal(a.TimeStart))
+	assert.True(t, start.Add(10*time.Minute).Equal(a.TimeEnd))
+
+	require.Len(t, ois[1].Volumes, 1)
+	b := ois[1].Volumes[0]
+	require.NotNil(t, b.Volume.OutlineCircle)
+	assert.Equal(t, 300.0, b.Volume.OutlineCircle.Radius.Value)
+	assert.Equal(t, 60.0, b.Volume.AltitudeUpper)
+	assert.True(t, b.TimeEnd.IsZero())
+
+	// an empty response is no operational intents
+	response = ""
+	ois, err = client.Query4dVolume(context.Background(), "query", vol)
+	require.NoError(t, err)
+	assert.Empty(t, ois)
+
+	// as is an empty array
+	response = "[]"
+	ois, err = client.Query4dVolume(context.Background(), "query", vol)
+	require.NoError(t, err)
+	assert.Empty(t, ois)
+
+	// the volumes of the old U-Space shape cannot be decoded
+	response = `[{"priority": 10, "volumes": [{"volume": {"outline_polygon": [[46.19, 6.12]]}}]}]`
+	_, err = client.Query4dVolume(context.Background(), "query", vol)
+	assert.ErrorContains(t, err, "failed to decode operational intents returned by manna-utm")
+}
+
+func TestQuery4dVolumeError(t *testing.T) {
+	t.Chdir(t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid volume", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	client, err := NewMannaUtmClient(srv.URL, nil, transport.DefaultPolicy(), false)
+	require.NoError(t, err)
+
+	_, err = client.Query4dVolume(context.Background(), "query", uspace.Volume4d{Polygon: orb.Polygon{{{46.19, 6.12}, {46.20, 6.12}, {46.19, 6.12}}}})
+	var clientErr *transport.ClientError
+	require.True(t, errors.As(err, &clientErr), "expected a client error, got %v", err)
+	assert.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+	var mutmErr *MannaUtmError
+	require.True(t, errors.As(err, &mutmErr))
+	assert.Contains(t, mutmErr.Body, "invalid volume")
+}
+
+func TestQuery4dVolumeMockUtm(t *testing.T) {
+	t.Chdir(t.TempDir())
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(mock_utm_server.GetServer())
+	defer srv.Close()
+	ctx := context.Background()
+	start := time.Now().Truncate(time.Millisecond)
+
+	client, err := NewMannaUtmClient(srv.URL, nil, transport.DefaultPolicy(), false)
+	require.NoError(t, err)
+	intent := intentAt(46.19, 6.12, start)
+	_, err = client.CreateOperationalIntent(ctx, 1, entityA, intent)
+	require.NoError(t, err)
+
+	// the U-Space volume sent is returned as an ASTM F3548-21 volume
+	ois, err := client.Query4dVolume(ctx, "query", intent.Volumes[0])
+	require.NoError(t, err)
+	require.Len(t, ois, 1)
+	assert.Equal(t, intent.Priority, ois[0].Priority)
+	require.Len(t, ois[0].Volumes, 1)
+	vol := ois[0].Volumes[0]
+	require.Len(t, vol.Volume.OutlinePolygon, 1)
+	for i, p := range intent.Volumes[0].Polygon[0] {
+		assert.InDelta(t, p[0], vol.Volume.OutlinePolygon[0][i][1], 1e-5, "latitude of vertex %d", i)
+		assert.InDelta(t, p[1], vol.Volume.OutlinePolygon[0][i][0], 1e-5, "longitude of vertex %d", i)
+	}
+	assert.Equal(t, 120.0, vol.Volume.AltitudeUpper)
+	assert.True(t, start.Equal(vol.TimeStart))
+	assert.True(t, start.Add(10*time.Minute).Equal(vol.TimeEnd))
+}
+
+func TestCancelOperationalIntent(t *testing.T) {
+	t.Chdir(t.TempDir())
+	var routes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routes = append(routes, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{"ovn": "ovn-2"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewMannaUtmClient(srv.URL, nil, transport.DefaultPolicy(), false)
+	require.NoError(t, err)
+
+	// a cancellation is sent as an end, the only transition route of manna-utm
+	resp, err := client.CancelOperationalIntent(context.Background(), "a3b5c7d9-0000-4000-8000-00000000000a", utm.StateAccepted)
+	require.NoError(t, err)
+	assert.Equal(t, "ovn-2", resp.Ovn())
+	assert.Equal(t, []string{"PUT /operationalintent/a3b5c7d9-0000-4000-8000-00000000000a/end"}, routes)
+
+	// an activated operational intent is rejected before any request is sent
+	_, err = client.CancelOperationalIntent(context.Background(), "a3b5c7d9-0000-4000-8000-00000000000a", utm.StateActivated)
+	var invalid *utm.InvalidTransitionError
+	assert.ErrorAs(t, err, &invalid)
+	assert.Len(t, routes, 1)
+}
@@ -4,20 +4,27 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 
 	log "github.com/sirupsen/logrus"
 	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/pkg/auth"
+	"manna.aero/manna.utm.cli/pkg/tracing"
+	"manna.aero/manna.utm.cli/pkg/transport"
 )
 
 // SendTelemetry interfaces with the manna-utm telemetry interface
 //
 // see https://github.com/m4a3/manna-utm/blob/persistence/src/main/java/manna/aero/utm/controller/UTMController.java#L165-L197
-func (mutm *MannaUtmClient) SendTelemetry(ctx context.Context, message *uspace.Telemetry, missionId string, uavId int) error {
+func (mutm *MannaUtmClient) SendTelemetry(ctx context.Context, message *uspace.Telemetry, missionId string, uavId int) (err error) {
 
-	requestUrl := fmt.Sprintf("%s/ussClient/v1/operational_intents/%s", mutm.baseUrl, missionId)
+	requestUrl, err := url.JoinPath(mutm.baseUrl.String(), "/ussClient/v1/operational_intents", missionId)
+	if err != nil {
+		return err
+	}
+	ctx, span := transport.StartSpan(ctx, "POST", requestUrl, tracing.String(tracing.AttrEntityId, missionId), tracing.Int(tracing.AttrUavId, uavId))
+	defer func() { span.End(err) }()
 
 	messageContents, err := json.Marshal(message)
 	if err != nil {
@@ -29,23 +36,20 @@ func (mutm *MannaUtmClient) SendTelemetry(ctx context.Context, message *uspace.T
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", mutm.UserAgent)
+	if err := auth.SetAuthorizationHeader(ctx, req, mutm.TokenSource, auth.ScopeConformanceMonitoringSA); err != nil {
+		return err
+	}
 
 	resp, err := mutm.c.Do(req)
 	if err != nil {
-		return MannaUtmError{StatusCode: resp.StatusCode, Body: err.Error()}
+		return requestError(err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(tracing.Int(tracing.AttrStatusCode, resp.StatusCode))
 
 	// Handle non-2xx responses with useful errors
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// read a limited amount so you don’t blow memory on huge error bodies
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
-		return MannaUtmError{StatusCode: resp.StatusCode, Body: string(b)}
-	}
-
-	resp, err = mutm.c.Do(req)
-	if err != nil {
-		return MannaUtmError{StatusCode: resp.StatusCode, Body: err.Error()}
+		return responseError(resp)
 	}
 
 	return nil
@@ -0,0 +1,226 @@
+package uspace_client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/auth"
+	"manna.aero/manna.utm.cli/pkg/tracing"
+	"manna.aero/manna.utm.cli/pkg/transport"
+)
+
+// CurrentOperationalIntent is the operational intent that manna-utm holds for
+// an entity.
+type CurrentOperationalIntent struct {
+	EntityId string                     `json:"entity_id"`
+	UavId    int                        `json:"uav_id"`
+	Ovn      string                     `json:"ovn"`
+	Version  int                        `json:"version"`
+	State    utm.OperationalIntentState `json:"state"`
+	Intent   *uspace.OperationalIntent  `json:"intent"`
+}
+
+// OperationalIntentConflictError is returned when manna-utm rejects an update
+// with 409 Conflict, either because <Ovn> is no longer the OVN of the
+// operational intent, or because the volumes at the indices in
+// <CollidingVolumes> intersect other operational intents.
+type OperationalIntentConflictError struct {
+	EntityId string
+	Ovn      string
+	// CurrentOvn is the OVN of the operational intent when the update was
+	// rejected, if it could be fetched.
+	CurrentOvn       string
+	CollidingVolumes []int
+	// CollidingIntents are the entity ids of the operational intents that the
+	// volumes collide with, if the response gave them, as the query of a
+	// volume does not identify the operational intents it returns.
+	CollidingIntents []string
+	Cause            *MannaUtmError
+}
+
+// Stale reports whether the update was rejected because it was not made
+// against the current OVN of the operational intent.
+func (e *OperationalIntentConflictError) Stale() bool {
+	return e.CurrentOvn != "" && e.CurrentOvn != e.Ovn
+}
+
+func (e *OperationalIntentConflictError) Error() string {
+	switch {
+	case e.Stale():
+		return fmt.Sprintf("operational intent %s has changed since OVN %s, its OVN is now %s", e.EntityId, e.Ovn, e.CurrentOvn)
+	case len(e.CollidingVolumes) > 0 && len(e.CollidingIntents) > 0:
+		return fmt.Sprintf("volumes %v of operational intent %s collide with operational intents %v", e.CollidingVolumes, e.EntityId, e.CollidingIntents)
+	case len(e.CollidingVolumes) > 0:
+		return fmt.Sprintf("volumes %v of operational intent %s collide with other operational intents", e.CollidingVolumes, e.EntityId)
+	default:
+		return fmt.Sprintf("update of operational intent %s conflicts: %v", e.EntityId, e.Cause)
+	}
+}
+
+func (e *OperationalIntentConflictError) Unwrap() error {
+	return e.Cause
+}
+
+// GetOperationalIntent fetches the operational intent that mock-utm holds for
+// <entityId>, with GET /operationalintent/<entityId>.
+//
+// NOTE that this is a route of mock-utm only, the UTMController of manna-utm
+// has no route to fetch an operational intent.
+func (mutm *MannaUtmClient) GetOperationalIntent(ctx context.Context, entityId string) (_ *CurrentOperationalIntent, err error) {
+	requestUrl, err := url.JoinPath(mutm.baseUrl.String(), path.Join("/operationalintent", entityId))
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := transport.StartSpan(ctx, "GET", requestUrl, tracing.String(tracing.AttrEntityId, entityId))
+	defer func() { span.End(err) }()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", requestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", mutm.UserAgent)
+	if err := auth.SetAuthorizationHeader(ctx, req, mutm.TokenSource, auth.ScopeStrategicCoordination); err != nil {
+		return nil, err
+	}
+
+	resp, err := mutm.c.Do(req)
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(tracing.Int(tracing.AttrStatusCode, resp.StatusCode))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, responseError(resp)
+	}
+
+	var current CurrentOperationalIntent
+	if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		return nil, fmt.Errorf("failed to decode operational intent returned by manna-utm: %w", err)
+	}
+	return &current, nil
+}
+
+// UpdateOperationalIntent replaces the volumes and priority of the operational
+// intent of <entityId> in mock-utm with those of <intent>, with
+// PUT /operationalintent/<entityId>. <ovn> and <version> are those of the
+// operational intent being replaced.
+//
+// NOTE that this is a route of mock-utm only, the UTMController of manna-utm
+// has no route to update an operational intent.
+//
+// When the update is rejected with 409 Conflict, an
+// OperationalIntentConflictError reports whether the OVN was stale or which
+// volumes of <intent> collided, see explainConflict.
+func (mutm *MannaUtmClient) UpdateOperationalIntent(ctx context.Context, entityId string, ovn string, version int, intent *uspace.OperationalIntent) (_ *OperationalIntentResponse, err error) {
+	requestUrl, err := url.JoinPath(mutm.baseUrl.String(), path.Join("/operationalintent", entityId))
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := transport.StartSpan(ctx, "PUT", requestUrl, tracing.String(tracing.AttrEntityId, entityId))
+	defer func() { span.End(err) }()
+
+	update := &uspace.OperationalIntentUpdate{Ovn: ovn, Version: version, OperationalIntent: intent}
+	reader, err := update.ToReader()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", requestUrl, reader)
+	if err != nil {
+		log.Errorf("An error occurred attempting to update operational intent in manna-utm: %v", err)
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", mutm.UserAgent)
+	req.Header.Set("Content-Type", "application/json")
+	if err := auth.SetAuthorizationHeader(ctx, req, mutm.TokenSource, auth.ScopeStrategicCoordination); err != nil {
+		return nil, err
+	}
+
+	mutm.logRequestContents(req, IdAndTimeRecord{startTime: time.Now(), missionId: entityId})
+
+	resp, err := mutm.c.Do(req)
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(tracing.Int(tracing.AttrStatusCode, resp.StatusCode))
+
+	if resp.StatusCode == http.StatusConflict {
+		return nil, mutm.explainConflict(ctx, entityId, ovn, intent, responseError(resp))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, responseError(resp)
+	}
+
+	log.Infof("successfully updated operational intent in manna-utm")
+	return readOperationalIntentResponse(resp)
+}
+
+// explainConflict finds why the update of the operational intent of
+// <entityId> against <ovn> to <intent> was rejected with the 409 response
+// <cause>. The current operational intent is fetched again: if its OVN is not
+// <ovn>, the update was stale. Otherwise each volume of <intent> is queried,
+// and it collides if the query returns an operational intent of the same or a
+// higher priority other than the current one. When the body of <cause> gives
+// the current OVN or the colliding volumes, as that of mock-utm does, they are
+// used instead.
+func (mutm *MannaUtmClient) explainConflict(ctx context.Context, entityId string, ovn string, intent *uspace.OperationalIntent, cause *MannaUtmError) error {
+	conflict := &OperationalIntentConflictError{EntityId: entityId, Ovn: ovn, Cause: cause}
+
+	var body struct {
+		Ovn                string   `json:"ovn"`
+		ConflictingVolumes []int    `json:"conflicting_volumes"`
+		ConflictingIntents []string `json:"conflicting_intents"`
+	}
+	if err := json.Unmarshal([]byte(cause.Body), &body); err == nil && (body.Ovn != "" || len(body.ConflictingVolumes) > 0) {
+		conflict.CurrentOvn = body.Ovn
+		conflict.CollidingVolumes = body.ConflictingVolumes
+		conflict.CollidingIntents = body.ConflictingIntents
+		return conflict
+	}
+
+	current, err := mutm.GetOperationalIntent(ctx, entityId)
+	if err != nil {
+		log.Warnf("unable to fetch operational intent %s to explain the conflict: %v", entityId, err)
+	} else {
+		conflict.CurrentOvn = current.Ovn
+		if conflict.Stale() {
+			return conflict
+		}
+	}
+
+	for i, vol := range intent.Volumes {
+		found, err := mutm.Query4dVolume(ctx, fmt.Sprintf("%s-conflict-%d", entityId, i), vol)
+		if err != nil {
+			log.Warnf("unable to query volume %d of operational intent %s to explain the conflict: %v", i, entityId, err)
+			continue
+		}
+		others := 0
+		for _, details := range found {
+			if details.Priority >= intent.Priority {
+				others++
+			}
+		}
+		// the query does not identify the operational intents it returns, the
+		// current one is among them if it intersects the volume
+		if current != nil && current.State != utm.StateEnded && current.Intent != nil &&
+			current.Intent.Priority >= intent.Priority && current.Intent.Intersects(vol) {
+			others--
+		}
+		if others > 0 {
+			conflict.CollidingVolumes = append(conflict.CollidingVolumes, i)
+		}
+	}
+	return conflict
+}
@@ -0,0 +1,133 @@
+package uspace_client
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"manna.aero/manna.utm.cli/model/uspace"
+	"manna.aero/manna.utm.cli/pkg/mock_utm_server"
+	"manna.aero/manna.utm.cli/pkg/transport"
+)
+
+const (
+	entityA = "a3b5c7d9-0000-4000-8000-00000000000a"
+	entityB = "a3b5c7d9-0000-4000-8000-00000000000b"
+)
+
+// intentAt is an operational intent with a volume over the [lat, lng] square
+// of side 0.01 degrees from <lat>, <lng>.
+func intentAt(lat float64, lng float64, start time.Time) *uspace.OperationalIntent {
+	return &uspace.OperationalIntent{
+		Priority:      10,
+		DepartureTime: start,
+		Volumes: []uspace.Volume4d{{
+			TimeStart:     start,
+			TimeEnd:       start.Add(10 * time.Minute),
+			AltitudeUpper: 120,
+			Polygon:       orb.Polygon{{{lat, lng}, {lat + 0.01, lng}, {lat + 0.01, lng + 0.01}, {lat, lng + 0.01}, {lat, lng}}},
+		}},
+	}
+}
+
+func TestUpdateOperationalIntentConflicts(t *testing.T) {
+	t.Chdir(t.TempDir())
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(mock_utm_server.GetServer())
+	defer srv.Close()
+	ctx := context.Background()
+	start := time.Now().Truncate(time.Millisecond)
+
+	client, err := NewMannaUtmClient(srv.URL, nil, transport.DefaultPolicy(), false)
+	require.NoError(t, err)
+	created, err := client.CreateOperationalIntent(ctx, 1, entityA, intentAt(46.19, 6.12, start))
+	require.NoError(t, err)
+	_, err = client.CreateOperationalIntent(ctx, 2, entityB, intentAt(46.30, 6.30, start))
+	require.NoError(t, err)
+
+	// volumes moved into the airspace of another operational intent collide
+	// with it, but not with the operational intent being updated
+	_, err = client.UpdateOperationalIntent(ctx, entityA, created.Ovn(), 1, intentAt(46.305, 6.305, start))
+	var conflict *OperationalIntentConflictError
+	require.True(t, errors.As(err, &conflict), "expected a conflict, got %v", err)
+	assert.False(t, conflict.Stale())
+	assert.Equal(t, []int{0}, conflict.CollidingVolumes)
+	assert.Equal(t, []string{entityB}, conflict.CollidingIntents)
+
+	updated, err := client.UpdateOperationalIntent(ctx, entityA, created.Ovn(), 1, intentAt(46.195, 6.125, start))
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.Version())
+
+	// an update against the OVN it replaced is stale
+	_, err = client.UpdateOperationalIntent(ctx, entityA, created.Ovn(), 1, intentAt(46.19, 6.12, start))
+	require.True(t, errors.As(err, &conflict), "expected a conflict, got %v", err)
+	assert.True(t, conflict.Stale())
+	assert.Equal(t, updated.Ovn(), conflict.CurrentOvn)
+	assert.Empty(t, conflict.CollidingVolumes)
+
+	current, err := client.GetOperationalIntent(ctx, entityA)
+	require.NoError(t, err)
+	assert.Equal(t, updated.Ovn(), current.Ovn)
+}
+
+// bareConflicts is <h> with the body of its 409 responses to updates replaced
+// by one that explains nothing, as the conflicts of manna-utm do.
+func bareConflicts(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if r.Method == http.MethodPut && rec.Code == http.StatusConflict {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "conflict"}`))
+			return
+		}
+		maps.Copy(w.Header(), rec.Header())
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	})
+}
+
+func TestUpdateOperationalIntentConflictsRefetched(t *testing.T) {
+	t.Chdir(t.TempDir())
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(bareConflicts(mock_utm_server.GetServer()))
+	defer srv.Close()
+	ctx := context.Background()
+	start := time.Now().Truncate(time.Millisecond)
+
+	client, err := NewMannaUtmClient(srv.URL, nil, transport.DefaultPolicy(), false)
+	require.NoError(t, err)
+	created, err := client.CreateOperationalIntent(ctx, 1, entityA, intentAt(46.19, 6.12, start))
+	require.NoError(t, err)
+	_, err = client.CreateOperationalIntent(ctx, 2, entityB, intentAt(46.30, 6.30, start))
+	require.NoError(t, err)
+
+	// the second volume is moved into the airspace of another operational
+	// intent, the first overlaps only the operational intent being updated
+	moved := intentAt(46.195, 6.125, start)
+	moved.Volumes = append(moved.Volumes, intentAt(46.305, 6.305, start).Volumes...)
+	_, err = client.UpdateOperationalIntent(ctx, entityA, created.Ovn(), 1, moved)
+	var conflict *OperationalIntentConflictError
+	require.True(t, errors.As(err, &conflict), "expected a conflict, got %v", err)
+	assert.False(t, conflict.Stale())
+	assert.Equal(t, created.Ovn(), conflict.CurrentOvn)
+	assert.Equal(t, []int{1}, conflict.CollidingVolumes)
+	assert.Empty(t, conflict.CollidingIntents)
+
+	updated, err := client.UpdateOperationalIntent(ctx, entityA, created.Ovn(), 1, intentAt(46.195, 6.125, start))
+	require.NoError(t, err)
+
+	// the current OVN is fetched again
+	_, err = client.UpdateOperationalIntent(ctx, entityA, created.Ovn(), 1, intentAt(46.19, 6.12, start))
+	require.True(t, errors.As(err, &conflict), "expected a conflict, got %v", err)
+	assert.True(t, conflict.Stale())
+	assert.Equal(t, updated.Ovn(), conflict.CurrentOvn)
+}
@@ -1,123 +1,233 @@
 package uss_client
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"path"
 	"strings"
-	"time"
 
 	log "github.com/sirupsen/logrus"
 	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/auth"
+	"manna.aero/manna.utm.cli/pkg/tracing"
+	"manna.aero/manna.utm.cli/pkg/transport"
 )
 
 type UssClient struct {
 	ussBaseUrl *url.URL
 	c          *http.Client
 	UserAgent  string
+	// TokenSource provides the bearer tokens sent with each request, no
+	// Authorization header is sent when it is nil.
+	TokenSource auth.TokenSource
 }
 
-func NewUssClient(base string) (*UssClient, error) {
+// NewUssClient creates a client for the USS at <base>, which may include a
+// path prefix, sending requests with the retries and timeouts of <policy>.
+// <tlsConfig> may be nil to use the default TLS settings.
+func NewUssClient(base string, tlsConfig *tls.Config, policy transport.Policy) (*UssClient, error) {
 	u, err := url.Parse(strings.TrimRight(base, "/") + "/")
 	if err != nil {
 		return nil, err
 	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported USS url scheme %q, expected http or https", u.Scheme)
+	}
+
 	return &UssClient{
 		ussBaseUrl: u,
-		c: &http.Client{
-			Timeout: 15 * time.Second,
-		},
-		UserAgent: "manna-utm-cli",
+		c:          transport.NewClient(tlsConfig, policy),
+		UserAgent:  "manna-utm-cli",
 	}, nil
 }
 
+// UssClientError is a failed request to a USS. <Err> is the
+// transport.ClientError or transport.ServerError of an error response, or the
+// error of a request that received no response, e.g. a transport.NetworkError.
 type UssClientError struct {
 	StatusCode int
 	Body       string
+	Err        error
 }
 
 func (e *UssClientError) Error() string {
+	if e.StatusCode == 0 && e.Err != nil {
+		return fmt.Sprintf("uss client error: %v", e.Err)
+	}
 	return fmt.Sprintf("uss client error: status=%d body=%q", e.StatusCode, e.Body)
 }
 
-// GetOperationalIntentDetailsByEntityId from the USS.
-func (ussClient *UssClient) GetOperationalIntentDetailsByEntityId(ctx context.Context, ussPort int, entityId string) (*utm.OperationalIntentDetails, error) {
-	requestUrl := fmt.Sprintf("http://localhost:%d/%s", ussPort, path.Join("/uss/v1/operational_intents", entityId))
+func (e *UssClientError) Unwrap() error {
+	return e.Err
+}
+
+// responseError reads the body of the error response <resp> into a
+// UssClientError.
+func responseError(resp *http.Response) *UssClientError {
+	// read a limited amount so you don’t blow memory on huge error bodies
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return &UssClientError{StatusCode: resp.StatusCode, Body: string(b), Err: transport.ResponseError(resp.StatusCode, string(b))}
+}
+
+// requestError is the UssClientError of a request that failed with <err>
+// before a full response was received.
+func requestError(err error) *UssClientError {
+	return &UssClientError{Body: err.Error(), Err: err}
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", requestUrl, nil)
+// GetOperationalIntentDetails gets the operational intent <entityId> managed
+// by the USS (getOperationalIntentDetails).
+func (ussClient *UssClient) GetOperationalIntentDetails(ctx context.Context, entityId string) (*utm.OperationalIntent, error) {
+	var resp utm.GetOperationalIntentDetailsResponse
+	err := ussClient.do(ctx, http.MethodGet, auth.ScopeStrategicCoordination, nil, &resp, entity(entityId), "operational_intents", entityId)
 	if err != nil {
-		log.Errorf("An error occurred fetching operational intent operationalIntentDetails from USS: %v", err)
+		return nil, err
 	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("User-Agent", ussClient.UserAgent)
-	req.Header.Set("Content-Type", "application/json")
+	return &resp.OperationalIntent, nil
+}
 
-	resp, err := ussClient.c.Do(req)
-	if err != nil && resp != nil { // client returned err
-		return nil, &UssClientError{StatusCode: resp.StatusCode, Body: err.Error()}
-	} else if err != nil { // err while trying to execute the request
-		return nil, fmt.Errorf("an error occurred while trying to execute request: %v", err)
+// GetOperationalIntentTelemetry gets the latest telemetry of the operational
+// intent <entityId> managed by the USS (getOperationalIntentTelemetry).
+func (ussClient *UssClient) GetOperationalIntentTelemetry(ctx context.Context, entityId string) (*utm.OperationalIntentTelemetry, error) {
+	var resp utm.OperationalIntentTelemetry
+	err := ussClient.do(ctx, http.MethodGet, auth.ScopeConformanceMonitoringSA, nil, &resp, entity(entityId), "operational_intents", entityId, "telemetry")
+	if err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return &resp, nil
+}
 
-	// Handle non-2xx responses with useful errors
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// read a limited amount so you don’t blow memory on huge error bodies
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
-		return nil, &UssClientError{StatusCode: resp.StatusCode, Body: string(b)}
+// GetOperationalIntentDetailsByEntityId gets the details of the operational
+// intent <entityId> managed by the USS.
+//
+// Deprecated: use GetOperationalIntentDetails, which also returns the
+// reference of the operational intent.
+func (ussClient *UssClient) GetOperationalIntentDetailsByEntityId(ctx context.Context, entityId string) (*utm.OperationalIntentDetails, error) {
+	oi, err := ussClient.GetOperationalIntentDetails(ctx, entityId)
+	if err != nil {
+		return nil, err
 	}
+	return &oi.Details, nil
+}
 
-	b, err := io.ReadAll(resp.Body)
+// GetLatestTelemetryForOperationalIntentByEntityId gets the latest telemetry
+// message for the specified operational intent, from the USS.
+//
+// Deprecated: use GetOperationalIntentTelemetry.
+func (ussClient *UssClient) GetLatestTelemetryForOperationalIntentByEntityId(ctx context.Context, entityId string) (*utm.OperationalIntentTelemetry, error) {
+	return ussClient.GetOperationalIntentTelemetry(ctx, entityId)
+}
+
+// NotifyOperationalIntentDetailsChanged notifies the USS of a change to an
+// operational intent that its <params>.Subscriptions cover
+// (notifyOperationalIntentDetailsChanged).
+func (ussClient *UssClient) NotifyOperationalIntentDetailsChanged(ctx context.Context, params utm.PutOperationalIntentDetailsParameters) error {
+	return ussClient.do(ctx, http.MethodPost, auth.ScopeStrategicCoordination, params, nil, entity(params.OperationalIntentId.String()), "operational_intents")
+}
+
+// GetConstraintDetails gets the constraint <entityId> managed by the USS
+// (getConstraintDetails).
+func (ussClient *UssClient) GetConstraintDetails(ctx context.Context, entityId string) (*utm.Constraint, error) {
+	var resp utm.GetConstraintDetailsResponse
+	err := ussClient.do(ctx, http.MethodGet, auth.ScopeConstraintProcessing, nil, &resp, entity(entityId), "constraints", entityId)
 	if err != nil {
-		return nil, &UssClientError{StatusCode: resp.StatusCode, Body: err.Error()}
+		return nil, err
 	}
+	return &resp.Constraint, nil
+}
 
-	var operationalIntentDetails utm.OperationalIntentDetails
-	if err := json.Unmarshal(b, &operationalIntentDetails); err != nil {
-		return nil, &UssClientError{StatusCode: resp.StatusCode, Body: err.Error()}
+// NotifyConstraintDetailsChanged notifies the USS of a change to a constraint
+// that its <params>.Subscriptions cover (notifyConstraintDetailsChanged).
+func (ussClient *UssClient) NotifyConstraintDetailsChanged(ctx context.Context, params utm.PutConstraintDetailsParameters) error {
+	return ussClient.do(ctx, http.MethodPost, auth.ScopeConstraintManagement, params, nil, entity(params.ConstraintId.String()), "constraints")
+}
+
+// MakeUssReport reports an exchange with the USS that went wrong, returning
+// the report with the id the USS assigned it (makeUssReport).
+func (ussClient *UssClient) MakeUssReport(ctx context.Context, report utm.ErrorReport) (*utm.ErrorReport, error) {
+	var resp utm.ErrorReport
+	err := ussClient.do(ctx, http.MethodPost, auth.ScopeStrategicCoordination, report, &resp, nil, "reports")
+	if err != nil {
+		return nil, err
 	}
+	return &resp, nil
+}
 
-	dec := json.NewDecoder(resp.Body)
-	dec.DisallowUnknownFields() // optional; helps catch API changes
-	if err := dec.Decode(resp.Body); err != nil {
-		return nil, &UssClientError{StatusCode: resp.StatusCode, Body: err.Error()}
+// GetLogs gets the exchanges that the USS has recorded (getLogs).
+func (ussClient *UssClient) GetLogs(ctx context.Context) (*utm.GetLogsResponse, error) {
+	var resp utm.GetLogsResponse
+	err := ussClient.do(ctx, http.MethodGet, auth.ScopeStrategicCoordination, nil, &resp, nil, "logs")
+	if err != nil {
+		return nil, err
 	}
-	return &operationalIntentDetails, nil
+	return &resp, nil
 }
 
-// GetLatestTelemetryForOperationalIntentByEntityId gets the latest telemetry
-// message for the specified operational intent, from the USS.
-func (ussClient *UssClient) GetLatestTelemetryForOperationalIntentByEntityId(ctx context.Context, entityId string) error {
-	requestUrl := fmt.Sprintf("%s/ussClient/v1/operational_intents/%s", ussClient.ussBaseUrl.String(), entityId)
+// entity is the attributes of the span of a request about the operational
+// intent or constraint <entityId>.
+func entity(entityId string) []tracing.Attribute {
+	return []tracing.Attribute{tracing.String(tracing.AttrEntityId, entityId)}
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", requestUrl, nil)
+// do sends a <method> request with the JSON of <body>, if not nil, to the
+// /uss/v1/<path> of the USS, authorized for <scope>, and decodes the response
+// into <out>, if not nil. The request is traced in a span with <attrs>.
+func (ussClient *UssClient) do(ctx context.Context, method string, scope string, body any, out any, attrs []tracing.Attribute, path ...string) (err error) {
+	requestUrl, err := url.JoinPath(ussClient.ussBaseUrl.String(), append([]string{"/uss/v1"}, path...)...)
 	if err != nil {
-		log.Errorf("An error occurred fetching operational intent details from USS: %v", err)
+		return err
+	}
+	ctx, span := transport.StartSpan(ctx, method, requestUrl, attrs...)
+	defer func() { span.End(err) }()
+
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, requestUrl, reqBody)
+	if err != nil {
+		return err
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", ussClient.UserAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if err := auth.SetAuthorizationHeader(ctx, req, ussClient.TokenSource, scope); err != nil {
+		return err
+	}
 
+	log.Debugf("%s %s", method, requestUrl)
 	resp, err := ussClient.c.Do(req)
 	if err != nil {
-		return err
+		return requestError(err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(tracing.Int(tracing.AttrStatusCode, resp.StatusCode))
 
 	// Handle non-2xx responses with useful errors
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// read a limited amount so you don’t blow memory on huge error bodies
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
-		return &UssClientError{StatusCode: resp.StatusCode, Body: string(b)}
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
 	}
 
-	dec := json.NewDecoder(resp.Body)
-	dec.DisallowUnknownFields() // optional; helps catch API changes
-	if err := dec.Decode(resp.Body); err != nil {
-		return err
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return requestError(err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to decode the response of the USS to %s %s: %w", method, requestUrl, err)
 	}
 	return nil
 }
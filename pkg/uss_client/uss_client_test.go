@@ -0,0 +1,177 @@
+package uss_client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"manna.aero/manna.utm.cli/model/utm"
+	"manna.aero/manna.utm.cli/pkg/tracing"
+	"manna.aero/manna.utm.cli/pkg/transport"
+)
+
+func TestGetLatestTelemetryForOperationalIntentByEntityId(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/uss/v1/operational_intents/a3b5c7d9-0000-4000-8000-000000000001/telemetry":
+			w.Write([]byte(`{
+				"operational_intent_id": "a3b5c7d9-0000-4000-8000-000000000001",
+				"telemetry": {
+					"time_measured": {"value": "2026-10-18T10:00:00Z", "format": "RFC3339"},
+					"position": {"longitude": 6.48, "latitude": 46.41, "accuracy_h": "HAUnknown", "accuracy_v": "VAUnknown", "extrapolated": false,
+						"altitude": {"value": 120.5, "reference": "W84", "units": "M"}},
+					"velocity": {"speed": 12.3, "units_speed": "MetersPerSecond", "track": 87.5}
+				},
+				"next_telemetry_opportunity": {"value": "2026-10-18T10:00:05Z", "format": "RFC3339"}
+			}`))
+		default:
+			w.WriteHeader(http.StatusPreconditionFailed)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewUssClient(srv.URL, nil, transport.DefaultPolicy())
+	require.NoError(t, err)
+
+	telemetry, err := client.GetLatestTelemetryForOperationalIntentByEntityId(context.Background(), "a3b5c7d9-0000-4000-8000-000000000001")
+	require.NoError(t, err)
+	require.NotNil(t, telemetry.Telemetry)
+	assert.Equal(t, 46.41, telemetry.Telemetry.Position.Latitude)
+	assert.Equal(t, 120.5, telemetry.Telemetry.Position.Altitude.Value)
+	assert.Equal(t, 87.5, telemetry.Telemetry.Velocity.Track)
+
+	next := time.Date(2026, 10, 18, 10, 0, 5, 0, time.UTC)
+	assert.True(t, next.Equal(telemetry.NextPoll(next.Add(-time.Second), time.Minute)))
+	assert.True(t, next.Add(time.Minute).Equal(telemetry.NextPoll(next, time.Minute)))
+
+	_, err = client.GetLatestTelemetryForOperationalIntentByEntityId(context.Background(), "unknown")
+	var clientErr *transport.ClientError
+	require.True(t, errors.As(err, &clientErr))
+	assert.Equal(t, http.StatusPreconditionFailed, clientErr.StatusCode)
+}
+
+func TestUssApi(t *testing.T) {
+	entityId := uuid.MustParse("a3b5c7d9-0000-4000-8000-000000000002")
+	var notified utm.PutOperationalIntentDetailsParameters
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /uss/v1/operational_intents/{id}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(utm.GetOperationalIntentDetailsResponse{OperationalIntent: utm.OperationalIntent{
+			Reference: utm.OperationalIntentReference{ID: entityId, Version: 2, State: utm.StateActivated, Ovn: "ovn-2", UssBaseUrl: "https://uss.example.com"},
+			Details:   utm.OperationalIntentDetails{Priority: 10},
+		}})
+	})
+	mux.HandleFunc("POST /uss/v1/operational_intents", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notified))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /uss/v1/reports", func(w http.ResponseWriter, r *http.Request) {
+		var report utm.ErrorReport
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&report))
+		report.ReportId = "report-1"
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(report)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client, err := NewUssClient(srv.URL, nil, transport.DefaultPolicy())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	oi, err := client.GetOperationalIntentDetails(ctx, entityId.String())
+	require.NoError(t, err)
+	assert.Equal(t, utm.StateActivated, oi.Reference.State)
+	assert.Equal(t, utm.EntityOvn("ovn-2"), oi.Reference.Ovn)
+	assert.Equal(t, uint16(10), oi.Details.Priority)
+	details, err := client.GetOperationalIntentDetailsByEntityId(ctx, entityId.String())
+	require.NoError(t, err)
+	assert.Equal(t, oi.Details, *details)
+
+	subscriptions := []utm.SubscriptionState{{SubscriptionId: uuid.New(), NotificationIndex: 3}}
+	require.NoError(t, client.NotifyOperationalIntentDetailsChanged(ctx, utm.PutOperationalIntentDetailsParameters{
+		OperationalIntentId: entityId,
+		Subscriptions:       subscriptions,
+	}))
+	assert.Equal(t, entityId, notified.OperationalIntentId)
+	assert.Nil(t, notified.OperationalIntent)
+	assert.Equal(t, subscriptions, notified.Subscriptions)
+
+	report, err := client.MakeUssReport(ctx, utm.ErrorReport{Exchange: utm.ExchangeRecord{
+		Url: srv.URL, Method: http.MethodGet, RecorderRole: utm.RecorderRoleClient, RequestTime: utm.NewTime(time.Now()), ResponseCode: 500,
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "report-1", report.ReportId)
+
+	_, err = client.GetConstraintDetails(ctx, entityId.String())
+	var clientErr *transport.ClientError
+	require.True(t, errors.As(err, &clientErr))
+	assert.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+}
+
+func TestTracing(t *testing.T) {
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(tracing.TraceparentHeader)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	require.NoError(t, tracing.Setup(tracing.Options{File: file}))
+	client, err := NewUssClient(srv.URL, nil, transport.DefaultPolicy())
+	require.NoError(t, err)
+
+	ctx, parent := tracing.Start(context.Background(), "scenario", tracing.SpanKindInternal)
+	_, err = client.GetOperationalIntentDetails(ctx, "a3b5c7d9-0000-4000-8000-000000000001")
+	require.NoError(t, err)
+	parent.End(nil)
+	require.NoError(t, tracing.Shutdown(context.Background()))
+
+	sc, err := tracing.ParseTraceparent(traceparent)
+	require.NoError(t, err)
+	assert.Equal(t, parent.Context().TraceID(), sc.TraceID())
+	assert.NotEqual(t, parent.Context().SpanID(), sc.SpanID())
+
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	var exported struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					SpanId       string `json:"spanId"`
+					ParentSpanId string `json:"parentSpanId"`
+					Name         string `json:"name"`
+					Attributes   []struct {
+						Key   string         `json:"key"`
+						Value map[string]any `json:"value"`
+					} `json:"attributes"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	require.NoError(t, json.Unmarshal(b, &exported))
+	span := exported.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "GET /uss/v1/operational_intents/{id}", span.Name)
+	assert.Equal(t, sc.SpanID().String(), span.SpanId)
+	assert.Equal(t, parent.Context().SpanID().String(), span.ParentSpanId)
+	attributes := map[string]any{}
+	for _, attr := range span.Attributes {
+		for _, v := range attr.Value {
+			attributes[attr.Key] = v
+		}
+	}
+	assert.Equal(t, "a3b5c7d9-0000-4000-8000-000000000001", attributes[tracing.AttrEntityId])
+	assert.Equal(t, "/uss/v1/operational_intents/{id}", attributes[tracing.AttrRoute])
+	assert.Equal(t, "200", attributes[tracing.AttrStatusCode])
+}